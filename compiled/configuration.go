@@ -17,9 +17,12 @@ func New(outpath string, templates []TemplateConfiguration, funcsmap ...string)
 		t := &ret.Templates[i]
 		t.TemplatesDataConfiguration = map[string]DataConfiguration{}
 		for n, d := range t.TemplatesData {
-			t.TemplatesDataConfiguration[n] = makeDataConfiguration(d)
+			t.TemplatesDataConfiguration[n] = MakeDataConfiguration(d)
 		}
 	}
+	if DevMode() {
+		ret.Registry.dev = newDevTemplates(ret)
+	}
 	return ret
 }
 
@@ -29,13 +32,41 @@ func (c *Configuration) SetPkg(s string) *Configuration {
 	return c
 }
 
+// SetTests enables the generation of the tests comparing the compiled templates
+// with the interpreted templates, they are written next to OutPath.
+func (c *Configuration) SetTests(enabled bool) *Configuration {
+	c.Tests = enabled
+	return c
+}
+
+// SetBenchmarks enables the generation of the benchmarks of the compiled templates
+// and the interpreted templates, they are written next to OutPath.
+func (c *Configuration) SetBenchmarks(enabled bool) *Configuration {
+	c.Benchmarks = enabled
+	return c
+}
+
+// Funcs adds the funcs to the compiled templates of the registry,
+// and to the templates interpreted in development mode.
+// The generated init adds the funcmaps accessible from the output program,
+// the others must be added before the templates are executed.
+func (c *Configuration) Funcs(funcs map[string]interface{}) *Configuration {
+	c.Registry.Funcs(funcs)
+	for i := range c.Templates {
+		c.Templates[i].AddFuncs(funcs)
+	}
+	return c
+}
+
 // Configuration holds all information to run the template compiler.
 type Configuration struct {
 	*Registry
-	OutPath   string
-	OutPkg    string
-	Templates []TemplateConfiguration
-	FuncsMap  []string
+	OutPath    string
+	OutPkg     string
+	Templates  []TemplateConfiguration
+	FuncsMap   []string
+	Tests      bool
+	Benchmarks bool
 }
 
 // TemplateConfiguration holds the configuration for a set of template files.
@@ -49,6 +80,25 @@ type TemplateConfiguration struct {
 	FuncsMap                   []string
 	FuncsExport                map[string]interface{}
 	PublicIdents               []map[string]string
+	// SizeProfile is the path to a profile written by template.SizeProfile.WriteFile,
+	// it improves the estimated output sizes of the templates with loops.
+	SizeProfile string
+	// TemplatesSamples are the data values to render the templates with in the generated tests,
+	// indexed by template name like TemplatesData.
+	TemplatesSamples map[string][]interface{}
+	// Funcs are the funcs of FuncsMap at runtime, the generated init adds them.
+	// The development mode parses the templates with them.
+	Funcs map[string]interface{}
+}
+
+// AddFuncs adds the funcs to the runtime funcs of the configuration.
+func (t *TemplateConfiguration) AddFuncs(funcs map[string]interface{}) {
+	if t.Funcs == nil {
+		t.Funcs = map[string]interface{}{}
+	}
+	for name, fn := range funcs {
+		t.Funcs[name] = fn
+	}
 }
 
 //DataConfiguration holds information about the data type consumed by the template.
@@ -59,8 +109,8 @@ type DataConfiguration struct {
 	PkgPath      string
 }
 
-// makeDataConfiguration transforms data into a DataConfiguration
-func makeDataConfiguration(some interface{}) DataConfiguration {
+// MakeDataConfiguration transforms data into a DataConfiguration
+func MakeDataConfiguration(some interface{}) DataConfiguration {
 	ret := DataConfiguration{}
 	if some == nil {
 		return ret
@@ -74,5 +124,8 @@ func makeDataConfiguration(some interface{}) DataConfiguration {
 	ret.DataTypeName = r.Name()
 	ret.PkgPath = r.PkgPath()
 	ret.DataType = filepath.Base(r.PkgPath()) + "." + r.Name()
+	if r.PkgPath() == "" { // a builtin type.
+		ret.DataType = r.Name()
+	}
 	return ret
 }
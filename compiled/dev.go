@@ -0,0 +1,241 @@
+package compiled
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	htmltemplate "github.com/mh-cbon/template-compiler/std/html/template"
+	"github.com/mh-cbon/template-compiler/std/text/template"
+	"github.com/mh-cbon/template-compiler/std/text/template/parse"
+)
+
+// DevEnv is the environment variable which enables the development mode when it is set to 1.
+const DevEnv = "TEMPLATE_COMPILER_DEV"
+
+// DevMode tells if the development mode is enabled,
+// with the build tag templatedev or the environment variable TEMPLATE_COMPILER_DEV=1.
+// In development mode, the registry of a Configuration serves the templates
+// interpreted from their source, they are parsed again when their file changes.
+func DevMode() bool {
+	return devBuild || os.Getenv(DevEnv) == "1"
+}
+
+// executer is an interpreted template set.
+type executer interface {
+	ExecuteTemplate(w io.Writer, name string, data interface{}) error
+}
+
+// devFile is a template file, or content, of a configuration interpreted at runtime.
+type devFile struct {
+	conf    *TemplateConfiguration
+	path    string
+	modTime time.Time
+	mu      sync.RWMutex
+	set     executer
+	err     error
+	// stale tells the file must be parsed again, such as when the funcs of the registry change.
+	stale bool
+}
+
+// load parses the file again with the funcs when it has changed since its last load,
+// it returns the names of the templates it defines, and tells if it was parsed again.
+func (f *devFile) load(funcs template.FuncMap) ([]string, bool, error) {
+	var modTime time.Time
+	if f.path != "" {
+		s, err := os.Stat(f.path)
+		if err != nil {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			f.set, f.err = nil, err
+			return nil, true, err
+		}
+		modTime = s.ModTime()
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.set != nil && f.stale == false && modTime.Equal(f.modTime) {
+		return nil, false, nil
+	}
+	f.modTime = modTime
+	f.set, f.err, f.stale = nil, nil, false
+
+	names := []string{}
+	if f.conf.HTML {
+		t := htmltemplate.New(f.name()).Funcs(htmltemplate.FuncMap(f.conf.Funcs)).Funcs(htmltemplate.FuncMap(funcs))
+		if f.path != "" {
+			t, f.err = t.ParseFiles(f.path)
+		} else {
+			t, f.err = t.Parse(f.conf.TemplateContent)
+		}
+		if f.err == nil {
+			for _, tpl := range t.Templates() {
+				if tpl.Tree != nil {
+					names = append(names, tpl.Name())
+				}
+			}
+			f.set = t
+		}
+	} else {
+		t := template.New(f.name()).Funcs(template.FuncMap(f.conf.Funcs)).Funcs(funcs)
+		if f.path != "" {
+			t, f.err = t.ParseFiles(f.path)
+		} else {
+			t, f.err = t.Parse(f.conf.TemplateContent)
+		}
+		if f.err == nil {
+			for _, tpl := range t.Templates() {
+				if tpl.Tree != nil {
+					names = append(names, tpl.Name())
+				}
+			}
+			f.set = t
+		}
+	}
+	return names, true, f.err
+}
+
+// name returns the name of the main template of the file.
+func (f *devFile) name() string {
+	if f.path != "" {
+		return filepath.Base(f.path)
+	}
+	return f.conf.TemplateName
+}
+
+// execute executes the template name of the file.
+func (f *devFile) execute(w io.Writer, name string, data interface{}) error {
+	f.mu.RLock()
+	set, err := f.set, f.err
+	f.mu.RUnlock()
+	if err != nil {
+		return err
+	}
+	return set.ExecuteTemplate(w, name, data)
+}
+
+// devTemplates serves the templates of a configuration interpreted from their source.
+type devTemplates struct {
+	conf      *Configuration
+	mu        sync.Mutex
+	files     map[string]*devFile
+	templates map[string]*devTemplate
+}
+
+// devTemplate is a template name of a devFile.
+type devTemplate struct {
+	file     *devFile
+	compiled *template.Compiled
+}
+
+func newDevTemplates(conf *Configuration) *devTemplates {
+	return &devTemplates{
+		conf:      conf,
+		files:     map[string]*devFile{},
+		templates: map[string]*devTemplate{},
+	}
+}
+
+// lookup returns the template name, its file is parsed again if it has changed.
+// When the template is unknown, the configuration is browsed for new files.
+func (d *devTemplates) lookup(name string) (*template.Compiled, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if t, ok := d.templates[name]; ok {
+		d.loadFile(t.file)
+		if t, ok := d.templates[name]; ok {
+			return t.compiled, true
+		}
+	}
+	d.loadFiles()
+	if t, ok := d.templates[name]; ok {
+		return t.compiled, true
+	}
+	return nil, false
+}
+
+// names returns the names of all the templates sorted asc,
+// the changed files are parsed again.
+func (d *devTemplates) names() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, f := range d.files {
+		d.loadFile(f)
+	}
+	d.loadFiles()
+	names := []string{}
+	for name := range d.templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// loadFiles loads the files of the configuration not loaded yet.
+func (d *devTemplates) loadFiles() {
+	for i := range d.conf.Templates {
+		t := &d.conf.Templates[i]
+		if t.TemplatesPath == "" {
+			key := fmt.Sprintf("%v:%v", i, t.TemplateName)
+			if _, ok := d.files[key]; ok == false {
+				d.files[key] = &devFile{conf: t}
+				d.loadFile(d.files[key])
+			}
+			continue
+		}
+		paths, _ := filepath.Glob(t.TemplatesPath)
+		for _, path := range paths {
+			if _, ok := d.files[path]; ok == false {
+				d.files[path] = &devFile{conf: t, path: path}
+				d.loadFile(d.files[path])
+			}
+		}
+	}
+}
+
+// invalidate forces the files to be parsed again when their templates are looked up.
+func (d *devTemplates) invalidate() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, f := range d.files {
+		f.mu.Lock()
+		f.stale = true
+		f.mu.Unlock()
+	}
+}
+
+// loadFile loads the file f and registers its new templates,
+// the templates it does not define anymore are removed.
+func (d *devTemplates) loadFile(f *devFile) {
+	names, reloaded, err := f.load(d.conf.Registry.runtimeFuncs())
+	if err != nil {
+		// the templates of the file return the error when they are executed.
+		names = []string{f.name()}
+	} else if reloaded {
+		defined := map[string]bool{}
+		for _, name := range names {
+			defined[name] = true
+		}
+		for name, t := range d.templates {
+			if t.file == f && defined[name] == false {
+				delete(d.templates, name)
+			}
+		}
+	}
+	for _, name := range names {
+		if _, ok := d.templates[name]; ok {
+			continue
+		}
+		name := name
+		d.templates[name] = &devTemplate{
+			file: f,
+			compiled: template.NewCompiled(name, func(t parse.Templater, w io.Writer, data interface{}) error {
+				return f.execute(w, name, data)
+			}).SetNamespace(d.conf.Registry),
+		}
+	}
+}
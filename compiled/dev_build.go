@@ -0,0 +1,7 @@
+//go:build templatedev
+// +build templatedev
+
+package compiled
+
+// devBuild enables the development mode with the build tag templatedev.
+const devBuild = true
@@ -0,0 +1,147 @@
+package compiled
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDevModeReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "template-compiler-dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "a.tpl")
+	if err := ioutil.WriteFile(path, []byte(`{{up .}}`), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	conf := New("gen.go", []TemplateConfiguration{
+		TemplateConfiguration{TemplatesPath: filepath.Join(dir, "*.tpl")},
+		TemplateConfiguration{TemplateName: "notafile", TemplateContent: `hello {{.}}`},
+	})
+	conf.Templates[0].AddFuncs(map[string]interface{}{"up": strings.ToUpper})
+	conf.Registry.dev = newDevTemplates(conf)
+	conf.Add("a.tpl", writeString("compiled"))
+
+	var b bytes.Buffer
+	if err := conf.ExecuteTemplate(&b, "a.tpl", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "A" {
+		t.Errorf("Unexpected output %q", b.String())
+	}
+
+	if err := ioutil.WriteFile(path, []byte(`{{define "x"}}x{{end}}{{up .}}!`), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	b.Reset()
+	if err := conf.ExecuteTemplate(&b, "a.tpl", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "A!" {
+		t.Errorf("Unexpected output after the change %q", b.String())
+	}
+	if names := strings.Join(conf.Names(), ","); names != "a.tpl,notafile,x" {
+		t.Errorf("Unexpected names %v", names)
+	}
+
+	b.Reset()
+	if err := conf.ExecuteTemplate(&b, "notafile", "you"); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "hello you" {
+		t.Errorf("Unexpected output %q", b.String())
+	}
+
+	// the execution fails when the source is removed.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	b.Reset()
+	if err := conf.ExecuteTemplate(&b, "a.tpl", "a"); err == nil {
+		t.Errorf("Expected an error when the source is removed, got %q", b.String())
+	}
+}
+
+func TestDevModeRegistryFuncs(t *testing.T) {
+	conf := New("gen.go", []TemplateConfiguration{
+		TemplateConfiguration{TemplateName: "notafile", TemplateContent: `{{shout .}}`},
+	})
+	conf.Registry.dev = newDevTemplates(conf)
+	conf.Add("notafile", writeString("compiled"))
+
+	var b bytes.Buffer
+	if err := conf.ExecuteTemplate(&b, "notafile", "a"); err == nil {
+		t.Errorf("Expected an error when the func is not defined, got %q", b.String())
+	}
+
+	conf.Registry.Funcs(map[string]interface{}{"shout": func(s string) string { return strings.ToUpper(s) + "!" }})
+	b.Reset()
+	if err := conf.ExecuteTemplate(&b, "notafile", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "A!" {
+		t.Errorf("Unexpected output %q", b.String())
+	}
+}
+
+func TestDevModeRemovedTemplates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "template-compiler-dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "a.tpl")
+	if err := ioutil.WriteFile(path, []byte(`{{define "x"}}x{{end}}{{define "y"}}y{{end}}a`), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	conf := New("gen.go", []TemplateConfiguration{
+		TemplateConfiguration{TemplatesPath: filepath.Join(dir, "*.tpl")},
+	})
+	conf.Registry.dev = newDevTemplates(conf)
+	conf.Add("x", writeString("compiled x"))
+
+	var b bytes.Buffer
+	if err := conf.ExecuteTemplate(&b, "x", nil); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "x" {
+		t.Errorf("Unexpected output %q", b.String())
+	}
+	if names := strings.Join(conf.Names(), ","); names != "a.tpl,x,y" {
+		t.Errorf("Unexpected names %v", names)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(`a`), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if names := strings.Join(conf.Names(), ","); names != "a.tpl,x" {
+		t.Errorf("Unexpected names after the change %v", names)
+	}
+	// x falls back to the compiled template, y is not found.
+	b.Reset()
+	if err := conf.ExecuteTemplate(&b, "x", nil); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "compiled x" {
+		t.Errorf("Unexpected output after the change %q", b.String())
+	}
+	if conf.Has("y") {
+		t.Errorf("Expected y to be removed")
+	}
+}
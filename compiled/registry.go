@@ -1,7 +1,14 @@
 package compiled
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/mh-cbon/template-compiler/std/text/template"
 	"github.com/mh-cbon/template-compiler/std/text/template/parse"
@@ -15,32 +22,379 @@ func NewRegistry() *Registry {
 }
 
 // Registry registers compiled templates by their name.
+// It is safe for concurrent use, the templates can be replaced
+// while they are looked up and executed.
 type Registry struct {
+	mu        sync.RWMutex
 	templates map[string]*template.Compiled
+	// dev serves the interpreted templates in development mode.
+	dev *devTemplates
+	// limits are the limits of the executions of the templates.
+	limits template.Limits
+	// observer is notified of the executions of the templates, it can be nil.
+	observer Observer
+	// funcs are the funcs of the templates at runtime.
+	funcs template.FuncMap
+	// requiredFuncs are the funcs the compiled templates look up at runtime,
+	// by template name, their values are nil funcs of the expected types.
+	requiredFuncs map[string]map[string]interface{}
 }
 
+// Observer is notified of the executions of the templates of a Registry,
+// of the top level templates and of their nested templates,
+// such as to measure their latencies and count their errors.
+type Observer = parse.Observer
+
 // Add registers a func as a compiled template with given name.
-func (t Registry) Add(name string, fn parse.CompiledTemplateFunc) {
-	t.templates[name] = template.NewCompiled(name, fn)
+func (t *Registry) Add(name string, fn parse.CompiledTemplateFunc) {
+	t.Set(name, template.NewCompiled(name, fn))
+}
+
+// AddAppend registers the append form of the compiled template with given name.
+// The template must be added first.
+func (t *Registry) AddAppend(name string, fn parse.CompiledAppendFunc) {
+	t.mustGetCompiled(name).SetAppend(fn)
+}
+
+// AddContext registers the context form of the compiled template with given name.
+// The template must be added first.
+func (t *Registry) AddContext(name string, fn parse.CompiledContextFunc) {
+	t.mustGetCompiled(name).SetContext(fn)
+}
+
+// SetSizeHint sets the estimated output size of the compiled template with given name.
+// The template must be added first.
+func (t *Registry) SetSizeHint(name string, n int) {
+	t.mustGetCompiled(name).SetSizeHint(n)
+}
+
+// SizeHint returns the estimated output size of the compiled template with given name,
+// such as an HTTP handler can size its buffer, it returns 0 if the template is not found.
+func (t *Registry) SizeHint(name string) int {
+	if tpl, ok := t.Lookup(name); ok {
+		return tpl.SizeHint()
+	}
+	return 0
 }
 
 // Get provides a compiled template matching given name.
-func (t Registry) Get(name string) *template.Compiled {
-	return t.templates[name]
+func (t *Registry) Get(name string) *template.Compiled {
+	tpl, _ := t.Lookup(name)
+	return tpl
+}
+
+// Lookup provides the compiled template matching given name,
+// and tells if it was found.
+// In development mode, it provides the interpreted template when its source is found.
+func (t *Registry) Lookup(name string) (*template.Compiled, bool) {
+	if t.dev != nil {
+		if tpl, ok := t.dev.lookup(name); ok {
+			return tpl, ok
+		}
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	tpl, ok := t.templates[name]
+	return tpl, ok
+}
+
+// mustGetCompiled provides the registered compiled template matching given name,
+// it panics if the template is not found.
+func (t *Registry) mustGetCompiled(name string) *template.Compiled {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if tpl, ok := t.templates[name]; ok {
+		return tpl
+	}
+	panic(
+		fmt.Errorf("template not found: %v", name),
+	)
+}
+
+// Has tells if a compiled template matches given name.
+func (t *Registry) Has(name string) bool {
+	_, ok := t.Lookup(name)
+	return ok
+}
+
+// Names returns the names of the registered templates sorted asc.
+func (t *Registry) Names() []string {
+	var devNames []string
+	if t.dev != nil {
+		// the dev templates read the funcs of the registry.
+		devNames = t.dev.names()
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	names := make([]string, 0, len(t.templates))
+	for name := range t.templates {
+		names = append(names, name)
+	}
+	for _, name := range devNames {
+		if _, ok := t.templates[name]; ok == false {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
 }
 
 // Set a compiled template as given name.
-func (t Registry) Set(name string, tpl *template.Compiled) {
+// The registry becomes the namespace of the template,
+// it can call any other template of the registry by its name.
+func (t *Registry) Set(name string, tpl *template.Compiled) {
+	tpl.SetNamespace(t)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.funcs) > 0 {
+		tpl.Funcs(t.funcs)
+	}
 	t.templates[name] = tpl
 }
 
+// Remove the compiled template matching given name.
+func (t *Registry) Remove(name string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.templates, name)
+}
+
 // MustGet provides the compiled template matching given name,
 // it panics if the template is not found.
-func (t Registry) MustGet(name string) *template.Compiled {
-	if t, ok := t.templates[name]; ok {
-		return t
+func (t *Registry) MustGet(name string) *template.Compiled {
+	if tpl, ok := t.Lookup(name); ok {
+		return tpl
 	}
 	panic(
 		fmt.Errorf("template not found: %v", name),
 	)
 }
+
+// SetLimits sets the limits of the executions of the templates,
+// such as the maximum depth of the nested template calls,
+// the maximum output size, and the maximum iterations of each range.
+// An execution exceeding a limit returns a *template.LimitError.
+func (t *Registry) SetLimits(limits template.Limits) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.limits = limits
+}
+
+// Limits returns the limits of the executions of the templates.
+func (t *Registry) Limits() template.Limits {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.limits
+}
+
+// SetObserver attaches o to the executions of the templates,
+// a nil observer detaches it.
+func (t *Registry) SetObserver(o Observer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.observer = o
+}
+
+// Observer returns the observer of the executions of the templates.
+func (t *Registry) Observer() Observer {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.observer
+}
+
+// executionOptions returns the limits and the observer of the executions of the templates.
+func (t *Registry) executionOptions() (template.Limits, Observer) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.limits, t.observer
+}
+
+// Funcs adds the funcs to the templates of the registry, and to the templates set later.
+// The compiled templates look up the unexported and the inlined funcs of their funcmaps at runtime,
+// the funcs must be added before the templates are executed.
+// It panics if a value of the map is not a func, see template.Template.Funcs.
+func (t *Registry) Funcs(funcs map[string]interface{}) *Registry {
+	t.mu.Lock()
+	if t.funcs == nil {
+		t.funcs = template.FuncMap{}
+	}
+	for name, fn := range funcs {
+		t.funcs[name] = fn
+	}
+	for _, tpl := range t.templates {
+		tpl.Funcs(funcs)
+	}
+	t.mu.Unlock()
+	if t.dev != nil {
+		// the dev templates are parsed again with the new funcs.
+		t.dev.invalidate()
+	}
+	return t
+}
+
+// runtimeFuncs returns a copy of the funcs added to the registry.
+func (t *Registry) runtimeFuncs() template.FuncMap {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	funcs := template.FuncMap{}
+	for name, fn := range t.funcs {
+		funcs[name] = fn
+	}
+	return funcs
+}
+
+// RequireFuncs declares the funcs the compiled template with given name looks up at runtime,
+// the values are nil funcs of the expected types, see CheckFuncs.
+func (t *Registry) RequireFuncs(name string, funcs map[string]interface{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.requiredFuncs == nil {
+		t.requiredFuncs = map[string]map[string]interface{}{}
+	}
+	t.requiredFuncs[name] = funcs
+}
+
+// CheckFuncs returns an error reporting the funcs required by the compiled templates
+// which are not added to the registry, or which type is not the expected one.
+// It is meant to be called at startup, once the funcs are added.
+func (t *Registry) CheckFuncs() error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	names := make([]string, 0, len(t.requiredFuncs))
+	for name := range t.requiredFuncs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	errs := []string{}
+	for _, name := range names {
+		required := t.requiredFuncs[name]
+		funcNames := make([]string, 0, len(required))
+		for funcName := range required {
+			funcNames = append(funcNames, funcName)
+		}
+		sort.Strings(funcNames)
+		for _, funcName := range funcNames {
+			fn, ok := t.funcs[funcName]
+			if ok == false {
+				errs = append(errs, fmt.Sprintf("template: %v: function %q not defined", name, funcName))
+			} else if want := reflect.TypeOf(required[funcName]); reflect.TypeOf(fn) != want {
+				errs = append(errs, fmt.Sprintf("template: %v: function %q is %T, wants %v", name, funcName, fn, want))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// ExecuteTemplate executes the template with given name to w.
+func (t *Registry) ExecuteTemplate(w io.Writer, name string, data interface{}) error {
+	limits, observer := t.executionOptions()
+	return t.executeTemplate(w, name, data, limits, observer)
+}
+
+// ExecuteTemplateLimits executes the template with given name to w within limits,
+// they take precedence over the limits of the registry.
+func (t *Registry) ExecuteTemplateLimits(w io.Writer, name string, data interface{}, limits template.Limits) error {
+	return t.executeTemplate(w, name, data, limits, t.Observer())
+}
+
+func (t *Registry) executeTemplate(w io.Writer, name string, data interface{}, limits template.Limits, observer Observer) error {
+	tpl, ok := t.Lookup(name)
+	if !ok {
+		return fmt.Errorf("template not found: %v", name)
+	}
+	if observer != nil {
+		return tpl.ExecuteObserved(context.Background(), w, data, limits, observer)
+	}
+	if limits.IsZero() == false {
+		return tpl.ExecuteLimits(w, data, limits)
+	}
+	return tpl.Execute(w, data)
+}
+
+// ExecuteContext executes the template with given name to w until ctx is done,
+// it returns the error of ctx when it is done.
+func (t *Registry) ExecuteContext(ctx context.Context, w io.Writer, name string, data interface{}) error {
+	tpl, ok := t.Lookup(name)
+	if !ok {
+		return fmt.Errorf("template not found: %v", name)
+	}
+	limits, observer := t.executionOptions()
+	if observer != nil {
+		return tpl.ExecuteObserved(ctx, w, data, limits, observer)
+	}
+	if limits.IsZero() == false {
+		return tpl.ExecuteContextLimits(ctx, w, data, limits)
+	}
+	return tpl.ExecuteContext(ctx, w, data)
+}
+
+// appendExecute appends the output of tpl to dst within the limits of the registry,
+// reported to its observer.
+func (t *Registry) appendExecute(tpl *template.Compiled, dst []byte, data interface{}) ([]byte, error) {
+	limits, observer := t.executionOptions()
+	if observer != nil {
+		w := template.SliceWriter(dst)
+		err := tpl.ExecuteObserved(context.Background(), &w, data, limits, observer)
+		return w, err
+	}
+	if limits.IsZero() == false {
+		return tpl.AppendExecuteLimits(dst, data, limits)
+	}
+	return tpl.AppendExecute(dst, data)
+}
+
+// maxPooledBufferSize is the capacity above which a buffer is not returned to the pool,
+// so a single large rendering does not retain its memory.
+const maxPooledBufferSize = 64 << 10
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 4096)
+		return &b
+	},
+}
+
+// Render executes the template with given name into a pooled buffer,
+// then writes the output to w at once.
+func (t *Registry) Render(w io.Writer, name string, data interface{}) error {
+	tpl, ok := t.Lookup(name)
+	if !ok {
+		return fmt.Errorf("template not found: %v", name)
+	}
+	b := bufferPool.Get().(*[]byte)
+	out, err := t.appendExecute(tpl, (*b)[:0], data)
+	if err == nil {
+		_, err = w.Write(out)
+	}
+	if cap(out) <= maxPooledBufferSize {
+		*b = out
+		bufferPool.Put(b)
+	}
+	return err
+}
+
+// AppendRender appends the output of the template with given name to dst.
+// When dst is nil, the output is rendered into a pooled buffer
+// and copied into a slice of the exact size.
+func (t *Registry) AppendRender(dst []byte, name string, data interface{}) ([]byte, error) {
+	tpl, ok := t.Lookup(name)
+	if !ok {
+		return dst, fmt.Errorf("template not found: %v", name)
+	}
+	if dst != nil {
+		return t.appendExecute(tpl, dst, data)
+	}
+	b := bufferPool.Get().(*[]byte)
+	out, err := t.appendExecute(tpl, (*b)[:0], data)
+	if err == nil {
+		dst = append(make([]byte, 0, len(out)), out...)
+	}
+	if cap(out) <= maxPooledBufferSize {
+		*b = out
+		bufferPool.Put(b)
+	}
+	return dst, err
+}
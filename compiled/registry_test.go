@@ -0,0 +1,482 @@
+package compiled
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mh-cbon/template-compiler/std/text/template"
+	"github.com/mh-cbon/template-compiler/std/text/template/parse"
+)
+
+func writeString(s string) parse.CompiledTemplateFunc {
+	return func(t parse.Templater, w io.Writer, data interface{}) error {
+		_, err := io.WriteString(w, s)
+		return err
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	r.Add("b", writeString("b"))
+	r.Add("a", writeString("a"))
+
+	if names := r.Names(); reflect.DeepEqual(names, []string{"a", "b"}) == false {
+		t.Errorf("Unexpected names %v", names)
+	}
+	if r.Has("a") == false || r.Has("c") {
+		t.Errorf("Unexpected Has results")
+	}
+	if tpl, ok := r.Lookup("a"); ok == false || tpl == nil {
+		t.Errorf("Expected to lookup the template a")
+	}
+	if tpl, ok := r.Lookup("c"); ok || tpl != nil {
+		t.Errorf("Expected to not lookup the template c")
+	}
+
+	var b bytes.Buffer
+	if err := r.ExecuteTemplate(&b, "b", nil); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "b" {
+		t.Errorf("Unexpected output %q", b.String())
+	}
+	if err := r.ExecuteTemplate(&b, "c", nil); err == nil || err.Error() != "template not found: c" {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	r.Remove("b")
+	if names := r.Names(); reflect.DeepEqual(names, []string{"a"}) == false {
+		t.Errorf("Unexpected names after remove %v", names)
+	}
+}
+
+func TestRegistryConcurrentReload(t *testing.T) {
+	r := NewRegistry()
+	r.Add("a", writeString("a0"))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 200; n++ {
+				var b bytes.Buffer
+				if err := r.ExecuteTemplate(&b, "a", nil); err != nil {
+					t.Error(err)
+					return
+				}
+				r.Names()
+				r.Has("b")
+			}
+		}()
+	}
+	for n := 0; n < 200; n++ {
+		r.Add("a", writeString(fmt.Sprintf("a%v", n)))
+		r.Add("b", writeString("b"))
+		r.Remove("b")
+	}
+	wg.Wait()
+}
+
+func TestRegistryCrossTemplateCalls(t *testing.T) {
+	r := NewRegistry()
+	r.Add("page", func(t parse.Templater, w io.Writer, data interface{}) error {
+		if _, err := io.WriteString(w, "<"); err != nil {
+			return err
+		}
+		if err := t.ExecuteTemplate(w, "row", data); err != nil {
+			return err
+		}
+		_, err := io.WriteString(w, ">")
+		return err
+	})
+	r.Add("row", func(t parse.Templater, w io.Writer, data interface{}) error {
+		_, err := fmt.Fprintf(w, "row %v", data)
+		return err
+	})
+	r.Add("broken", func(t parse.Templater, w io.Writer, data interface{}) error {
+		return t.ExecuteTemplate(w, "nop", data)
+	})
+
+	var b bytes.Buffer
+	if err := r.ExecuteTemplate(&b, "page", 1); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "<row 1>" {
+		t.Errorf("Unexpected output %q", b.String())
+	}
+
+	// the callee is looked up at execution.
+	r.Add("row", writeString("new row"))
+	b.Reset()
+	if err := r.ExecuteTemplate(&b, "page", 1); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "<new row>" {
+		t.Errorf("Unexpected output after the callee change %q", b.String())
+	}
+
+	err := r.ExecuteTemplate(&b, "broken", nil)
+	expected := `template: no template "nop" associated with template "broken"`
+	if err == nil || err.Error() != expected {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
+
+func TestRegistryExecuteContext(t *testing.T) {
+	r := NewRegistry()
+	r.Add("page", func(t parse.Templater, w io.Writer, data interface{}) error {
+		return t.ExecuteTemplate(w, "row", data)
+	})
+	r.AddContext("page", func(ctx context.Context, t parse.Templater, w io.Writer, data interface{}) error {
+		for i := 0; i < 3; i++ {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := t.ExecuteTemplateContext(ctx, w, "row", i); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	r.Add("row", func(t parse.Templater, w io.Writer, data interface{}) error {
+		_, err := fmt.Fprintf(w, "row %v;", data)
+		return err
+	})
+
+	var b bytes.Buffer
+	if err := r.ExecuteContext(context.Background(), &b, "page", nil); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "row 0;row 1;row 2;" {
+		t.Errorf("Unexpected output %q", b.String())
+	}
+
+	// the template without a context func is executed when ctx is not done.
+	b.Reset()
+	if err := r.ExecuteContext(context.Background(), &b, "row", 1); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "row 1;" {
+		t.Errorf("Unexpected output %q", b.String())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, name := range []string{"page", "row"} {
+		b.Reset()
+		if err := r.ExecuteContext(ctx, &b, name, nil); err != context.Canceled {
+			t.Errorf("%v: Unexpected error %v", name, err)
+		}
+		if b.Len() > 0 {
+			t.Errorf("%v: Unexpected output %q", name, b.String())
+		}
+	}
+	if err := r.ExecuteContext(ctx, &b, "nop", nil); err == nil || err.Error() != "template not found: nop" {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
+
+func TestRegistryLimits(t *testing.T) {
+	r := NewRegistry()
+	r.Add("loop", func(t parse.Templater, w io.Writer, data interface{}) error {
+		if _, err := io.WriteString(w, "."); err != nil {
+			return err
+		}
+		return t.ExecuteTemplate(w, "loop", data)
+	})
+	r.Add("range", func(t parse.Templater, w io.Writer, data interface{}) error {
+		limiter, _ := t.(parse.Limiter)
+		for i := 1; i <= 10; i++ {
+			if limiter != nil {
+				if err := limiter.CheckRange("range", i); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+
+	var b bytes.Buffer
+	r.SetLimits(template.Limits{MaxDepth: 5})
+	err := r.ExecuteTemplate(&b, "loop", nil)
+	expected := &template.LimitError{Name: "loop", Limit: "template depth", Max: 5}
+	if reflect.DeepEqual(err, expected) == false {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if b.String() != "......" {
+		t.Errorf("Unexpected output %q", b.String())
+	}
+
+	b.Reset()
+	err = r.ExecuteTemplateLimits(&b, "loop", nil, template.Limits{MaxBytes: 3})
+	expected = &template.LimitError{Name: "loop", Limit: "output size", Max: 3}
+	if reflect.DeepEqual(err, expected) == false {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if b.String() != "..." {
+		t.Errorf("Unexpected output %q", b.String())
+	}
+
+	r.SetLimits(template.Limits{MaxRangeIterations: 5})
+	err = r.ExecuteTemplate(&b, "range", nil)
+	expected = &template.LimitError{Name: "range", Limit: "range iterations", Max: 5}
+	if reflect.DeepEqual(err, expected) == false {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if err.Error() != "template: range: exceeded maximum range iterations (5)" {
+		t.Errorf("Unexpected error message %v", err)
+	}
+	if _, err := r.AppendRender(nil, "range", nil); reflect.DeepEqual(err, expected) == false {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if err := r.ExecuteContext(context.Background(), &b, "range", nil); reflect.DeepEqual(err, expected) == false {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if err := r.ExecuteTemplateLimits(&b, "range", nil, template.Limits{MaxRangeIterations: 10}); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	r.SetLimits(template.Limits{})
+	if err := r.ExecuteTemplate(&b, "range", nil); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+
+	// the templates executed directly, such as by the generated Render funcs, apply the limits.
+	r.SetLimits(template.Limits{MaxDepth: 5})
+	expected = &template.LimitError{Name: "loop", Limit: "template depth", Max: 5}
+	b.Reset()
+	if err := r.MustGet("loop").Execute(&b, nil); reflect.DeepEqual(err, expected) == false {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if b.String() != "......" {
+		t.Errorf("Unexpected output %q", b.String())
+	}
+	if err := r.MustGet("loop").ExecuteContext(context.Background(), &b, nil); reflect.DeepEqual(err, expected) == false {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if out, err := r.MustGet("loop").AppendExecute(nil, nil); reflect.DeepEqual(err, expected) == false || string(out) != "......" {
+		t.Errorf("Unexpected output %q, error %v", out, err)
+	}
+}
+
+type execErrorData struct{}
+
+func (execErrorData) Method() (string, error) {
+	return "", fmt.Errorf("some error")
+}
+
+func TestRegistryExecError(t *testing.T) {
+	tpl := template.Must(template.New("c.tpl").Parse(`{{define "row"}}
+  {{.Method}}{{end}}`))
+	var b bytes.Buffer
+	expected := tpl.ExecuteTemplate(&b, "row", execErrorData{})
+	if expected == nil {
+		t.Fatal("expected the interpreted template to fail")
+	}
+
+	r := NewRegistry()
+	r.Add("row", func(t parse.Templater, w io.Writer, data interface{}) error {
+		if _, err := data.(execErrorData).Method(); err != nil {
+			return template.NewExecError("row", "c.tpl:2:4", ".Method", fmt.Errorf("error calling Method: %w", err))
+		}
+		return nil
+	})
+	err := r.ExecuteTemplate(&b, "row", execErrorData{})
+	if err == nil || err.Error() != expected.Error() {
+		t.Errorf("unexpected error\nexpected=%v\ngot=%v", expected, err)
+	}
+	execErr, ok := err.(template.ExecError)
+	if ok == false || execErr.Name != "row" || execErr.Location != "c.tpl:2:4" {
+		t.Errorf("unexpected error %#v", err)
+	}
+	if expected.(template.ExecError).Location != execErr.Location {
+		t.Errorf("unexpected location %q", expected.(template.ExecError).Location)
+	}
+	if template.NewExecError("x", "c.tpl:1:0", "x", err) != err {
+		t.Errorf("expected the ExecError to be returned unchanged")
+	}
+}
+
+type observation struct {
+	event string
+	name  string
+	n     int
+	err   error
+}
+
+// recordingObserver records the executions it is notified of.
+type recordingObserver struct {
+	mu           sync.Mutex
+	observations []observation
+}
+
+func (o *recordingObserver) OnStart(name string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.observations = append(o.observations, observation{event: "start", name: name})
+}
+
+func (o *recordingObserver) OnEnd(name string, n int, dur time.Duration, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.observations = append(o.observations, observation{event: "end", name: name, n: n, err: err})
+}
+
+func TestRegistryObserver(t *testing.T) {
+	r := NewRegistry()
+	r.Add("page", func(t parse.Templater, w io.Writer, data interface{}) error {
+		if _, ok := t.(parse.Observer); ok == false {
+			return fmt.Errorf("expected an observed execution")
+		}
+		if _, err := io.WriteString(w, "<"); err != nil {
+			return err
+		}
+		if err := t.ExecuteTemplate(w, "row", data); err != nil {
+			return err
+		}
+		_, err := io.WriteString(w, ">")
+		return err
+	})
+	r.Add("row", writeString("row"))
+	someErr := fmt.Errorf("some error")
+	r.Add("broken", func(t parse.Templater, w io.Writer, data interface{}) error {
+		return someErr
+	})
+
+	o := &recordingObserver{}
+	r.SetObserver(o)
+	if r.Observer() != o {
+		t.Errorf("Unexpected observer %v", r.Observer())
+	}
+	var b bytes.Buffer
+	if err := r.ExecuteTemplate(&b, "page", nil); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "<row>" {
+		t.Errorf("Unexpected output %q", b.String())
+	}
+	expected := []observation{
+		{event: "start", name: "page"},
+		{event: "start", name: "row"},
+		{event: "end", name: "row", n: 3},
+		{event: "end", name: "page", n: 5},
+	}
+	if reflect.DeepEqual(o.observations, expected) == false {
+		t.Errorf("Unexpected observations %v", o.observations)
+	}
+
+	o.observations = nil
+	if err := r.ExecuteContext(context.Background(), &b, "broken", nil); err != someErr {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if out, err := r.AppendRender(nil, "row", nil); err != nil || string(out) != "row" {
+		t.Errorf("Unexpected output %q %v", out, err)
+	}
+	expected = []observation{
+		{event: "start", name: "broken"},
+		{event: "end", name: "broken", err: someErr},
+		{event: "start", name: "row"},
+		{event: "end", name: "row", n: 3},
+	}
+	if reflect.DeepEqual(o.observations, expected) == false {
+		t.Errorf("Unexpected observations %v", o.observations)
+	}
+
+	// the limits apply to the observed executions.
+	o.observations = nil
+	err := r.ExecuteTemplateLimits(&b, "page", nil, template.Limits{MaxBytes: 2})
+	if _, ok := err.(*template.LimitError); ok == false || len(o.observations) != 4 || o.observations[3].err != err {
+		t.Errorf("Unexpected error %v, observations %v", err, o.observations)
+	}
+
+	o.observations = nil
+	r.SetObserver(nil)
+	if err := r.ExecuteTemplate(&b, "page", nil); err == nil {
+		t.Errorf("Expected the execution not to be observed")
+	}
+	if len(o.observations) != 0 {
+		t.Errorf("Unexpected observations %v", o.observations)
+	}
+
+	// the templates executed directly, such as by the generated Render funcs, are observed.
+	r.SetObserver(o)
+	b.Reset()
+	if err := r.MustGet("page").Execute(&b, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.MustGet("row").ExecuteContext(context.Background(), &b, nil); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := r.MustGet("row").AppendExecute(nil, nil); err != nil || string(out) != "row" {
+		t.Errorf("Unexpected output %q %v", out, err)
+	}
+	if b.String() != "<row>row" {
+		t.Errorf("Unexpected output %q", b.String())
+	}
+	expected = []observation{
+		{event: "start", name: "page"},
+		{event: "start", name: "row"},
+		{event: "end", name: "row", n: 3},
+		{event: "end", name: "page", n: 5},
+		{event: "start", name: "row"},
+		{event: "end", name: "row", n: 3},
+		{event: "start", name: "row"},
+		{event: "end", name: "row", n: 3},
+	}
+	if reflect.DeepEqual(o.observations, expected) == false {
+		t.Errorf("Unexpected observations %v", o.observations)
+	}
+}
+
+func TestRegistryFuncs(t *testing.T) {
+	r := NewRegistry()
+	call := func(t parse.Templater, w io.Writer, data interface{}) error {
+		_, err := io.WriteString(w, t.GetFuncs()["up"].(func(string) string)("a"))
+		return err
+	}
+	r.Add("before", call)
+	r.RequireFuncs("before", map[string]interface{}{"up": (func(string) string)(nil)})
+	r.RequireFuncs("other", map[string]interface{}{
+		"up":   (func(string) string)(nil),
+		"down": (func(string) string)(nil),
+	})
+	r.RequireFuncs("typed", map[string]interface{}{"count": (func(string) int)(nil)})
+
+	expected := `template: before: function "up" not defined
+template: other: function "down" not defined
+template: other: function "up" not defined
+template: typed: function "count" not defined`
+	if err := r.CheckFuncs(); err == nil || err.Error() != expected {
+		t.Errorf("unexpected error\nexpected=%v\ngot=%v", expected, err)
+	}
+
+	r.Funcs(map[string]interface{}{"up": strings.ToUpper, "count": strings.Count})
+	r.Add("after", call)
+	for _, name := range []string{"before", "after"} {
+		var b bytes.Buffer
+		if err := r.ExecuteTemplate(&b, name, nil); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != "A" {
+			t.Errorf("%v: unexpected output %q", name, b.String())
+		}
+	}
+	expected = `template: other: function "down" not defined
+template: typed: function "count" is func(string, string) int, wants func(string) int`
+	if err := r.CheckFuncs(); err == nil || err.Error() != expected {
+		t.Errorf("unexpected error\nexpected=%v\ngot=%v", expected, err)
+	}
+
+	r.Funcs(map[string]interface{}{"down": strings.ToLower, "count": func(s string) int { return len(s) }})
+	if err := r.CheckFuncs(); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
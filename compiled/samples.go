@@ -0,0 +1,85 @@
+package compiled
+
+import "reflect"
+
+// sampleString is the string value of the populated samples,
+// it contains characters that the html escapers rewrite.
+const sampleString = `<a href="/?q=1&b=2">'x'</a>`
+
+// maxSampleDepth limits the depth of the populated samples for recursive types.
+const maxSampleDepth = 3
+
+// Samples returns the data values to render the template with given name in the generated tests.
+// It returns the TemplatesSamples of name, or "*",
+// otherwise the zero value of the template data type and a populated value of it.
+func (t TemplateConfiguration) Samples(name string) []interface{} {
+	if ret, ok := t.TemplatesSamples[name]; ok {
+		return ret
+	}
+	if ret, ok := t.TemplatesSamples["*"]; ok {
+		return ret
+	}
+	data, ok := t.TemplatesData[name]
+	if !ok {
+		data = t.TemplatesData["*"]
+	}
+	if data == nil {
+		return []interface{}{nil}
+	}
+	typ := reflect.TypeOf(data)
+	zero := reflect.New(typ).Elem()
+	if typ.Kind() == reflect.Ptr {
+		zero = reflect.New(typ.Elem())
+	}
+	populated := reflect.New(typ).Elem()
+	populate(populated, 0)
+	return []interface{}{zero.Interface(), populated.Interface()}
+}
+
+// populate sets the exported fields, elements and scalars of v to non zero values.
+func populate(v reflect.Value, depth int) {
+	if depth > maxSampleDepth || v.CanSet() == false {
+		return
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(sampleString)
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(42)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		v.SetUint(42)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(3.25)
+	case reflect.Ptr:
+		v.Set(reflect.New(v.Type().Elem()))
+		populate(v.Elem(), depth+1)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			populate(v.Field(i), depth+1)
+		}
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), 2, 2))
+		for i := 0; i < v.Len(); i++ {
+			populate(v.Index(i), depth+1)
+		}
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			populate(v.Index(i), depth+1)
+		}
+	case reflect.Map:
+		m := reflect.MakeMap(v.Type())
+		for _, k := range []string{"a", "b"} {
+			key := reflect.New(v.Type().Key()).Elem()
+			if key.Kind() != reflect.String {
+				break
+			}
+			key.SetString(k)
+			value := reflect.New(v.Type().Elem()).Elem()
+			populate(value, depth+1)
+			m.SetMapIndex(key, value)
+		}
+		v.Set(m)
+	}
+}
@@ -0,0 +1,217 @@
+package compiler
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template/parse"
+
+	"github.com/mh-cbon/export-funcmap/export"
+)
+
+// dataAnnotation matches the comment {{/* @data pkgpath.Type */}} declaring the data type of a template.
+var dataAnnotation = regexp.MustCompile(`^/\*\s*@data\s+(\S+)\s*\*/$`)
+
+// parseDataAnnotations returns the data types declared by the annotations of the template content,
+// indexed by template name. The annotation of a define is declared into its body.
+func parseDataAnnotations(name, content string) (map[string]string, error) {
+	ret := map[string]string{}
+	trees := map[string]*parse.Tree{}
+	t := parse.New(name)
+	t.Mode = parse.ParseComments | parse.SkipFuncCheck
+	if _, err := t.Parse(content, "", "", trees); err != nil {
+		return ret, err
+	}
+	for treeName, tree := range trees {
+		if tree.Root == nil {
+			continue
+		}
+		for _, node := range tree.Root.Nodes {
+			if c, ok := node.(*parse.CommentNode); ok {
+				if m := dataAnnotation.FindStringSubmatch(c.Text); m != nil {
+					ret[treeName] = m[1]
+					break
+				}
+			}
+		}
+	}
+	return ret, nil
+}
+
+// getDataAnnotations returns the data types declared by the annotations
+// of the templates of the configuration, indexed by template name.
+// The templates must be declared with literals, otherwise they are ignored.
+func getDataAnnotations(templateConf *ast.CompositeLit) (map[string]string, error) {
+	ret := map[string]string{}
+	if kv := getKeyValue(templateConf, "TemplatesPath"); kv != nil {
+		pattern, ok := getStringLit(kv.Value)
+		if ok == false {
+			return ret, nil
+		}
+		paths, err := filepath.Glob(pattern)
+		if err != nil {
+			return ret, fmt.Errorf("Failed to glob the templates: %v %v", pattern, err)
+		}
+		for _, path := range paths {
+			content, err := ioutil.ReadFile(path)
+			if err != nil {
+				return ret, err
+			}
+			annotations, err := parseDataAnnotations(filepath.Base(path), string(content))
+			if err != nil {
+				return ret, err
+			}
+			for name, dataType := range annotations {
+				ret[name] = dataType
+			}
+		}
+		return ret, nil
+	}
+	nameKv := getKeyValue(templateConf, "TemplateName")
+	contentKv := getKeyValue(templateConf, "TemplateContent")
+	if nameKv == nil || contentKv == nil {
+		return ret, nil
+	}
+	name, ok := getStringLit(nameKv.Value)
+	content, ok2 := getStringLit(contentKv.Value)
+	if ok == false || ok2 == false {
+		return ret, nil
+	}
+	return parseDataAnnotations(name, content)
+}
+
+// getStringLit returns the value of a string literal.
+func getStringLit(e ast.Expr) (string, bool) {
+	lit, ok := e.(*ast.BasicLit)
+	if ok == false || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	return s, err == nil
+}
+
+// injectDataAnnotations adds the data types declared by the annotations to the TemplatesData key,
+// the data types declared into the configuration for a template name take precedence.
+// It returns the imports of the data types.
+func injectDataAnnotations(imports []*ast.ImportSpec, templateConf *ast.CompositeLit, annotations map[string]string) ([]*ast.ImportSpec, error) {
+	ret := []*ast.ImportSpec{}
+	if len(annotations) == 0 {
+		return ret, nil
+	}
+	kv := getKeyValue(templateConf, "TemplatesData")
+	if kv == nil {
+		kv = &ast.KeyValueExpr{
+			Key:   &ast.Ident{Name: "TemplatesData"},
+			Value: stringToExpr("map[string]interface{}{}"),
+		}
+		templateConf.Elts = append(templateConf.Elts, kv)
+	}
+	data, ok := kv.Value.(*ast.CompositeLit)
+	if ok == false {
+		return ret, fmt.Errorf("The TemplatesData key must be a map literal to declare the data types of the annotations")
+	}
+	declared := map[string]bool{}
+	for _, v := range data.Elts {
+		if name, ok := getStringLit(v.(*ast.KeyValueExpr).Key); ok {
+			declared[name] = true
+		}
+	}
+
+	names := []string{}
+	for name := range annotations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if declared[name] {
+			continue
+		}
+		dataType := annotations[name]
+		isPtr := strings.HasPrefix(dataType, "*")
+		dataType = strings.TrimPrefix(dataType, "*")
+		i := strings.LastIndex(dataType, ".")
+		if i < 0 {
+			return ret, fmt.Errorf("The data type %v of the template %v is not a pkgpath.Type annotation", annotations[name], name)
+		}
+		pkgpath, typeName := dataType[:i], dataType[i+1:]
+		wd, _ := os.Getwd()
+		pkg, err := build.Default.Import(pkgpath, wd, 0)
+		if err != nil {
+			return ret, fmt.Errorf("Failed to resolve the data type %v of the template %v: %v", annotations[name], name, err)
+		}
+		if pkg.IsCommand() {
+			return ret, fmt.Errorf(
+				"Impossible to consume the datatype %v located in the main package of the GO program %v",
+				typeName, pkg.ImportPath,
+			)
+		}
+
+		alias := pkg.Name
+		if spec := getImportSpecOf(append(imports, ret...), pkgpath); spec != nil {
+			if spec.Name != nil {
+				alias = spec.Name.Name
+			}
+		} else {
+			alias = uniqueImportAlias(append(imports, ret...), pkg.Name)
+			if alias == filepath.Base(pkgpath) {
+				ret = append(ret, export.NewImportSpec(pkgpath, ""))
+			} else {
+				ret = append(ret, export.NewImportSpec(pkgpath, alias))
+			}
+		}
+		value := fmt.Sprintf("%v.%v{}", alias, typeName)
+		if isPtr {
+			value = "&" + value
+		}
+		data.Elts = append(data.Elts, &ast.KeyValueExpr{
+			Key:   &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(name)},
+			Value: stringToExpr(value),
+		})
+	}
+	return ret, nil
+}
+
+// getImportSpecOf returns the import of pkgpath.
+func getImportSpecOf(imports []*ast.ImportSpec, pkgpath string) *ast.ImportSpec {
+	for _, i := range imports {
+		if i.Path.Value == strconv.Quote(pkgpath) && (i.Name == nil || i.Name.Name != "_") {
+			return i
+		}
+	}
+	return nil
+}
+
+// uniqueImportAlias returns an alias based on the package name not used by the imports.
+func uniqueImportAlias(imports []*ast.ImportSpec, name string) string {
+	used := map[string]bool{}
+	for _, i := range imports {
+		if i.Name != nil {
+			used[i.Name.Name] = true
+		} else {
+			p, _ := strconv.Unquote(i.Path.Value)
+			used[filepath.Base(p)] = true
+		}
+	}
+	if used[name] == false {
+		return name
+	}
+	for n := 1; ; n++ {
+		if x := fmt.Sprintf("%v%v", name, n); used[x] == false {
+			return x
+		}
+	}
+}
+
+// stringToExpr converts a go expression string to an ast.Expr.
+func stringToExpr(expr string) ast.Expr {
+	f := stringToAst("package aa\nvar x = " + expr)
+	return f.Decls[0].(*ast.GenDecl).Specs[0].(*ast.ValueSpec).Values[0]
+}
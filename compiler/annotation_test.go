@@ -0,0 +1,149 @@
+package compiler
+
+import (
+	"bytes"
+	"go/ast"
+	"go/printer"
+	"go/token"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type AnnotationTestData struct {
+	tplstr   string
+	expected map[string]string
+}
+
+func TestParseDataAnnotations(t *testing.T) {
+
+	allTestData := []AnnotationTestData{
+		AnnotationTestData{
+			tplstr: `{{/* @data github.com/acme/app/models.UserPage */}}{{.}}`,
+			expected: map[string]string{
+				"x": "github.com/acme/app/models.UserPage",
+			},
+		},
+		AnnotationTestData{
+			tplstr: `{{/* @data github.com/acme/app/models.UserPage */}}{{define "row"}}{{/* @data *github.com/acme/app/models.User */}}{{.}}{{end}}`,
+			expected: map[string]string{
+				"x":   "github.com/acme/app/models.UserPage",
+				"row": "*github.com/acme/app/models.User",
+			},
+		},
+		AnnotationTestData{
+			tplstr:   `{{/* some comment */}}{{.}}`,
+			expected: map[string]string{},
+		},
+	}
+
+	for i, testData := range allTestData {
+		got, err := parseDataAnnotations("x", testData.tplstr)
+		if err != nil {
+			t.Errorf("Test(%v): Unexpected error %v", i, err)
+			continue
+		}
+		if reflect.DeepEqual(got, testData.expected) == false {
+			t.Errorf("Test(%v): Unexpected annotations\nexpected=%v\ngot=     %v", i, testData.expected, got)
+		}
+	}
+}
+
+type InjectAnnotationTestData struct {
+	conf        string
+	imports     []string
+	annotations map[string]string
+	// the configuration expected after the injection
+	expected string
+	// the expected imports
+	expectedImports []string
+	// the expected error
+	expectedErr string
+}
+
+func TestInjectDataAnnotations(t *testing.T) {
+
+	allTestData := []InjectAnnotationTestData{
+		InjectAnnotationTestData{
+			conf: `compiled.TemplateConfiguration{TemplateName: "x"}`,
+			annotations: map[string]string{
+				"x": "github.com/mh-cbon/template-compiler/demo/data.MyTemplateData",
+			},
+			expected:        `compiled.TemplateConfiguration{TemplateName: "x", TemplatesData: map[string]interface{}{"x": data.MyTemplateData{}}}`,
+			expectedImports: []string{`"github.com/mh-cbon/template-compiler/demo/data"`},
+		},
+		InjectAnnotationTestData{
+			conf: `compiled.TemplateConfiguration{TemplatesData: map[string]interface{}{"*": nil, "x": aliasdata.MyTemplateData{}}}`,
+			imports: []string{
+				"github.com/mh-cbon/template-compiler/demo/data",
+			},
+			annotations: map[string]string{
+				"x":   "github.com/mh-cbon/template-compiler/demo/data.MyTemplateData",
+				"row": "*github.com/mh-cbon/template-compiler/demo/data.MyTemplateData",
+			},
+			expected:        `compiled.TemplateConfiguration{TemplatesData: map[string]interface{}{"*": nil, "x": aliasdata.MyTemplateData{}, "row": &data.MyTemplateData{}}}`,
+			expectedImports: []string{},
+		},
+		InjectAnnotationTestData{
+			conf: `compiled.TemplateConfiguration{TemplatesData: map[string]interface{}{}}`,
+			imports: []string{
+				"github.com/acme/data",
+			},
+			annotations: map[string]string{
+				"x": "github.com/mh-cbon/template-compiler/demo/data.MyTemplateData",
+			},
+			expected:        `compiled.TemplateConfiguration{TemplatesData: map[string]interface{}{"x": data1.MyTemplateData{}}}`,
+			expectedImports: []string{`data1 "github.com/mh-cbon/template-compiler/demo/data"`},
+		},
+		InjectAnnotationTestData{
+			conf: `compiled.TemplateConfiguration{}`,
+			annotations: map[string]string{
+				"x": "MyTemplateData",
+			},
+			expectedErr: `The data type MyTemplateData of the template x is not a pkgpath.Type annotation`,
+		},
+		InjectAnnotationTestData{
+			conf: `compiled.TemplateConfiguration{}`,
+			annotations: map[string]string{
+				"x": "github.com/mh-cbon/template-compiler/demo.TemplateData",
+			},
+			expectedErr: `Impossible to consume the datatype TemplateData located in the main package`,
+		},
+	}
+
+	for i, testData := range allTestData {
+		conf := stringToExpr(testData.conf).(*ast.CompositeLit)
+		imports := []*ast.ImportSpec{}
+		for _, i := range testData.imports {
+			imports = append(imports, &ast.ImportSpec{Path: &ast.BasicLit{Kind: token.STRING, Value: `"` + i + `"`}})
+		}
+		got, err := injectDataAnnotations(imports, conf, testData.annotations)
+		if testData.expectedErr != "" {
+			if err == nil || strings.Contains(err.Error(), testData.expectedErr) == false {
+				t.Errorf("Test(%v): Expected error %q, got %v", i, testData.expectedErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test(%v): Unexpected error %v", i, err)
+			continue
+		}
+		if s := printAst(conf); s != testData.expected {
+			t.Errorf("Test(%v): Unexpected configuration\nexpected=%v\ngot=     %v", i, testData.expected, s)
+		}
+		gotImports := []string{}
+		for _, spec := range got {
+			gotImports = append(gotImports, printAst(spec))
+		}
+		if reflect.DeepEqual(gotImports, testData.expectedImports) == false {
+			t.Errorf("Test(%v): Unexpected imports\nexpected=%v\ngot=     %v", i, testData.expectedImports, gotImports)
+		}
+	}
+}
+
+// printAst returns the go code of the node.
+func printAst(node interface{}) string {
+	var b bytes.Buffer
+	printer.Fprint(&b, token.NewFileSet(), node)
+	return b.String()
+}
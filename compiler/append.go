@@ -0,0 +1,280 @@
+package compiler
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// static names of the compiled template functions.
+const (
+	appendDstName    = "dst"
+	appendWriterName = "w"
+	appendBufferName = "bb"
+)
+
+// strconvAppendFuncs maps the strconv formatting funcs to their append form.
+var strconvAppendFuncs = map[string]string{
+	"Itoa":        "AppendInt",
+	"FormatInt":   "AppendInt",
+	"FormatUint":  "AppendUint",
+	"FormatFloat": "AppendFloat",
+	"FormatBool":  "AppendBool",
+	"Quote":       "AppendQuote",
+}
+
+// fmtAppendFuncs maps the fmt printing funcs to their append form.
+var fmtAppendFuncs = map[string]string{
+	"Fprintf":  "Appendf",
+	"Fprint":   "Append",
+	"Fprintln": "Appendln",
+}
+
+// addAppendFunc adds the append form of the compiled template function fnName,
+// such func fnaTplAppend(t parse.Templater, dst []byte, indata interface{}) ([]byte, error).
+// The writes to w are rewritten to appends to dst,
+// the remaining uses of w append to dst via a template.SliceWriter.
+func (c *CompiledTemplatesProgram) addAppendFunc(name, fnName string) {
+	var src *ast.FuncDecl
+	for _, f := range c.funcs {
+		if f.Name.Name == fnName {
+			src = f
+		}
+	}
+	// work on a copy of the function.
+	fn := stringToAst("package aa\n" + astNodeToString(src)).Decls[0].(*ast.FuncDecl)
+	sign := stringToAst(`package aa
+func ` + name + `(t parse.Templater, ` + appendDstName + ` []byte, indata interface{}) ([]byte, error) {}`)
+	fn.Name = sign.Decls[0].(*ast.FuncDecl).Name
+	fn.Type = sign.Decls[0].(*ast.FuncDecl).Type
+
+	fn.Body.List = c.appendStmts(fn.Body.List)
+
+	if usesIdent(fn.Body, appendBufferName) == false {
+		fn.Body.List = removeVarDecl(fn.Body.List, appendBufferName)
+	}
+	if usesIdent(fn.Body, appendWriterName) {
+		alias := c.addImport("github.com/mh-cbon/template-compiler/std/text/template")
+		wStmt := getStmtsAst(appendWriterName + ` := (*` + alias + `.SliceWriter)(&` + appendDstName + `)`)
+		fn.Body.List = append(wStmt, fn.Body.List...)
+	}
+	c.funcs = append(c.funcs, fn)
+}
+
+// appendStmts rewrites a list of statements to append to dst.
+func (c *CompiledTemplatesProgram) appendStmts(list []ast.Stmt) []ast.Stmt {
+	ret := make([]ast.Stmt, 0, len(list))
+	for _, stmt := range list {
+		stmt = c.appendStmt(stmt)
+		if n := len(ret); n > 0 {
+			if merged, ok := mergeBufferAppend(ret[n-1], stmt); ok {
+				ret[n-1] = merged
+				continue
+			}
+		}
+		ret = append(ret, stmt)
+	}
+	return ret
+}
+
+// appendStmt rewrites a statement to append to dst.
+// the returns are rewritten to return dst first.
+func (c *CompiledTemplatesProgram) appendStmt(stmt ast.Stmt) ast.Stmt {
+	switch s := stmt.(type) {
+	case *ast.IfStmt:
+		if x, ok := c.writeToAppend(s); ok {
+			return x
+		}
+		s.Body.List = c.appendStmts(s.Body.List)
+		switch e := s.Else.(type) {
+		case *ast.BlockStmt:
+			e.List = c.appendStmts(e.List)
+		case *ast.IfStmt:
+			if x := c.appendStmt(e); x != ast.Stmt(e) {
+				s.Else = &ast.BlockStmt{List: []ast.Stmt{x}}
+			}
+		}
+	case *ast.RangeStmt:
+		s.Body.List = c.appendStmts(s.Body.List)
+	case *ast.ForStmt:
+		s.Body.List = c.appendStmts(s.Body.List)
+	case *ast.BlockStmt:
+		s.List = c.appendStmts(s.List)
+	case *ast.SwitchStmt:
+		for _, cc := range s.Body.List {
+			cc.(*ast.CaseClause).Body = c.appendStmts(cc.(*ast.CaseClause).Body)
+		}
+	case *ast.TypeSwitchStmt:
+		for _, cc := range s.Body.List {
+			cc.(*ast.CaseClause).Body = c.appendStmts(cc.(*ast.CaseClause).Body)
+		}
+	case *ast.ReturnStmt:
+		if len(s.Results) == 1 {
+			s.Results = append([]ast.Expr{ast.NewIdent(appendDstName)}, s.Results...)
+		}
+	}
+	return stmt
+}
+
+// writeToAppend rewrites a checked write such
+// if _, werr := io.WriteString(w, x); werr != nil { return werr }
+// to dst = append(dst, x...)
+func (c *CompiledTemplatesProgram) writeToAppend(s *ast.IfStmt) (ast.Stmt, bool) {
+	init, ok := s.Init.(*ast.AssignStmt)
+	if ok == false || init.Tok != token.DEFINE || len(init.Rhs) != 1 || s.Else != nil {
+		return nil, false
+	}
+	call, ok := init.Rhs[0].(*ast.CallExpr)
+	if ok == false {
+		return nil, false
+	}
+	expr, ok := c.appendCallOf(call)
+	if ok == false {
+		return nil, false
+	}
+	return getStmtsAst(appendDstName + ` = ` + expr)[0], true
+}
+
+// appendCallOf returns the append form of a write call.
+func (c *CompiledTemplatesProgram) appendCallOf(call *ast.CallExpr) (string, bool) {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if ok == false || len(call.Args) == 0 || isIdent(call.Args[0], appendWriterName) == false {
+		// w.Write(x) is the only write with w as receiver.
+		if ok && isIdent(sel.X, appendWriterName) && sel.Sel.Name == "Write" && len(call.Args) == 1 {
+			return `append(` + appendDstName + `, ` + astNodeToString(call.Args[0]) + `...)`, true
+		}
+		return "", false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	if ok == false {
+		return "", false
+	}
+	args := []string{appendDstName}
+	for _, a := range call.Args[1:] {
+		args = append(args, astNodeToString(a))
+	}
+
+	if c.isImportAlias(pkg.Name, "io") && sel.Sel.Name == "WriteString" && len(call.Args) == 2 {
+		if x, ok := c.strconvAppendCallOf(call.Args[1]); ok {
+			return x, true
+		}
+		return `append(` + appendDstName + `, ` + args[1] + `...)`, true
+	}
+	if c.isImportAlias(pkg.Name, "fmt") {
+		if name, ok := fmtAppendFuncs[sel.Sel.Name]; ok {
+			return pkg.Name + `.` + name + `(` + strings.Join(args, ", ") + `)`, true
+		}
+	}
+	if c.isImportAlias(pkg.Name, "github.com/mh-cbon/template-compiler/std/html/template") && len(call.Args) == 2 {
+		for _, name := range streamingEscapers {
+			if name == sel.Sel.Name {
+				name = strings.TrimSuffix(name, "To") + "Append"
+				return pkg.Name + `.` + name + `(` + strings.Join(args, ", ") + `)`, true
+			}
+		}
+	}
+	return "", false
+}
+
+// strconvAppendCallOf returns the append form of a strconv formatting call,
+// strconv.Itoa(x) becomes strconv.AppendInt(dst, int64(x), 10).
+func (c *CompiledTemplatesProgram) strconvAppendCallOf(expr ast.Expr) (string, bool) {
+	call, ok := expr.(*ast.CallExpr)
+	if ok == false {
+		return "", false
+	}
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if ok == false {
+		return "", false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	if ok == false || c.isImportAlias(pkg.Name, "strconv") == false {
+		return "", false
+	}
+	name, ok := strconvAppendFuncs[sel.Sel.Name]
+	if ok == false {
+		return "", false
+	}
+	args := []string{appendDstName}
+	for _, a := range call.Args {
+		args = append(args, astNodeToString(a))
+	}
+	if sel.Sel.Name == "Itoa" {
+		args = []string{appendDstName, "int64(" + args[1] + ")", "10"}
+	}
+	return pkg.Name + `.` + name + `(` + strings.Join(args, ", ") + `)`, true
+}
+
+// mergeBufferAppend merges the formatting of a value into the scratch buffer
+// with the following write of that buffer,
+// bb = strconv.AppendInt(bb[:0], x, 10) and dst = append(dst, bb...)
+// become dst = strconv.AppendInt(dst, x, 10).
+func mergeBufferAppend(prev, cur ast.Stmt) (ast.Stmt, bool) {
+	p, ok := prev.(*ast.AssignStmt)
+	if ok == false || len(p.Lhs) != 1 || len(p.Rhs) != 1 || isIdent(p.Lhs[0], appendBufferName) == false {
+		return nil, false
+	}
+	fill, ok := p.Rhs[0].(*ast.CallExpr)
+	if ok == false || len(fill.Args) == 0 {
+		return nil, false
+	}
+	if s, ok := fill.Args[0].(*ast.SliceExpr); ok == false || isIdent(s.X, appendBufferName) == false {
+		return nil, false
+	}
+	if astNodeToString(cur) != appendDstName+` = append(`+appendDstName+`, `+appendBufferName+`...)` {
+		return nil, false
+	}
+	fill.Args[0] = ast.NewIdent(appendDstName)
+	p.Lhs[0] = ast.NewIdent(appendDstName)
+	return p, true
+}
+
+// isImportAlias tells if name is the alias of the imported pkgpath.
+func (c *CompiledTemplatesProgram) isImportAlias(name, pkgpath string) bool {
+	return c.hasImport(pkgpath) && c.addImport(pkgpath) == name
+}
+
+// isIdent tells if expr is the identifier name.
+func isIdent(expr ast.Expr, name string) bool {
+	i, ok := expr.(*ast.Ident)
+	return ok && i.Name == name
+}
+
+// usesIdent tells if the identifier name is used within node,
+// selected fields and methods are ignored.
+func usesIdent(node ast.Node, name string) bool {
+	found := false
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch x := n.(type) {
+		case *ast.SelectorExpr:
+			found = found || usesIdent(x.X, name)
+			return false
+		case *ast.ValueSpec:
+			// a declaration is not a use.
+			for _, v := range x.Values {
+				found = found || usesIdent(v, name)
+			}
+			return false
+		case *ast.Ident:
+			found = found || x.Name == name
+		}
+		return found == false
+	})
+	return found
+}
+
+// removeVarDecl removes the declaration var name ... from list.
+func removeVarDecl(list []ast.Stmt, name string) []ast.Stmt {
+	ret := list[:0]
+	for _, stmt := range list {
+		if d, ok := stmt.(*ast.DeclStmt); ok {
+			if g, ok := d.Decl.(*ast.GenDecl); ok && len(g.Specs) == 1 {
+				if v, ok := g.Specs[0].(*ast.ValueSpec); ok && len(v.Names) == 1 && v.Names[0].Name == name {
+					continue
+				}
+			}
+		}
+		ret = append(ret, stmt)
+	}
+	return ret
+}
@@ -0,0 +1,171 @@
+package compiler
+
+import (
+	"go/ast"
+	"testing"
+)
+
+type AppendTestData struct {
+	fn       string
+	expected string
+	// the imports expected to be added to the program.
+	expectedImports []string
+}
+
+func TestAppendFunc(t *testing.T) {
+
+	allDataTest := []AppendTestData{
+		AppendTestData{
+			fn: `func fn(t parse.Templater, w io.Writer, indata interface{}) error {
+  if _, werr := w.Write(builtin0); werr != nil {
+    return werr
+  }
+  var var0 string = "hello"
+  if _, werr := io.WriteString(w, var0); werr != nil {
+    return werr
+  }
+  if werr := template.HTMLEscaperTo(w, var0); werr != nil {
+    return werr
+  }
+  return nil
+}`,
+			expected: `func fnAppend(t parse.Templater, dst []byte, indata interface{}) ([]byte, error) {
+  dst = append(dst, builtin0...)
+  var var0 string = "hello"
+  dst = append(dst, var0...)
+  dst = template.HTMLEscaperAppend(dst, var0)
+  return dst, nil
+}`,
+		},
+		AppendTestData{
+			fn: `func fn(t parse.Templater, w io.Writer, indata interface{}) error {
+  var bb []byte
+  var tplY int = 4
+  if _, werr := io.WriteString(w, strconv.Itoa(tplY)); werr != nil {
+    return werr
+  }
+  bb = strconv.AppendQuote(bb[:0], "x")
+  if _, werr := w.Write(bb); werr != nil {
+    return werr
+  }
+  if _, werr := fmt.Fprintf(w, "%v", tplY); werr != nil {
+    return werr
+  }
+  return nil
+}`,
+			expected: `func fnAppend(t parse.Templater, dst []byte, indata interface{}) ([]byte, error) {
+  var tplY int = 4
+  dst = strconv.AppendInt(dst, int64(tplY), 10)
+  dst = strconv.AppendQuote(dst, "x")
+  dst = fmt.Appendf(dst, "%v", tplY)
+  return dst, nil
+}`,
+		},
+		AppendTestData{
+			fn: `func fn(t parse.Templater, w io.Writer, indata interface{}) error {
+  var data *aliasdata.MyTemplateData
+  if d, ok := indata.(*aliasdata.MyTemplateData); ok {
+    data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants *data.MyTemplateData", indata)
+  }
+  if data == nil {
+    if _, werr := fmt.Fprintf(w, "%v", data); werr != nil {
+      return werr
+    }
+  } else if _, werr := data.WriteTo(w); werr != nil {
+    return werr
+  }
+  for _, iterable := range data.Items {
+    if werr := t.ExecuteTemplate(w, "z", iterable); werr != nil {
+      return werr
+    }
+  }
+  return nil
+}`,
+			expected: `func fnAppend(t parse.Templater, dst []byte, indata interface{}) ([]byte, error) {
+  w := (*aliastemplate.SliceWriter)(&dst)
+  var data *aliasdata.MyTemplateData
+  if d, ok := indata.(*aliasdata.MyTemplateData); ok {
+    data = d
+  } else if indata != nil {
+    return dst, fmt.Errorf("template: : unexpected data type %T, wants *data.MyTemplateData", indata)
+  }
+  if data == nil {
+    dst = fmt.Appendf(dst, "%v", data)
+  } else if _, werr := data.WriteTo(w); werr != nil {
+    return dst, werr
+  }
+  for _, iterable := range data.Items {
+    if werr := t.ExecuteTemplate(w, "z", iterable); werr != nil {
+      return dst, werr
+    }
+  }
+  return dst, nil
+}`,
+			expectedImports: []string{
+				"aliastemplate:github.com/mh-cbon/template-compiler/std/text/template",
+			},
+		},
+		AppendTestData{
+			fn: `func fn(t parse.Templater, w io.Writer, indata interface{}) error {
+  var var0 *bytes.Buffer = nil
+  if var0 == nil {
+    if _, werr := fmt.Fprintf(w, "%v", var0); werr != nil {
+      return werr
+    }
+  } else if _, werr := w.Write(var0.Bytes()); werr != nil {
+    return werr
+  }
+  return nil
+}`,
+			expected: `func fnAppend(t parse.Templater, dst []byte, indata interface{}) ([]byte, error) {
+  var var0 *bytes.Buffer = nil
+  if var0 == nil {
+    dst = fmt.Appendf(dst, "%v", var0)
+  } else {
+    dst = append(dst, var0.Bytes()...)
+  }
+  return dst, nil
+}`,
+		},
+	}
+
+	for i, dataTest := range allDataTest {
+		c := NewCompiledTemplatesProgram("xx")
+		c.addImport("io")
+		c.addImport("fmt")
+		c.addImport("strconv")
+		c.addImport("github.com/mh-cbon/template-compiler/std/html/template")
+		importsLen := len(c.imports)
+		c.funcs = append(c.funcs, stringToAst("package aa\n" + dataTest.fn).Decls[0].(*ast.FuncDecl))
+
+		c.addAppendFunc("fnAppend", "fn")
+
+		if len(c.funcs) != 2 {
+			t.Errorf("Test(%v): Expected to get 2 functions, but found %v", i, len(c.funcs))
+			continue
+		}
+		got := formatGoCode(astNodeToString(c.funcs[1]))
+		expected := formatGoCode(dataTest.expected)
+		if got != expected {
+			t.Errorf("Test(%v): Unexpected content of append function\nexpected=\n%v\ngot\n%v", i, expected, got)
+			continue
+		}
+		src := formatGoCode(astNodeToString(c.funcs[0]))
+		if src != formatGoCode(dataTest.fn) {
+			t.Errorf("Test(%v): The source function was modified\n%v", i, src)
+			continue
+		}
+		imports := convertImportsSpecs(c.imports[importsLen:])
+		if len(imports) != len(dataTest.expectedImports) {
+			t.Errorf("Test(%v): Expected to get %v new imports, but found %v", i, len(dataTest.expectedImports), imports)
+			continue
+		}
+		for _, im := range dataTest.expectedImports {
+			if containsStr(imports, im) == false {
+				t.Errorf("Test(%v): Expected to find import=%v", i, im)
+			}
+		}
+	}
+}
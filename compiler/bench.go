@@ -0,0 +1,146 @@
+package compiler
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// BenchmarkPattern matches the generated benchmarks, to pass to go test -bench.
+const BenchmarkPattern = "^Benchmark(Compiled|Interpreted)"
+
+// generateBenchmarks generates the benchmarks of the template name,
+// they render the compiled template and the interpreted template over its samples.
+func (p *testProgram) generateBenchmarks(varName, name, jit, samples string, funcsErr error) string {
+	compiledName := p.uniqueName("BenchmarkCompiled" + exportedIdent(name))
+	interpretedName := p.uniqueName("BenchmarkInterpreted" + exportedIdent(name))
+
+	bench := ""
+	bench += fmt.Sprintf("// %v renders the compiled template %q.\n", compiledName, name)
+	bench += fmt.Sprintf("func %v(b *testing.B) {\n", compiledName)
+	bench += fmt.Sprintf("\ttpl := %v.MustGet(%q)\n", varName, name)
+	bench += fmt.Sprintf("\tsamples := %v\n", samples)
+	bench += "\tb.ReportAllocs()\n"
+	bench += "\tb.ResetTimer()\n"
+	bench += "\tfor n := 0; n < b.N; n++ {\n"
+	bench += "\t\ttpl.Execute(ioutil.Discard, samples[n%len(samples)])\n"
+	bench += "\t}\n"
+	bench += "}\n\n"
+
+	bench += fmt.Sprintf("// %v renders the interpreted template %q.\n", interpretedName, name)
+	bench += fmt.Sprintf("func %v(b *testing.B) {\n", interpretedName)
+	if funcsErr != nil {
+		bench += fmt.Sprintf("\tb.Skip(%q)\n", funcsErr.Error())
+		bench += "}\n\n"
+		return bench
+	}
+	bench += fmt.Sprintf("\tjit, err := %v\n", jit)
+	bench += "\tif err != nil {\n\t\tb.Fatal(err)\n\t}\n"
+	bench += fmt.Sprintf("\tsamples := %v\n", samples)
+	bench += "\tb.ReportAllocs()\n"
+	bench += "\tb.ResetTimer()\n"
+	bench += "\tfor n := 0; n < b.N; n++ {\n"
+	bench += fmt.Sprintf("\t\tjit.ExecuteTemplate(ioutil.Discard, %q, samples[n%%len(samples)])\n", name)
+	bench += "\t}\n"
+	bench += "}\n\n"
+	return bench
+}
+
+// BenchmarkResult is the measure of a benchmark.
+type BenchmarkResult struct {
+	NsPerOp     float64
+	BytesPerOp  int64
+	AllocsPerOp int64
+}
+
+// BenchmarkComparison is the measures of a compiled template and its interpreted template.
+type BenchmarkComparison struct {
+	Name        string
+	Compiled    *BenchmarkResult
+	Interpreted *BenchmarkResult
+}
+
+// ParseBenchmarks reads the output of go test -bench -benchmem,
+// it returns the comparisons of the generated benchmarks in their order of appearance.
+func ParseBenchmarks(r io.Reader) ([]*BenchmarkComparison, error) {
+	ret := []*BenchmarkComparison{}
+	byName := map[string]*BenchmarkComparison{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		name := fields[0]
+		// remove the GOMAXPROCS suffix.
+		if i := strings.LastIndex(name, "-"); i > -1 {
+			if _, err := strconv.Atoi(name[i+1:]); err == nil {
+				name = name[:i]
+			}
+		}
+		var compiled bool
+		if strings.HasPrefix(name, "BenchmarkCompiled") {
+			name, compiled = strings.TrimPrefix(name, "BenchmarkCompiled"), true
+		} else if strings.HasPrefix(name, "BenchmarkInterpreted") {
+			name = strings.TrimPrefix(name, "BenchmarkInterpreted")
+		} else {
+			continue
+		}
+		result := &BenchmarkResult{}
+		for i := 2; i+1 < len(fields); i += 2 {
+			var err error
+			switch fields[i+1] {
+			case "ns/op":
+				result.NsPerOp, err = strconv.ParseFloat(fields[i], 64)
+			case "B/op":
+				result.BytesPerOp, err = strconv.ParseInt(fields[i], 10, 64)
+			case "allocs/op":
+				result.AllocsPerOp, err = strconv.ParseInt(fields[i], 10, 64)
+			}
+			if err != nil {
+				return ret, fmt.Errorf("Failed to parse the benchmark %v: %v", fields[0], err)
+			}
+		}
+		c, ok := byName[name]
+		if !ok {
+			c = &BenchmarkComparison{Name: name}
+			byName[name] = c
+			ret = append(ret, c)
+		}
+		if compiled {
+			c.Compiled = result
+		} else {
+			c.Interpreted = result
+		}
+	}
+	return ret, scanner.Err()
+}
+
+// WriteBenchmarkTable writes a table of the comparisons to w.
+func WriteBenchmarkTable(w io.Writer, comparisons []*BenchmarkComparison) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "template\tcompiled ns/op\tinterpreted ns/op\tspeedup\tcompiled B/op\tinterpreted B/op\tcompiled allocs/op\tinterpreted allocs/op")
+	for _, c := range comparisons {
+		compiled, interpreted := c.Compiled, c.Interpreted
+		if compiled == nil {
+			compiled = &BenchmarkResult{}
+		}
+		if interpreted == nil {
+			interpreted = &BenchmarkResult{}
+		}
+		speedup := "-"
+		if compiled.NsPerOp > 0 && interpreted.NsPerOp > 0 {
+			speedup = fmt.Sprintf("%.2fx", interpreted.NsPerOp/compiled.NsPerOp)
+		}
+		fmt.Fprintf(tw, "%v\t%.0f\t%.0f\t%v\t%v\t%v\t%v\t%v\n",
+			c.Name,
+			compiled.NsPerOp, interpreted.NsPerOp, speedup,
+			compiled.BytesPerOp, interpreted.BytesPerOp,
+			compiled.AllocsPerOp, interpreted.AllocsPerOp,
+		)
+	}
+	return tw.Flush()
+}
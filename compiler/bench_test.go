@@ -0,0 +1,54 @@
+package compiler
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseBenchmarks(t *testing.T) {
+	out := `goos: linux
+goarch: amd64
+pkg: github.com/mh-cbon/template-compiler/demo
+BenchmarkCompiledATpl-8      	20000000	        78.9 ns/op	      48 B/op	       1 allocs/op
+BenchmarkInterpretedATpl-8   	 3000000	       668 ns/op	      96 B/op	       2 allocs/op
+BenchmarkRenderWithJitTemplateA-8        	 3000000	       603 ns/op	      96 B/op	       2 allocs/op
+BenchmarkInterpretedTt       	 3000000	       500 ns/op	      64 B/op	       2 allocs/op
+PASS
+ok  	github.com/mh-cbon/template-compiler/demo	12.345s
+`
+	expected := []*BenchmarkComparison{
+		&BenchmarkComparison{
+			Name:        "ATpl",
+			Compiled:    &BenchmarkResult{NsPerOp: 78.9, BytesPerOp: 48, AllocsPerOp: 1},
+			Interpreted: &BenchmarkResult{NsPerOp: 668, BytesPerOp: 96, AllocsPerOp: 2},
+		},
+		&BenchmarkComparison{
+			Name:        "Tt",
+			Interpreted: &BenchmarkResult{NsPerOp: 500, BytesPerOp: 64, AllocsPerOp: 2},
+		},
+	}
+	got, err := ParseBenchmarks(strings.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reflect.DeepEqual(got, expected) == false {
+		t.Fatalf("Unexpected benchmarks expected=%v, got=%v", expected, got)
+	}
+
+	var b bytes.Buffer
+	if err := WriteBenchmarkTable(&b, got); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines in the table, got\n%v", b.String())
+	}
+	if fields := strings.Fields(lines[1]); reflect.DeepEqual(fields, []string{"ATpl", "79", "668", "8.47x", "48", "96", "1", "2"}) == false {
+		t.Errorf("Unexpected table row %v", fields)
+	}
+	if fields := strings.Fields(lines[2]); reflect.DeepEqual(fields, []string{"Tt", "0", "500", "-", "0", "64", "0", "2"}) == false {
+		t.Errorf("Unexpected table row %v", fields)
+	}
+}
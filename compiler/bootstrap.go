@@ -121,9 +121,12 @@ func prepareConfiguration(importsContext []*ast.ImportSpec, confNode *ast.GenDec
 	compiledNew := confNode.Specs[0].(*ast.ValueSpec).Values[0].(*ast.CallExpr)
 	// need to check the Fun Call is compiled.New,
 	// it might not be if the callexpr compiled.New is followed
-	// byt a SetPkg(...) call,
+	// byt SetPkg(...), SetTests(...) or SetBenchmarks(...) calls,
 	// in such case callExpr.Fun.X is not an ast.ident.
-	if _, ok := compiledNew.Fun.(*ast.SelectorExpr).X.(*ast.Ident); ok == false {
+	for {
+		if _, ok := compiledNew.Fun.(*ast.SelectorExpr).X.(*ast.Ident); ok {
+			break
+		}
 		// not compiled.New
 		compiledNew = compiledNew.Fun.(*ast.SelectorExpr).X.(*ast.CallExpr)
 	}
@@ -166,13 +169,18 @@ func prepareConfiguration(importsContext []*ast.ImportSpec, confNode *ast.GenDec
 	// - for an HTML key, check if it says true/false
 	// - for a Data key, creates and adds a DataConfiguration{}
 	// - for a TemplatesData key, searches for all related package and import them
+	// - for the @data annotations of the templates, adds their data types to the TemplatesData key
 	// - for a FuncsMap key, exports them to their symbolic version, and their public idents,
 	//   add those new data to the configuration of the template.
+	// - for a TemplatesSamples key, removes it, the samples are consumed by the generated tests only.
 	templatesConf := compiledNew.Args[1].(*ast.CompositeLit)
 
 	for _, t := range templatesConf.Elts {
 		templateConf := t.(*ast.CompositeLit)
 
+		// manage TemplatesSamples key
+		removeKeyValue(templateConf, "TemplatesSamples")
+
 		// manage HTML key
 		isHTML := isAnHTMLTemplateConf(templateConf)
 
@@ -183,6 +191,22 @@ func prepareConfiguration(importsContext []*ast.ImportSpec, confNode *ast.GenDec
 			}
 		}
 
+		// manage the data annotations of the templates,
+		// add their data types to the TemplatesData key and import them.
+		annotations, err := getDataAnnotations(templateConf)
+		if err != nil {
+			return newImports, err
+		}
+		programImports := append([]*ast.ImportSpec{
+			export.NewImportSpec("fmt", ""),
+			export.NewImportSpec("github.com/mh-cbon/template-compiler/compiler", ""),
+		}, newImports...)
+		imports, err := injectDataAnnotations(programImports, templateConf, annotations)
+		if err != nil {
+			return newImports, err
+		}
+		newImports = append(newImports, imports...)
+
 		// manage FuncsMap key
 		var varToExport []string
 		if funcsMapKey := getKeyValue(templateConf, "FuncsMap"); funcsMapKey != nil {
@@ -248,6 +272,20 @@ func getKeyValue(templateConf *ast.CompositeLit, search string) *ast.KeyValueExp
 	return nil
 }
 
+// from a ast.CompositeLit such
+// TypeOfData{Key: value}, removes the key/value
+// matching search
+func removeKeyValue(templateConf *ast.CompositeLit, search string) {
+	elts := templateConf.Elts[:0]
+	for _, keyValue := range templateConf.Elts {
+		if ident, ok := keyValue.(*ast.KeyValueExpr).Key.(*ast.Ident); ok && ident.Name == search {
+			continue
+		}
+		elts = append(elts, keyValue)
+	}
+	templateConf.Elts = elts
+}
+
 // transforms the ast.Node of a value
 // []string{"",""...} into a slice of string values.
 func getFuncsMapKeyValues(value *ast.CompositeLit) []string {
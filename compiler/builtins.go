@@ -0,0 +1,79 @@
+package compiler
+
+import (
+	"fmt"
+	"reflect"
+
+	htmltemplate "github.com/mh-cbon/template-compiler/std/html/template"
+	texttemplate "github.com/mh-cbon/template-compiler/std/text/template"
+)
+
+// builtinFunc describes a builtin func of the templates,
+// fn has the signature of the builtin,
+// pkgs are the packages of its public identifier, it is empty for the unexported builtins.
+type builtinFunc struct {
+	fn   interface{}
+	pkgs []string
+}
+
+var (
+	textTemplatePkgs = []string{"text/template", "github.com/mh-cbon/template-compiler/std/text/template"}
+	htmlTemplatePkgs = []string{"github.com/mh-cbon/template-compiler/std/html/template"}
+)
+
+// builtinFuncs are the builtin funcs the converter evaluates or inlines.
+var builtinFuncs = map[string]builtinFunc{
+	"and":      builtinFunc{fn: (func(interface{}, ...interface{}) interface{})(nil)},
+	"or":       builtinFunc{fn: (func(interface{}, ...interface{}) interface{})(nil)},
+	"not":      builtinFunc{fn: (func(interface{}) bool)(nil)},
+	"len":      builtinFunc{fn: (func(interface{}) (int, error))(nil)},
+	"eq":       builtinFunc{fn: (func(interface{}, ...interface{}) (bool, error))(nil)},
+	"ne":       builtinFunc{fn: (func(interface{}, interface{}) (bool, error))(nil)},
+	"lt":       builtinFunc{fn: (func(interface{}, interface{}) (bool, error))(nil)},
+	"le":       builtinFunc{fn: (func(interface{}, interface{}) (bool, error))(nil)},
+	"gt":       builtinFunc{fn: (func(interface{}, interface{}) (bool, error))(nil)},
+	"ge":       builtinFunc{fn: (func(interface{}, interface{}) (bool, error))(nil)},
+	"html":     builtinFunc{fn: texttemplate.HTMLEscaper, pkgs: textTemplatePkgs},
+	"js":       builtinFunc{fn: texttemplate.JSEscaper, pkgs: textTemplatePkgs},
+	"urlquery": builtinFunc{fn: texttemplate.URLQueryEscaper, pkgs: textTemplatePkgs},
+	"print":    builtinFunc{fn: fmt.Sprint, pkgs: []string{"fmt"}},
+	"printf":   builtinFunc{fn: fmt.Sprintf, pkgs: []string{"fmt"}},
+	"println":  builtinFunc{fn: fmt.Sprintln, pkgs: []string{"fmt"}},
+
+	"_html_template_attrescaper":     builtinFunc{fn: htmltemplate.AttrEscaper, pkgs: htmlTemplatePkgs},
+	"_html_template_commentescaper":  builtinFunc{fn: htmltemplate.CommentEscaper, pkgs: htmlTemplatePkgs},
+	"_html_template_cssescaper":      builtinFunc{fn: htmltemplate.CSSEscaper, pkgs: htmlTemplatePkgs},
+	"_html_template_cssvaluefilter":  builtinFunc{fn: htmltemplate.CSSValueFilter, pkgs: htmlTemplatePkgs},
+	"_html_template_htmlnamefilter":  builtinFunc{fn: htmltemplate.HTMLNameFilter, pkgs: htmlTemplatePkgs},
+	"_html_template_htmlescaper":     builtinFunc{fn: htmltemplate.HTMLTextEscaper, pkgs: htmlTemplatePkgs},
+	"_html_template_jsregexpescaper": builtinFunc{fn: htmltemplate.JSRegexpEscaper, pkgs: htmlTemplatePkgs},
+	"_html_template_jsstrescaper":    builtinFunc{fn: htmltemplate.JSStrEscaper, pkgs: htmlTemplatePkgs},
+	"_html_template_jsvalescaper":    builtinFunc{fn: htmltemplate.JSValEscaper, pkgs: htmlTemplatePkgs},
+	"_html_template_nospaceescaper":  builtinFunc{fn: htmltemplate.HTMLNospaceEscaper, pkgs: htmlTemplatePkgs},
+	"_html_template_rcdataescaper":   builtinFunc{fn: htmltemplate.RcdataEscaper, pkgs: htmlTemplatePkgs},
+	"_html_template_urlescaper":      builtinFunc{fn: htmltemplate.URLEscaper, pkgs: htmlTemplatePkgs},
+	"_html_template_urlfilter":       builtinFunc{fn: htmltemplate.URLFilter, pkgs: htmlTemplatePkgs},
+	"_html_template_urlnormalizer":   builtinFunc{fn: (func(...interface{}) string)(nil)},
+}
+
+// isBuiltinFunc tells if the func name of a funcmap is the builtin func of the templates,
+// a funcmap can override the builtins.
+// The funcmap holds the exported signatures of the funcs, not the funcs,
+// the builtin is identified by its signature and by the package of its public identifier.
+func isBuiltinFunc(name string, funcs map[string]interface{}, publicIdents []map[string]string) bool {
+	builtin, ok := builtinFuncs[name]
+	if ok == false {
+		return false
+	}
+	fn, ok := funcs[name]
+	if ok == false || reflect.TypeOf(fn) != reflect.TypeOf(builtin.fn) {
+		return false
+	}
+	// the first public identifier is called, see identifierToPublicCall.
+	for _, i := range publicIdents {
+		if i["FuncName"] == name {
+			return containsStr(builtin.pkgs, i["Pkg"])
+		}
+	}
+	return len(builtin.pkgs) == 0
+}
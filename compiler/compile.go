@@ -3,6 +3,7 @@ package compiler
 import (
 	"fmt"
 	"go/ast"
+	"go/build"
 	html "html/template"
 	"io/ioutil"
 	"os"
@@ -11,19 +12,48 @@ import (
 	"strings"
 	text "text/template"
 	"text/template/parse"
+	"unicode"
 
 	"github.com/mh-cbon/template-compiler/compiled"
+	texttemplate "github.com/mh-cbon/template-compiler/std/text/template"
 	"github.com/mh-cbon/template-tree-simplifier/simplifier"
 	"github.com/serenize/snaker"
 )
 
+// renderFunc is a typed function to render a template.
+type renderFunc struct {
+	doc string
+	fn  *ast.FuncDecl
+}
+
 // CompiledTemplatesProgram ...
 type CompiledTemplatesProgram struct {
 	varName      string
+	templates    []*TemplateToCompile
 	imports      []*ast.ImportSpec
 	funcs        []*ast.FuncDecl
+	renderFuncs  []renderFunc
 	idents       []string
 	builtinTexts map[string]string
+	// funcsMap are the funcmaps shared by all the templates.
+	funcsMap []string
+	// pkgPath is the import path of the output program.
+	pkgPath string
+	// linked are the templates called statically, by name.
+	linked map[string]*linkedTemplate
+	// recursive are the template calls which remain dynamic, by caller then callee name.
+	recursive map[string]map[string]bool
+	// execErrors are the error returns of the converted actions, see addExecErrors.
+	execErrors map[*ast.ReturnStmt]execErrorAt
+	// outPath is the path of the output program.
+	outPath string
+	// lineFiles are the paths of the template files written into the //line directives, by tree.
+	lineFiles map[*parse.Tree]string
+	// requiredFuncs are the types of the funcs looked up at runtime, by template then func name.
+	requiredFuncs map[string]map[string]string
+	// warnings are the issues of the output program which do not prevent its generation,
+	// CompileAndWrite prints them.
+	warnings []string
 }
 
 // NewCompiledTemplatesProgram prepare a new instance.
@@ -33,9 +63,13 @@ func NewCompiledTemplatesProgram(varName string) *CompiledTemplatesProgram {
 	ret := &CompiledTemplatesProgram{
 		varName: varName,
 		idents: []string{
-			"t", "b", "w", "werr", "data", "indata", varName,
+			"t", "b", "w", "bb", "dst", "werr", "data", "indata", "ctx", "limiter", "observer", varName,
 		},
-		builtinTexts: map[string]string{},
+		builtinTexts:  map[string]string{},
+		linked:        map[string]*linkedTemplate{},
+		execErrors:    map[*ast.ReturnStmt]execErrorAt{},
+		lineFiles:     map[*parse.Tree]string{},
+		requiredFuncs: map[string]map[string]string{},
 	}
 	ret.addImport("io")
 	ret.addImport("github.com/mh-cbon/template-compiler/std/text/template/parse")
@@ -48,9 +82,18 @@ func (c *CompiledTemplatesProgram) CompileAndWrite(config *compiled.Configuratio
 	if err != nil {
 		return err
 	}
+	for _, w := range c.warnings {
+		fmt.Fprintf(os.Stderr, "template-compiler: warning: %v\n", w)
+	}
 	if err := ioutil.WriteFile(config.OutPath, []byte(program), os.ModePerm); err != nil {
 		return fmt.Errorf("Failed to write the compiled templates: %v", err)
 	}
+	if config.Tests || config.Benchmarks {
+		tests := c.generateTestProgram(config)
+		if err := ioutil.WriteFile(testPath(config.OutPath), []byte(tests), os.ModePerm); err != nil {
+			return fmt.Errorf("Failed to write the compiled templates tests: %v", err)
+		}
+	}
 	return nil
 }
 
@@ -65,6 +108,12 @@ func (c *CompiledTemplatesProgram) Compile(config *compiled.Configuration) (stri
 		return "", err
 	}
 
+	c.funcsMap = config.FuncsMap
+	c.outPath = config.OutPath
+	if pkg, err := build.Default.ImportDir(filepath.Dir(config.OutPath), build.FindOnly); err == nil {
+		c.pkgPath = pkg.ImportPath
+	}
+
 	return c.compileTemplates(config.OutPkg, templatesToCompile)
 }
 
@@ -73,10 +122,13 @@ func (c *CompiledTemplatesProgram) compileTemplates(outpkg string, templatesToCo
 	if err := c.convertTemplates(templatesToCompile); err != nil {
 		return "", err
 	}
+	c.templates = templatesToCompile
 	return c.generateProgram(outpkg, templatesToCompile), nil
 }
 
 // convertTemplates convert each TemplateToCompile into functions.
+// The funcs of all the templates are named first, such as the template calls
+// are converted to static calls of their funcs.
 func (c *CompiledTemplatesProgram) convertTemplates(templatesToCompile []*TemplateToCompile) error {
 	for _, t := range templatesToCompile {
 		for _, f := range t.files {
@@ -88,6 +140,33 @@ func (c *CompiledTemplatesProgram) convertTemplates(templatesToCompile []*Templa
 				if err != nil {
 					return err
 				}
+				data, err := t.getData(name)
+				if err != nil {
+					return err
+				}
+				c.linkTemplate(name, f.tplsFunc[name], data, dataConfig)
+			}
+		}
+	}
+	trees := map[string][]*parse.Tree{}
+	for _, t := range templatesToCompile {
+		for _, f := range t.files {
+			for name, tree := range f.tplsTree {
+				trees[name] = append(trees[name], tree)
+			}
+		}
+	}
+	c.recursive = recursiveCalls(trees)
+	for _, t := range templatesToCompile {
+		for _, f := range t.files {
+			for _, name := range f.names() {
+				dataConfig, err := t.getDataConfiguration(name)
+				if err != nil {
+					return err
+				}
+				if f.path != "" && c.outPath != "" {
+					c.lineFiles[f.tplsTree[name]] = c.lineFile(f.path)
+				}
 
 				err = convertTplTree(
 					f.tplsFunc[name],
@@ -101,6 +180,24 @@ func (c *CompiledTemplatesProgram) convertTemplates(templatesToCompile []*Templa
 				if err != nil {
 					return err
 				}
+				c.addExecErrors()
+				c.addRangeLimits(f.tplsFunc[name], name)
+				c.addObservedCalls(f.tplsFunc[name])
+
+				f.tplsAppendFunc[name] = c.makeFuncName(f.tplsFunc[name] + "Append")
+				c.addAppendFunc(f.tplsAppendFunc[name], f.tplsFunc[name])
+
+				c.addRenderFunc(name, dataConfig)
+			}
+		}
+	}
+	c.addDataFuncs(templatesToCompile)
+	c.addContextFuncs(templatesToCompile)
+	// estimate the sizes once all the trees are folded.
+	for _, t := range templatesToCompile {
+		for _, f := range t.files {
+			for _, name := range f.names() {
+				f.tplsSizeHint[name] = estimateSize(f.tplsTree, name, t.sizeProfile)
 			}
 		}
 	}
@@ -134,10 +231,13 @@ func updateOutPkg(conf *compiled.Configuration) error {
 
 // getDataQualifier returns the contextualized data qualifer for the program imports.
 func (c *CompiledTemplatesProgram) getDataQualifier(dataConf compiled.DataConfiguration) string {
-	dataAlias := c.addImport(dataConf.PkgPath)
-	dataQualifier := fmt.Sprintf("%v.%v", dataAlias, dataConf.DataTypeName)
+	dataQualifier := dataConf.DataTypeName
+	if dataConf.PkgPath != "" { // not a builtin type.
+		dataAlias := c.addImport(dataConf.PkgPath)
+		dataQualifier = fmt.Sprintf("%v.%v", dataAlias, dataConf.DataTypeName)
+	}
 	if dataConf.IsPtr {
-		dataQualifier = fmt.Sprintf("*%v.%v", dataAlias, dataConf.DataTypeName)
+		dataQualifier = "*" + dataQualifier
 	}
 	return dataQualifier
 }
@@ -221,6 +321,59 @@ func (c *CompiledTemplatesProgram) makeFuncName(baseName string) string {
 	return x
 }
 
+// makeExportedFuncName produces a new unique exported func name.
+func (c *CompiledTemplatesProgram) makeExportedFuncName(baseName string) string {
+	x := baseName
+	i := 1
+	for c.isCollidingIdent(x) {
+		x = fmt.Sprintf("%v%v", baseName, i)
+		i++
+	}
+	c.idents = append(c.idents, x)
+	return x
+}
+
+// addRenderFunc adds typed functions to render the template with given name,
+// such func RenderATpl(w io.Writer, data *pkg.Data) error
+// and func AppendRenderATpl(dst []byte, data *pkg.Data) ([]byte, error).
+// They are not added when the data type of the template is unknown.
+func (c *CompiledTemplatesProgram) addRenderFunc(name string, dataConf compiled.DataConfiguration) {
+	if dataConf.DataTypeName == "" {
+		return
+	}
+	dataQualifier := c.getDataQualifier(dataConf)
+
+	funcName := c.makeExportedFuncName("Render" + exportedIdent(name))
+	gocode := fmt.Sprintf(
+		`package aa
+func %v(w io.Writer, data %v) error {
+	return %v.MustGet(%q).Execute(w, data)
+}`,
+		funcName, dataQualifier,
+		c.varName, name,
+	)
+	f := stringToAst(gocode)
+	c.renderFuncs = append(c.renderFuncs, renderFunc{
+		doc: fmt.Sprintf("%v renders the template %q.", funcName, name),
+		fn:  f.Decls[0].(*ast.FuncDecl),
+	})
+
+	funcName = c.makeExportedFuncName("AppendRender" + exportedIdent(name))
+	gocode = fmt.Sprintf(
+		`package aa
+func %v(dst []byte, data %v) ([]byte, error) {
+	return %v.MustGet(%q).AppendExecute(dst, data)
+}`,
+		funcName, dataQualifier,
+		c.varName, name,
+	)
+	f = stringToAst(gocode)
+	c.renderFuncs = append(c.renderFuncs, renderFunc{
+		doc: fmt.Sprintf("%v appends the template %q to dst.", funcName, name),
+		fn:  f.Decls[0].(*ast.FuncDecl),
+	})
+}
+
 // createFunc creates the ast code of a compiled template function with given name.
 func (c *CompiledTemplatesProgram) createFunc(name string) *ast.FuncDecl {
 	gocode := fmt.Sprintf(
@@ -244,16 +397,53 @@ func (c *CompiledTemplatesProgram) addBuiltintText(text string) string {
 }
 
 // generateInitFunc generates the init func body.
-// the init func contains the code to declare the compiled templates
+// the init func contains the code to add the funcmaps to the configuration,
+// to declare the compiled templates, the funcs they look up at runtime,
 // and associate their defined templates.
+// The funcmaps not accessible from the output program are reported in the warnings
+// when their templates look up funcs at runtime.
 func (c *CompiledTemplatesProgram) generateInitFunc(tpls []*TemplateToCompile) string {
 	initfunc := ""
 	initfunc += fmt.Sprintf("func init () {\n")
+	registryFuncs := map[string]bool{}
+	for i, t := range tpls {
+		for _, target := range append(append([]string{}, c.funcsMap...), t.FuncsMap...) {
+			if containsStr(defaultFuncsMap, target) {
+				continue
+			}
+			// the funcmaps not accessible are not available to the development mode.
+			pkgpath, variable, err := resolveFuncsMap(target, c.pkgPath, "the output program")
+			if err != nil {
+				if name, funcs := c.firstRequiredFuncs(t); name != "" {
+					c.warnings = append(c.warnings, fmt.Sprintf(
+						"%v, the template %q looks up the funcs %v at runtime, add them to the registry before it is executed",
+						err, name, funcs))
+				}
+				continue
+			}
+			if pkgpath != "" {
+				variable = fmt.Sprintf("%v.%v", c.addImport(pkgpath), variable)
+			}
+			initfunc += fmt.Sprintf("  %v.Templates[%v].AddFuncs(%v)\n", c.varName, i, variable)
+			if registryFuncs[variable] == false {
+				initfunc += fmt.Sprintf("  %v.Registry.Funcs(%v)\n", c.varName, variable)
+				registryFuncs[variable] = true
+			}
+		}
+	}
 	for _, t := range tpls {
 		for _, f := range t.files {
 			for _, name := range f.names() {
 				funcname := f.tplsFunc[name]
 				initfunc += fmt.Sprintf("  %v.Add(%#v, %v)\n", c.varName, name, funcname)
+				initfunc += fmt.Sprintf("  %v.AddAppend(%#v, %v)\n", c.varName, name, f.tplsAppendFunc[name])
+				initfunc += fmt.Sprintf("  %v.AddContext(%#v, %v)\n", c.varName, name, f.tplsContextFunc[name])
+				if n := f.tplsSizeHint[name]; n > 0 {
+					initfunc += fmt.Sprintf("  %v.SetSizeHint(%#v, %v)\n", c.varName, name, n)
+				}
+				if funcs := c.requiredFuncs[name]; len(funcs) > 0 {
+					initfunc += fmt.Sprintf("  %v.RequireFuncs(%#v, %v)\n", c.varName, name, requiredFuncsLit(funcs))
+				}
 			}
 		}
 	}
@@ -275,16 +465,63 @@ func (c *CompiledTemplatesProgram) generateInitFunc(tpls []*TemplateToCompile) s
 	return initfunc
 }
 
+// firstRequiredFuncs returns the name of the first template of t looking up funcs at runtime,
+// and the sorted names of those funcs, the name is empty if none does.
+func (c *CompiledTemplatesProgram) firstRequiredFuncs(t *TemplateToCompile) (string, []string) {
+	for _, f := range t.files {
+		for _, name := range f.names() {
+			funcs := c.requiredFuncs[name]
+			if len(funcs) == 0 {
+				continue
+			}
+			names := make([]string, 0, len(funcs))
+			for fn := range funcs {
+				names = append(names, fn)
+			}
+			sort.Strings(names)
+			return name, names
+		}
+	}
+	return "", nil
+}
+
+// requiredFuncsLit returns the map literal of the funcs looked up at runtime,
+// their values are nil funcs of their types.
+func requiredFuncsLit(funcs map[string]string) string {
+	names := make([]string, 0, len(funcs))
+	for name := range funcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	lit := "map[string]interface{}{"
+	for i, name := range names {
+		if i > 0 {
+			lit += ", "
+		}
+		lit += fmt.Sprintf("%q: (%v)(nil)", name, funcs[name])
+	}
+	return lit + "}"
+}
+
 // generateProgram generates the output program.
 func (c *CompiledTemplatesProgram) generateProgram(outpkg string, tpls []*TemplateToCompile) string {
+	// the init func may add imports.
+	initfunc := c.generateInitFunc(tpls)
 	program := fmt.Sprintf("package %v\n\n", outpkg)
 	program += fmt.Sprintf("//golint:ignore\n\n")
 	program += fmt.Sprintf("%v\n\n", c.generateImportStmt())
 	program += fmt.Sprintf("%v\n\n", c.generateBuiltins())
-	program += fmt.Sprintf("%v\n\n", c.generateInitFunc(tpls))
+	program += fmt.Sprintf("%v\n\n", initfunc)
 	for _, f := range c.funcs {
 		program += fmt.Sprintf("%v\n\n", astNodeToString(f))
 	}
+	for _, r := range c.renderFuncs {
+		program += fmt.Sprintf("// %v\n", r.doc)
+		program += fmt.Sprintf("%v\n\n", astNodeToString(r.fn))
+	}
+	if len(c.lineFiles) > 0 {
+		program = writeLineDirectives(program, filepath.Base(c.outPath))
+	}
 	return program
 }
 
@@ -324,15 +561,21 @@ func convertConfigToTemplatesToCompile(conf *compiled.Configuration) []*Template
 // TemplateToCompile links a configuration and all the template files it matches.
 type TemplateToCompile struct {
 	*compiled.TemplateConfiguration
-	files []TemplateFileToCompile
+	files       []TemplateFileToCompile
+	sizeProfile texttemplate.SizeProfile
 }
 
 // TemplateFileToCompile links a template file with all the templates defined in it.
 type TemplateFileToCompile struct {
 	name             string
+	path             string
+	content          string
 	tplsTree         map[string]*parse.Tree
 	tplsFunc         map[string]string
+	tplsAppendFunc   map[string]string
+	tplsContextFunc  map[string]string
 	tplsTypeCheck    map[string]*simplifier.State
+	tplsSizeHint     map[string]int
 	definedTemplates []string
 }
 
@@ -379,6 +622,13 @@ func makeTemplateToCompile(templateConf compiled.TemplateConfiguration) *Templat
 
 // prepare evalutes the files of the TemplateConfiguration and prepares the resulting templates.
 func (t *TemplateToCompile) prepare() error {
+	if t.SizeProfile != "" {
+		p, err := texttemplate.ReadSizeProfile(t.SizeProfile)
+		if err != nil {
+			return fmt.Errorf("Failed to read the size profile: %v %v", t.SizeProfile, err)
+		}
+		t.sizeProfile = p
+	}
 	if t.TemplatesPath != "" {
 		tplsPath, err := filepath.Glob(t.TemplatesPath)
 		if err != nil {
@@ -406,9 +656,13 @@ func makeTemplateFileToCompileFromFile(tplPath string, tplToCompile *TemplateToC
 
 	fileTpl := TemplateFileToCompile{
 		name:             filepath.Base(tplPath),
+		path:             tplPath,
 		tplsTree:         map[string]*parse.Tree{},
 		tplsFunc:         map[string]string{},
+		tplsAppendFunc:   map[string]string{},
+		tplsContextFunc:  map[string]string{},
 		tplsTypeCheck:    map[string]*simplifier.State{},
+		tplsSizeHint:     map[string]int{},
 		definedTemplates: []string{},
 	}
 
@@ -428,6 +682,9 @@ func makeTemplateFileToCompileFromFile(tplPath string, tplToCompile *TemplateToC
 	if err != nil {
 		return fileTpl, err
 	}
+	if err := inferTemplatesData(fileTpl.name, treeNames, tplToCompile); err != nil {
+		return fileTpl, err
+	}
 	fileTpl.tplsTree = treeNames
 	for treeName, tree := range fileTpl.tplsTree {
 		data, err := tplToCompile.getData(treeName)
@@ -450,9 +707,13 @@ func makeTemplateFileToCompileFromStr(name, tplContent string, tplToCompile *Tem
 
 	fileTpl := TemplateFileToCompile{
 		name:             name,
+		content:          tplContent,
 		tplsTree:         map[string]*parse.Tree{},
 		tplsFunc:         map[string]string{},
+		tplsAppendFunc:   map[string]string{},
+		tplsContextFunc:  map[string]string{},
 		tplsTypeCheck:    map[string]*simplifier.State{},
+		tplsSizeHint:     map[string]int{},
 		definedTemplates: []string{},
 	}
 	funcs := tplToCompile.FuncsExport
@@ -467,6 +728,9 @@ func makeTemplateFileToCompileFromStr(name, tplContent string, tplToCompile *Tem
 	if err != nil {
 		return fileTpl, err
 	}
+	if err := inferTemplatesData(fileTpl.name, treeNames, tplToCompile); err != nil {
+		return fileTpl, err
+	}
 	fileTpl.tplsTree = treeNames
 	mainName := fileTpl.name
 	for treeName, tree := range fileTpl.tplsTree {
@@ -564,6 +828,25 @@ func snakeToCamel(s string) string {
 	return s
 }
 
+// exportedIdent makes an exported identifier of a template name,
+// a.tpl becomes ATpl.
+func exportedIdent(name string) string {
+	ret := ""
+	upper := true
+	for _, r := range name {
+		if unicode.IsLetter(r) == false && unicode.IsDigit(r) == false {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		ret += string(r)
+	}
+	return ret
+}
+
 func cleanTplName(name string) string {
 	return replaceAll(name, []string{"."}, "_")
 }
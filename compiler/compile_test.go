@@ -4,6 +4,7 @@ import (
 	"go/ast"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/mh-cbon/template-compiler/compiled"
@@ -21,6 +22,8 @@ type CompileTestData struct {
 	expectedInitFunc string
 	expectedTplsFunc map[string]string
 	expectedBuiltins map[string]string
+	// the exported render and append render functions to find in the program, by function name
+	expectedRenderFuncs map[string]string
 }
 
 func TestCompile(t *testing.T) {
@@ -41,17 +44,32 @@ func TestCompile(t *testing.T) {
 			},
 			expectedImports: []string{
 				"io",
+				"context",
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
+				"aliasdata:github.com/mh-cbon/template-compiler/demo/data",
 			},
 			expectedInitFunc: `func init() {
   xx.Add("a.tpl", fnaTpl)
+  xx.AddAppend("a.tpl", fnaTplAppend)
+  xx.AddContext("a.tpl", fnaTplContext)
 }`,
 			expectedTplsFunc: map[string]string{
 				"fnaTpl": `func fnaTpl(t parse.Templater, w io.Writer, indata interface{}) error {
 return nil
+}`,
+				"fnaTplAppend": `func fnaTplAppend(t parse.Templater, dst []byte, indata interface{}) ([]byte, error) {
+  return dst, nil
 }`,
 			},
 			expectedBuiltins: map[string]string{},
+			expectedRenderFuncs: map[string]string{
+				"RenderATpl": `func RenderATpl(w io.Writer, data aliasdata.MyTemplateData) error {
+  return xx.MustGet("a.tpl").Execute(w, data)
+}`,
+				"AppendRenderATpl": `func AppendRenderATpl(dst []byte, data aliasdata.MyTemplateData) ([]byte, error) {
+  return xx.MustGet("a.tpl").AppendExecute(dst, data)
+}`,
+			},
 		},
 		CompileTestData{
 			varName: "yy",
@@ -67,31 +85,57 @@ return nil
 			},
 			expectedImports: []string{
 				"io",
-				"strconv",
+				"context",
 				"fmt",
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
 				"aliasdata:github.com/mh-cbon/template-compiler/demo/data",
+				"github.com/mh-cbon/template-compiler/std/text/template",
 			},
 			expectedInitFunc: `func init() {
 yy.Add("b.tpl", fnbTpl)
+yy.AddAppend("b.tpl", fnbTplAppend)
+yy.AddContext("b.tpl", fnbTplContext)
+yy.SetSizeHint("b.tpl", 1)
 }`,
 			expectedTplsFunc: map[string]string{
 				"fnbTpl": `func fnbTpl(t parse.Templater, w io.Writer, indata interface{}) error {
   var data aliasdata.MyTemplateData
   if d, ok := indata.(aliasdata.MyTemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: b.tpl: unexpected data type %T, wants data.MyTemplateData", indata)
   }
-  var tplY int = 4
-  if _, werr := io.WriteString(w, strconv.Itoa(tplY)); werr != nil {
-    return werr
+  if _, werr := w.Write(builtin0); werr != nil {
+    return template.NewExecError("b.tpl", "b.tpl:1:13", "4", werr)
   }
   if _, werr := fmt.Fprintf(w, "%v", data); werr != nil {
-    return werr
+    return template.NewExecError("b.tpl", "b.tpl:1:19", "{{.}}", werr)
   }
   return nil
+}`,
+				"fnbTplAppend": `func fnbTplAppend(t parse.Templater, dst []byte, indata interface{}) ([]byte, error) {
+  var data aliasdata.MyTemplateData
+  if d, ok := indata.(aliasdata.MyTemplateData); ok {
+    data = d
+  } else if indata != nil {
+    return dst, fmt.Errorf("template: b.tpl: unexpected data type %T, wants data.MyTemplateData", indata)
+  }
+  dst = append(dst, builtin0...)
+  dst = fmt.Appendf(dst, "%v", data)
+  return dst, nil
+}`,
+			},
+			expectedBuiltins: map[string]string{
+				"builtin0": `[]byte("4")`,
+			},
+			expectedRenderFuncs: map[string]string{
+				"RenderBTpl": `func RenderBTpl(w io.Writer, data aliasdata.MyTemplateData) error {
+  return yy.MustGet("b.tpl").Execute(w, data)
+}`,
+				"AppendRenderBTpl": `func AppendRenderBTpl(dst []byte, data aliasdata.MyTemplateData) ([]byte, error) {
+  return yy.MustGet("b.tpl").AppendExecute(dst, data)
 }`,
 			},
-			expectedBuiltins: map[string]string{},
 		},
 		CompileTestData{
 			varName: "yy",
@@ -106,33 +150,39 @@ yy.Add("b.tpl", fnbTpl)
 			},
 			expectedImports: []string{
 				"io",
+				"context",
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
-				"github.com/mh-cbon/template-compiler/std/html/template",
+				"aliasdata:github.com/mh-cbon/template-compiler/demo/data",
+				"github.com/mh-cbon/template-compiler/std/text/template",
 			},
 			expectedInitFunc: `func init() {
 yy.Add("b.tpl", fnbTpl)
+yy.AddAppend("b.tpl", fnbTplAppend)
+yy.AddContext("b.tpl", fnbTplContext)
+yy.SetSizeHint("b.tpl", 34)
 }`,
 			expectedTplsFunc: map[string]string{
 				"fnbTpl": `func fnbTpl(t parse.Templater, w io.Writer, indata interface{}) error {
   if _, werr := w.Write(builtin0); werr != nil {
-    return werr
-  }
-  var tplY int = 4
-  if _, werr := w.Write(builtin0); werr != nil {
-    return werr
-  }
-  var var0 string = template.HTMLEscaper(tplY)
-  if _, werr := io.WriteString(w, var0); werr != nil {
-    return werr
-  }
-  if _, werr := w.Write(builtin0); werr != nil {
-    return werr
+    return template.NewExecError("b.tpl", "b.tpl:1:0", "samebuiltin4samebuil...", werr)
   }
   return nil
+}`,
+				"fnbTplAppend": `func fnbTplAppend(t parse.Templater, dst []byte, indata interface{}) ([]byte, error) {
+  dst = append(dst, builtin0...)
+  return dst, nil
 }`,
 			},
 			expectedBuiltins: map[string]string{
-				"builtin0": `[]byte("samebuiltin")`,
+				"builtin0": `[]byte("samebuiltinsamebuiltin4samebuiltin")`,
+			},
+			expectedRenderFuncs: map[string]string{
+				"RenderBTpl": `func RenderBTpl(w io.Writer, data aliasdata.MyTemplateData) error {
+  return yy.MustGet("b.tpl").Execute(w, data)
+}`,
+				"AppendRenderBTpl": `func AppendRenderBTpl(dst []byte, data aliasdata.MyTemplateData) ([]byte, error) {
+  return yy.MustGet("b.tpl").AppendExecute(dst, data)
+}`,
 			},
 		},
 		CompileTestData{
@@ -149,32 +199,60 @@ yy.Add("b.tpl", fnbTpl)
 			},
 			expectedImports: []string{
 				"io",
+				"context",
+				"fmt",
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
 				"aliasdata:github.com/mh-cbon/template-compiler/demo/data",
 				"github.com/mh-cbon/template-compiler/std/html/template",
+				"aliastemplate:github.com/mh-cbon/template-compiler/std/text/template",
 			},
 			expectedInitFunc: `func init() {
 yy.Add("b.tpl", fnbTpl)
+yy.AddAppend("b.tpl", fnbTplAppend)
+yy.AddContext("b.tpl", fnbTplContext)
+yy.SetSizeHint("b.tpl", 4)
 }`,
 			expectedTplsFunc: map[string]string{
 				"fnbTpl": `func fnbTpl(t parse.Templater, w io.Writer, indata interface{}) error {
   var data *aliasdata.MyTemplateData
   if d, ok := indata.(*aliasdata.MyTemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: b.tpl: unexpected data type %T, wants *data.MyTemplateData", indata)
   }
-  var tplY bool = true
-  var var0 string = template.HTMLEscaper(tplY)
-  if _, werr := io.WriteString(w, var0); werr != nil {
-    return werr
+  if _, werr := w.Write(builtin0); werr != nil {
+    return aliastemplate.NewExecError("b.tpl", "b.tpl:1:16", "true", werr)
   }
   var var1 string = template.HTMLEscaper(data)
   if _, werr := io.WriteString(w, var1); werr != nil {
-    return werr
+    return aliastemplate.NewExecError("b.tpl", "b.tpl:1:22", "{{$var1}}", werr)
   }
   return nil
+}`,
+				"fnbTplAppend": `func fnbTplAppend(t parse.Templater, dst []byte, indata interface{}) ([]byte, error) {
+  var data *aliasdata.MyTemplateData
+  if d, ok := indata.(*aliasdata.MyTemplateData); ok {
+    data = d
+  } else if indata != nil {
+    return dst, fmt.Errorf("template: b.tpl: unexpected data type %T, wants *data.MyTemplateData", indata)
+  }
+  dst = append(dst, builtin0...)
+  var var1 string = template.HTMLEscaper(data)
+  dst = append(dst, var1...)
+  return dst, nil
+}`,
+			},
+			expectedBuiltins: map[string]string{
+				"builtin0": `[]byte("true")`,
+			},
+			expectedRenderFuncs: map[string]string{
+				"RenderBTpl": `func RenderBTpl(w io.Writer, data *aliasdata.MyTemplateData) error {
+  return yy.MustGet("b.tpl").Execute(w, data)
+}`,
+				"AppendRenderBTpl": `func AppendRenderBTpl(dst []byte, data *aliasdata.MyTemplateData) ([]byte, error) {
+  return yy.MustGet("b.tpl").AppendExecute(dst, data)
 }`,
 			},
-			expectedBuiltins: map[string]string{},
 		},
 		CompileTestData{
 			varName: "yy",
@@ -190,11 +268,20 @@ yy.Add("b.tpl", fnbTpl)
 			},
 			expectedImports: []string{
 				"io",
+				"context",
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
+				"aliasdata:github.com/mh-cbon/template-compiler/demo/data",
+				"github.com/mh-cbon/template-compiler/std/text/template",
 			},
 			expectedInitFunc: `func init() {
       yy.Add("b.tpl", fnbTpl)
+      yy.AddAppend("b.tpl", fnbTplAppend)
+      yy.AddContext("b.tpl", fnbTplContext)
+      yy.SetSizeHint("b.tpl", 10)
       yy.Add("z", fnbTplZ)
+      yy.AddAppend("z", fnbTplZAppend)
+      yy.AddContext("z", fnbTplZContext)
+      yy.SetSizeHint("z", 10)
       tpl0X0 := yy.MustGet("b.tpl")
       tpl0Y0 := yy.MustGet("z")
       tpl0X0, _ = tpl0X0.Compiled(tpl0Y0)
@@ -203,21 +290,43 @@ yy.Add("b.tpl", fnbTpl)
 			expectedTplsFunc: map[string]string{
 				"fnbTpl": `func fnbTpl(t parse.Templater, w io.Writer, indata interface{}) error {
   if _, werr := w.Write(builtin0); werr != nil {
-    return werr
+    return template.NewExecError("b.tpl", "b.tpl:1:30", "b template", werr)
   }
   return nil
 }`,
 				"fnbTplZ": `func fnbTplZ(t parse.Templater, w io.Writer, indata interface{}) error {
   if _, werr := w.Write(builtin1); werr != nil {
-    return werr
+    return template.NewExecError("z", "b.tpl:1:14", "z template", werr)
   }
   return nil
+}`,
+				"fnbTplAppend": `func fnbTplAppend(t parse.Templater, dst []byte, indata interface{}) ([]byte, error) {
+  dst = append(dst, builtin0...)
+  return dst, nil
+}`,
+				"fnbTplZAppend": `func fnbTplZAppend(t parse.Templater, dst []byte, indata interface{}) ([]byte, error) {
+  dst = append(dst, builtin1...)
+  return dst, nil
 }`,
 			},
 			expectedBuiltins: map[string]string{
 				"builtin0": `[]byte("b template")`,
 				"builtin1": `[]byte("z template")`,
 			},
+			expectedRenderFuncs: map[string]string{
+				"RenderBTpl": `func RenderBTpl(w io.Writer, data *aliasdata.MyTemplateData) error {
+  return yy.MustGet("b.tpl").Execute(w, data)
+}`,
+				"AppendRenderBTpl": `func AppendRenderBTpl(dst []byte, data *aliasdata.MyTemplateData) ([]byte, error) {
+  return yy.MustGet("b.tpl").AppendExecute(dst, data)
+}`,
+				"RenderZ": `func RenderZ(w io.Writer, data *aliasdata.MyTemplateData) error {
+  return yy.MustGet("z").Execute(w, data)
+}`,
+				"AppendRenderZ": `func AppendRenderZ(dst []byte, data *aliasdata.MyTemplateData) ([]byte, error) {
+  return yy.MustGet("z").AppendExecute(dst, data)
+}`,
+			},
 		},
 		CompileTestData{
 			varName: "yy",
@@ -240,13 +349,28 @@ yy.Add("b.tpl", fnbTpl)
 			},
 			expectedImports: []string{
 				"io",
+				"context",
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
+				"aliasdata:github.com/mh-cbon/template-compiler/demo/data",
+				"github.com/mh-cbon/template-compiler/std/text/template",
 			},
 			expectedInitFunc: `func init() {
         yy.Add("b.tpl", fnbTpl)
+        yy.AddAppend("b.tpl", fnbTplAppend)
+        yy.AddContext("b.tpl", fnbTplContext)
+        yy.SetSizeHint("b.tpl", 10)
         yy.Add("z", fnbTplZ)
+        yy.AddAppend("z", fnbTplZAppend)
+        yy.AddContext("z", fnbTplZContext)
+        yy.SetSizeHint("z", 10)
         yy.Add("b.tpl", fn0fnbTpl)
+        yy.AddAppend("b.tpl", fn0fnbTplAppend)
+        yy.AddContext("b.tpl", fn0fnbTplContext)
+        yy.SetSizeHint("b.tpl", 12)
         yy.Add("x", fnbTplX)
+        yy.AddAppend("x", fnbTplXAppend)
+        yy.AddContext("x", fnbTplXContext)
+        yy.SetSizeHint("x", 10)
         tpl0X0 := yy.MustGet("b.tpl")
         tpl0Y0 := yy.MustGet("z")
         tpl0X0, _ = tpl0X0.Compiled(tpl0Y0)
@@ -259,27 +383,43 @@ yy.Add("b.tpl", fnbTpl)
 			expectedTplsFunc: map[string]string{
 				"fnbTpl": `func fnbTpl(t parse.Templater, w io.Writer, indata interface{}) error {
   if _, werr := w.Write(builtin0); werr != nil {
-    return werr
+    return template.NewExecError("b.tpl", "b.tpl:1:30", "b template", werr)
   }
   return nil
 }`,
 				"fnbTplZ": `func fnbTplZ(t parse.Templater, w io.Writer, indata interface{}) error {
   if _, werr := w.Write(builtin1); werr != nil {
-    return werr
+    return template.NewExecError("z", "b.tpl:1:14", "z template", werr)
   }
   return nil
 }`,
 				"fn0fnbTpl": `func fn0fnbTpl(t parse.Templater, w io.Writer, indata interface{}) error {
   if _, werr := w.Write(builtin2); werr != nil {
-    return werr
+    return template.NewExecError("b.tpl", "b.tpl:1:30", "b template 2", werr)
   }
   return nil
 }`,
 				"fnbTplX": `func fnbTplX(t parse.Templater, w io.Writer, indata interface{}) error {
   if _, werr := w.Write(builtin3); werr != nil {
-    return werr
+    return template.NewExecError("x", "b.tpl:1:14", "x template", werr)
   }
   return nil
+}`,
+				"fnbTplAppend": `func fnbTplAppend(t parse.Templater, dst []byte, indata interface{}) ([]byte, error) {
+  dst = append(dst, builtin0...)
+  return dst, nil
+}`,
+				"fnbTplZAppend": `func fnbTplZAppend(t parse.Templater, dst []byte, indata interface{}) ([]byte, error) {
+  dst = append(dst, builtin1...)
+  return dst, nil
+}`,
+				"fn0fnbTplAppend": `func fn0fnbTplAppend(t parse.Templater, dst []byte, indata interface{}) ([]byte, error) {
+  dst = append(dst, builtin2...)
+  return dst, nil
+}`,
+				"fnbTplXAppend": `func fnbTplXAppend(t parse.Templater, dst []byte, indata interface{}) ([]byte, error) {
+  dst = append(dst, builtin3...)
+  return dst, nil
 }`,
 			},
 			expectedBuiltins: map[string]string{
@@ -288,6 +428,32 @@ yy.Add("b.tpl", fnbTpl)
 				"builtin2": `[]byte("b template 2")`,
 				"builtin3": `[]byte("x template")`,
 			},
+			expectedRenderFuncs: map[string]string{
+				"RenderBTpl": `func RenderBTpl(w io.Writer, data aliasdata.MyTemplateData) error {
+  return yy.MustGet("b.tpl").Execute(w, data)
+}`,
+				"AppendRenderBTpl": `func AppendRenderBTpl(dst []byte, data aliasdata.MyTemplateData) ([]byte, error) {
+  return yy.MustGet("b.tpl").AppendExecute(dst, data)
+}`,
+				"RenderZ": `func RenderZ(w io.Writer, data aliasdata.MyTemplateData) error {
+  return yy.MustGet("z").Execute(w, data)
+}`,
+				"AppendRenderZ": `func AppendRenderZ(dst []byte, data aliasdata.MyTemplateData) ([]byte, error) {
+  return yy.MustGet("z").AppendExecute(dst, data)
+}`,
+				"RenderBTpl1": `func RenderBTpl1(w io.Writer, data aliasdata.MyTemplateData) error {
+  return yy.MustGet("b.tpl").Execute(w, data)
+}`,
+				"AppendRenderBTpl1": `func AppendRenderBTpl1(dst []byte, data aliasdata.MyTemplateData) ([]byte, error) {
+  return yy.MustGet("b.tpl").AppendExecute(dst, data)
+}`,
+				"RenderX": `func RenderX(w io.Writer, data aliasdata.MyTemplateData) error {
+  return yy.MustGet("x").Execute(w, data)
+}`,
+				"AppendRenderX": `func AppendRenderX(dst []byte, data aliasdata.MyTemplateData) ([]byte, error) {
+  return yy.MustGet("x").AppendExecute(dst, data)
+}`,
+			},
 		},
 	}
 
@@ -351,7 +517,11 @@ yy.Add("b.tpl", fnbTpl)
 
 		for _, conf := range dataTest.conf {
 			for _, tplfile := range conf.files {
-				for _, fnname := range tplfile.tplsFunc {
+				fnnames := []string{}
+				for name, fnname := range tplfile.tplsFunc {
+					fnnames = append(fnnames, fnname, tplfile.tplsAppendFunc[name])
+				}
+				for _, fnname := range fnnames {
 
 					tfn := extractFunc(f, fnname)
 					if tfn == nil {
@@ -421,6 +591,30 @@ yy.Add("b.tpl", fnbTpl)
 			}
 		}
 		//-
+		renderFuncs := append(extractFuncLike(f, "Render"), extractFuncLike(f, "AppendRender")...)
+		if len(renderFuncs) != len(dataTest.expectedRenderFuncs) {
+			t.Errorf("Test(%v): Expected to get %v render functions, but found %v\n\n%v",
+				i, len(dataTest.expectedRenderFuncs), len(renderFuncs), program)
+			return
+		}
+
+		for name, expectedFn := range dataTest.expectedRenderFuncs {
+			rfn := extractFunc(f, name)
+			if rfn == nil {
+				t.Errorf("Test(%v): Expected to find a render function=%v\n\n%v", i, name, program)
+				return
+			}
+			funcString := formatGoCode(astNodeToString(rfn))
+			expectedFn = formatGoCode(expectedFn)
+			if expectedFn != funcString {
+				t.Errorf(
+					"Test(%v): Unexpected content of render function %v\nexpected=\n%v\ngot\n%v\n\n%v",
+					i, name, expectedFn, funcString, program,
+				)
+				return
+			}
+		}
+		//-
 	}
 	//-
 }
@@ -474,6 +668,17 @@ func extractVarLike(file *ast.File, varName string) []*ast.GenDecl {
 	return found
 }
 
+// given a go file ast.Node, extracts the (top-level) func declarations with name starting with given name.
+func extractFuncLike(file *ast.File, funcName string) []*ast.FuncDecl {
+	var found []*ast.FuncDecl
+	for _, d := range file.Decls {
+		if fn, ok := d.(*ast.FuncDecl); ok && strings.HasPrefix(fn.Name.Name, funcName) {
+			found = append(found, fn)
+		}
+	}
+	return found
+}
+
 // given a go file ast.Node, extracts a (top-level) func declaration.
 func extractFunc(file *ast.File, funcName string) *ast.FuncDecl {
 	var found *ast.FuncDecl
@@ -617,3 +822,96 @@ var htmlPublicIdents = []map[string]string{
 	map[string]string{"Pkg": "github.com/mh-cbon/template-compiler/std/html/template", "FuncName": "_html_template_urlescaper", "Sel": "template.URLEscaper"},
 	map[string]string{"FuncName": "_html_template_urlfilter", "Sel": "template.URLFilter", "Pkg": "github.com/mh-cbon/template-compiler/std/html/template"},
 }
+
+func TestGenerateInitFuncAddFuncs(t *testing.T) {
+	c := NewCompiledTemplatesProgram("xx")
+	c.pkgPath = "github.com/mh-cbon/template-compiler/demo"
+	c.funcsMap = []string{"text/template:builtins", "github.com/mh-cbon/template-compiler/demo:funcs"}
+	tpl := makeTemplateToCompile(compiled.TemplateConfiguration{
+		FuncsMap: []string{
+			"github.com/mh-cbon/template-compiler/demo/data:Funcs",
+			"github.com/mh-cbon/template-compiler/demo/data:funcs",
+		},
+	})
+	initfunc := c.generateInitFunc([]*TemplateToCompile{tpl})
+	expected := []string{
+		"xx.Templates[0].AddFuncs(funcs)\n",
+		"xx.Templates[0].AddFuncs(aliasdata.Funcs)\n",
+		"xx.Registry.Funcs(funcs)\n",
+		"xx.Registry.Funcs(aliasdata.Funcs)\n",
+	}
+	for _, e := range expected {
+		if strings.Contains(initfunc, e) == false {
+			t.Errorf("Expected to find %q in the init func\n%v", e, initfunc)
+		}
+	}
+	for _, e := range []string{"builtins", "data.funcs"} {
+		if strings.Contains(initfunc, e) {
+			t.Errorf("Unexpected %q in the init func\n%v", e, initfunc)
+		}
+	}
+	if c.hasImport("github.com/mh-cbon/template-compiler/demo/data") == false {
+		t.Errorf("Expected the funcmap package to be imported")
+	}
+	if len(c.warnings) > 0 {
+		t.Errorf("Unexpected warnings %q", c.warnings)
+	}
+}
+
+func TestGenerateInitFuncRequireFuncs(t *testing.T) {
+	program, err := compileLinkTestData([]compiled.TemplateConfiguration{
+		compiled.TemplateConfiguration{
+			TemplateName:    "page",
+			TemplateContent: `{{define "row"}}{{split "a" "b"}}{{end}}{{up "a"}}{{up "b"}}`,
+			TemplatesData:   map[string]interface{}{"*": nil},
+			FuncsExport: map[string]interface{}{
+				"up":    func(s string) string { return s },
+				"split": func(s string, sep string) []string { return nil },
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		`compiledTemplates.RequireFuncs("page", map[string]interface{}{"up": (func(string) string)(nil)})`,
+		`compiledTemplates.RequireFuncs("row", map[string]interface{}{"split": (func(string, string) []string)(nil)})`,
+	}
+	for _, e := range expected {
+		if strings.Contains(program, e) == false {
+			t.Errorf("Expected to find %q in the program\n%v", e, program)
+		}
+	}
+	if strings.Count(program, "RequireFuncs") != 2 {
+		t.Errorf("Unexpected RequireFuncs in the program\n%v", program)
+	}
+}
+
+func TestGenerateInitFuncInaccessibleFuncs(t *testing.T) {
+	c := NewCompiledTemplatesProgram("compiledTemplates")
+	conf := compiled.New("gen.go", []compiled.TemplateConfiguration{
+		compiled.TemplateConfiguration{
+			TemplateName:    "page",
+			TemplateContent: `{{up "a"}}`,
+			TemplatesData:   map[string]interface{}{"*": nil},
+			FuncsMap:        []string{"github.com/mh-cbon/template-compiler/demo/data:funcs"},
+			FuncsExport: map[string]interface{}{
+				"up": func(s string) string { return s },
+			},
+		},
+	}).SetPkg("main")
+	tpls, err := c.getTemplatesToCompile(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.compileTemplates("main", tpls); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		`the funcmap github.com/mh-cbon/template-compiler/demo/data:funcs is not accessible from the output program, ` +
+			`the template "page" looks up the funcs [up] at runtime, add them to the registry before it is executed`,
+	}
+	if reflect.DeepEqual(c.warnings, expected) == false {
+		t.Errorf("Unexpected warnings\nexpected=%q\ngot     =%q", expected, c.warnings)
+	}
+}
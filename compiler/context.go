@@ -0,0 +1,143 @@
+package compiler
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// static name of the context of the compiled template functions.
+const contextName = "ctx"
+
+// addContextFuncs adds the context form of the compiled template functions,
+// such func fnaTplContext(ctx context.Context, t parse.Templater, w io.Writer, indata interface{}) error.
+// It checks ctx at each loop iteration and before each template call,
+// the template calls are rewritten to their context form.
+func (c *CompiledTemplatesProgram) addContextFuncs(tpls []*TemplateToCompile) {
+	// name all the funcs first, so the calls are rewritten to their context form.
+	names := map[string]string{}
+	fnNames := []string{}
+	for _, t := range tpls {
+		for _, f := range t.files {
+			for _, name := range f.names() {
+				f.tplsContextFunc[name] = c.makeFuncName(f.tplsFunc[name] + "Context")
+				names[f.tplsFunc[name]] = f.tplsContextFunc[name]
+				fnNames = append(fnNames, f.tplsFunc[name])
+				if l, ok := c.linked[name]; ok && l.dataFnName != "" && l.fnName == f.tplsFunc[name] {
+					names[l.dataFnName] = c.makeFuncName(l.dataFnName + "Context")
+					fnNames = append(fnNames, l.dataFnName)
+				}
+			}
+		}
+	}
+	alias := c.addImport("context")
+	for _, fnName := range fnNames {
+		var src *ast.FuncDecl
+		for _, f := range c.funcs {
+			if f.Name.Name == fnName {
+				src = f
+			}
+		}
+		// work on a copy of the function.
+		fn := stringToAst("package aa\n" + astNodeToString(src)).Decls[0].(*ast.FuncDecl)
+		fn.Name = ast.NewIdent(names[fnName])
+		ctxParam := &ast.Field{
+			Names: []*ast.Ident{ast.NewIdent(contextName)},
+			Type:  &ast.SelectorExpr{X: ast.NewIdent(alias), Sel: ast.NewIdent("Context")},
+		}
+		fn.Type.Params.List = append([]*ast.Field{ctxParam}, fn.Type.Params.List...)
+		fn.Body.List = contextStmts(fn.Body.List, names)
+		c.funcs = append(c.funcs, fn)
+	}
+}
+
+// contextStmts rewrites a list of statements to check the context.
+func contextStmts(list []ast.Stmt, names map[string]string) []ast.Stmt {
+	ret := make([]ast.Stmt, 0, len(list))
+	for _, stmt := range list {
+		switch s := stmt.(type) {
+		case *ast.IfStmt:
+			if contextTemplateCall(s, names) {
+				ret = append(ret, contextCheckStmt())
+			} else {
+				s.Body.List = contextStmts(s.Body.List, names)
+				switch e := s.Else.(type) {
+				case *ast.BlockStmt:
+					e.List = contextStmts(e.List, names)
+				case *ast.IfStmt:
+					contextStmts([]ast.Stmt{e}, names)
+				}
+			}
+		case *ast.RangeStmt:
+			s.Body.List = contextLoopBody(s.Body.List, names)
+		case *ast.ForStmt:
+			s.Body.List = contextLoopBody(s.Body.List, names)
+		case *ast.BlockStmt:
+			s.List = contextStmts(s.List, names)
+		case *ast.SwitchStmt:
+			for _, cc := range s.Body.List {
+				cc.(*ast.CaseClause).Body = contextStmts(cc.(*ast.CaseClause).Body, names)
+			}
+		case *ast.TypeSwitchStmt:
+			for _, cc := range s.Body.List {
+				cc.(*ast.CaseClause).Body = contextStmts(cc.(*ast.CaseClause).Body, names)
+			}
+		}
+		ret = append(ret, stmt)
+	}
+	return ret
+}
+
+// contextLoopBody rewrites the body of a loop to check the context at each iteration.
+func contextLoopBody(list []ast.Stmt, names map[string]string) []ast.Stmt {
+	list = contextStmts(list, names)
+	first := 0
+	for first < len(list) && isLineDirective(list[first]) {
+		first++
+	}
+	if first < len(list) && astNodeToString(list[first]) == astNodeToString(contextCheckStmt()) {
+		// the body starts with a template call.
+		return list
+	}
+	return append([]ast.Stmt{contextCheckStmt()}, list...)
+}
+
+// contextTemplateCall rewrites a template call such
+// if werr := t.ExecuteTemplate(w, "x", data); werr != nil { return werr }
+// to t.ExecuteTemplateContext(ctx, w, "x", data),
+// and the static call fnx(t, w, data) to fnxContext(ctx, t, w, data).
+// It tells if s is a template call.
+func contextTemplateCall(s *ast.IfStmt, names map[string]string) bool {
+	init, ok := s.Init.(*ast.AssignStmt)
+	if ok == false || init.Tok != token.DEFINE || len(init.Rhs) != 1 {
+		return false
+	}
+	call, ok := init.Rhs[0].(*ast.CallExpr)
+	if ok == false {
+		return false
+	}
+	switch fn := call.Fun.(type) {
+	case *ast.SelectorExpr:
+		if isIdent(fn.X, "t") == false || fn.Sel.Name != "ExecuteTemplate" {
+			return false
+		}
+		fn.Sel = ast.NewIdent("ExecuteTemplateContext")
+	case *ast.Ident:
+		name, ok := names[fn.Name]
+		if ok == false {
+			return false
+		}
+		call.Fun = ast.NewIdent(name)
+	default:
+		return false
+	}
+	call.Args = append([]ast.Expr{ast.NewIdent(contextName)}, call.Args...)
+	return true
+}
+
+// contextCheckStmt returns the statement returning the error of the context when it is done.
+func contextCheckStmt() ast.Stmt {
+	return getStmtsAst(`
+if err := ` + contextName + `.Err(); err != nil {
+  return err
+}`)[0]
+}
@@ -0,0 +1,97 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mh-cbon/template-compiler/compiled"
+	"github.com/mh-cbon/template-compiler/demo/data"
+)
+
+type ContextTestData struct {
+	templates []compiled.TemplateConfiguration
+	// the code expected to be found in the program
+	expected []string
+}
+
+func TestAddContextFuncs(t *testing.T) {
+
+	allTestData := []ContextTestData{
+		ContextTestData{
+			templates: []compiled.TemplateConfiguration{
+				compiled.TemplateConfiguration{
+					TemplateName:    "page",
+					TemplateContent: `{{range .Items}}<{{.}}>{{end}}`,
+					TemplatesData:   map[string]interface{}{"*": data.MyTemplateData{}},
+				},
+			},
+			expected: []string{
+				`compiledTemplates.AddContext("page", fnpageContext)`,
+				`func fnpageContext(ctx context.Context, t parse.Templater, w io.Writer, indata interface{}) error {`,
+				`range data.Items {
+		if err := ctx.Err(); err != nil {
+			return err
+		}`,
+			},
+		},
+		ContextTestData{
+			templates: []compiled.TemplateConfiguration{
+				compiled.TemplateConfiguration{
+					TemplateName:    "page",
+					TemplateContent: `{{define "row"}}{{.Some}}{{end}}{{template "row" .}}{{template "other"}}`,
+					TemplatesData:   map[string]interface{}{"*": data.MyTemplateData{}},
+				},
+				compiled.TemplateConfiguration{
+					TemplateName:    "other",
+					TemplateContent: `other`,
+					TemplatesData:   map[string]interface{}{"*": nil},
+				},
+			},
+			expected: []string{
+				`		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if werr := fnpage_rowDataContext(ctx, t, w, data); werr != nil {`,
+				`		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if werr := fnotherContext(ctx, t, w, nil); werr != nil {`,
+				`		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if werr := t.ExecuteTemplateContext(ctx, w, "other", nil); werr != nil {`,
+				`func fnpage_rowDataContext(ctx context.Context, t parse.Templater, w io.Writer, data aliasdata.MyTemplateData) error {`,
+			},
+		},
+		ContextTestData{
+			templates: []compiled.TemplateConfiguration{
+				compiled.TemplateConfiguration{
+					TemplateName:    "a",
+					TemplateContent: `{{define "x"}}a{{end}}{{template "x"}}`,
+					TemplatesData:   map[string]interface{}{"*": nil},
+				},
+				compiled.TemplateConfiguration{
+					TemplateName:    "b",
+					TemplateContent: `{{define "x"}}b{{end}}{{template "x"}}`,
+					TemplatesData:   map[string]interface{}{"*": nil},
+				},
+			},
+			expected: []string{
+				`t.ExecuteTemplateContext(ctx, w, "x", nil)`,
+			},
+		},
+	}
+
+	for i, testData := range allTestData {
+		program, err := compileLinkTestData(testData.templates)
+		if err != nil {
+			t.Errorf("Test(%v): Unexpected error %v", i, err)
+			continue
+		}
+		for _, e := range testData.expected {
+			if strings.Contains(program, e) == false {
+				t.Errorf("Test(%v): Expected to find %v in the program\n%v", i, e, program)
+			}
+		}
+	}
+}
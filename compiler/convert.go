@@ -10,6 +10,7 @@ import (
 	html "html/template"
 	"path/filepath"
 	"reflect"
+	"regexp"
 	"strings"
 	"text/template/parse"
 
@@ -37,11 +38,31 @@ var optimizedCalls = map[string]opitmizedFunc{
 	},
 }
 
+// streamingEscapers are the html/template escapers and the html builtin
+// having a func(w io.Writer, s string) error form.
+var streamingEscapers = map[string]string{
+	"html":                           "HTMLBuiltinEscaperTo",
+	"_html_template_attrescaper":     "AttrEscaperTo",
+	"_html_template_commentescaper":  "CommentEscaperTo",
+	"_html_template_cssescaper":      "CSSEscaperTo",
+	"_html_template_cssvaluefilter":  "CSSValueFilterTo",
+	"_html_template_htmlnamefilter":  "HTMLNameFilterTo",
+	"_html_template_htmlescaper":     "HTMLEscaperTo",
+	"_html_template_jsregexpescaper": "JSRegexpEscaperTo",
+	"_html_template_jsstrescaper":    "JSStrEscaperTo",
+	"_html_template_jsvalescaper":    "JSValEscaperTo",
+	"_html_template_nospaceescaper":  "HTMLNospaceEscaperTo",
+	"_html_template_rcdataescaper":   "RcdataEscaperTo",
+	"_html_template_urlescaper":      "URLEscaperTo",
+	"_html_template_urlfilter":       "URLFilterTo",
+	"_html_template_urlnormalizer":   "URLNormalizerTo",
+}
+
 // converter holds data to convert a template tree into a function.
 type converter struct {
 	tree             *parse.Tree
 	writerName       string
-	bwriterName      string
+	abufferName      string
 	fn               *ast.FuncDecl
 	state            *state
 	errvars          int
@@ -50,6 +71,14 @@ type converter struct {
 	funcsMap         map[string]interface{}
 	publicIdents     []map[string]string
 	skipNextVarPrint string
+	varTypes         map[string]reflect.Type
+	lazyOperands     map[string][]parse.Node
+	deferredNodes    map[parse.Node]string
+	nextNode         parse.Node
+	// addressables are the variables holding a value the interpreter can address.
+	addressables map[string]bool
+	// action is the node being converted, its errors are reported at its location.
+	action parse.Node
 }
 
 // createErrVars creates a unique error var name for a fucntion scope.
@@ -129,20 +158,32 @@ func convertTplTree(
 	c := converter{
 		tree:            tree,
 		writerName:      "w",
-		bwriterName:     "bw",
+		abufferName:     "bb",
 		state:           &state{typeCheck: typeCheck},
 		errvars:         -1,
 		compiledProgram: compiledProgram,
 		funcsMap:        funcsMap,
 		publicIdents:    publicIdents,
+		varTypes:        map[string]reflect.Type{},
+		lazyOperands:    map[string][]parse.Node{},
+		deferredNodes:   map[parse.Node]string{},
+		addressables:    map[string]bool{},
 	}
 
 	c.fn = c.compiledProgram.createFunc(fnname)
 
+	// evaluate the constant expressions, merge the static texts.
+	foldConstants(c.tree, c.funcsMap, c.publicIdents)
+
 	// if the template uses {{.}} anywhere, adds a prelude to type input data appropiately.
 	if simplifier.IsUsingDot(c.tree) {
 		dataQualifier := compiledProgram.getDataQualifier(dataConfiguration)
-		c.fn.Body.List = append(c.fn.Body.List, makePrelude(dataQualifier)...)
+		fmtalias := compiledProgram.addImport("fmt")
+		dataType := dataConfiguration.DataType
+		if dataConfiguration.IsPtr {
+			dataType = "*" + dataType
+		}
+		c.fn.Body.List = append(c.fn.Body.List, makePrelude(dataQualifier, fmtalias, tree.Name, dataType)...)
 	}
 
 	// enter into the function scope
@@ -162,9 +203,13 @@ func convertTplTree(
 // convert them to ast nodes,
 // add them to the current BlockStmt.
 func (c *converter) convert(node interface{}, typeCheck *simplifier.State) {
+	if n, ok := node.(parse.Node); ok && n.Type() != parse.NodeList {
+		c.addLineDirective(n)
+	}
 	switch node := node.(type) {
 
 	case *parse.TextNode:
+		c.action = node
 		if len(node.Text) > 0 {
 			for _, stmt := range c.handleTextNode(node) {
 				c.state.addNode(stmt)
@@ -172,11 +217,10 @@ func (c *converter) convert(node interface{}, typeCheck *simplifier.State) {
 		}
 
 	case *parse.ListNode:
-		for _, n := range node.Nodes {
-			c.convert(n, typeCheck)
-		}
+		c.convertList(node.Nodes, typeCheck)
 
 	case *parse.ActionNode:
+		c.action = node
 
 		optimized := c.handleOptimizedActionNode(node, typeCheck)
 		if len(optimized) > 0 {
@@ -194,17 +238,13 @@ func (c *converter) convert(node interface{}, typeCheck *simplifier.State) {
 		ifStmt := c.handleIfNode(node, typeCheck)
 		c.state.addNode(ifStmt)
 		c.state.enter(ifStmt.Body, c.state.dotVar())
-		for _, n := range node.List.Nodes {
-			c.convert(n, typeCheck)
-		}
+		c.convertList(node.List.Nodes, typeCheck)
 		c.state.leave()
 
 		if ifStmt.Else != nil {
 			elseStmt := ifStmt.Else.(*ast.BlockStmt)
 			c.state.enter(elseStmt, c.state.dotVar())
-			for _, n := range node.ElseList.Nodes {
-				c.convert(n, typeCheck)
-			}
+			c.convertList(node.ElseList.Nodes, typeCheck)
 			c.state.leave()
 		}
 
@@ -213,18 +253,14 @@ func (c *converter) convert(node interface{}, typeCheck *simplifier.State) {
 		c.state.addNode(rangeStmt)
 		typeCheck.Enter()
 		c.state.enter(rangeStmt.Body, dotVarName)
-		for _, n := range node.List.Nodes {
-			c.convert(n, typeCheck)
-		}
+		c.convertList(node.List.Nodes, typeCheck)
 		c.state.leave()
 
 		if node.ElseList != nil {
 			elseStmt := c.handleRangeElseNode(node, typeCheck)
 			c.state.addNode(elseStmt)
 			c.state.enter(elseStmt.Body, c.state.dotVar())
-			for _, n := range node.ElseList.Nodes {
-				c.convert(n, typeCheck)
-			}
+			c.convertList(node.ElseList.Nodes, typeCheck)
 			c.state.leave()
 		}
 		typeCheck.Leave()
@@ -239,22 +275,19 @@ func (c *converter) convert(node interface{}, typeCheck *simplifier.State) {
 		c.state.addNode(embedInBlockStmt(ifStmt))
 		c.state.enter(ifStmt.Body, dotVarName)
 		typeCheck.Enter()
-		for _, n := range node.List.Nodes {
-			c.convert(n, typeCheck)
-		}
+		c.convertList(node.List.Nodes, typeCheck)
 		c.state.leave()
 
 		if ifStmt.Else != nil {
 			elseStmt := ifStmt.Else.(*ast.BlockStmt)
 			c.state.enter(elseStmt, c.state.dotVar())
-			for _, n := range node.ElseList.Nodes {
-				c.convert(n, typeCheck)
-			}
+			c.convertList(node.ElseList.Nodes, typeCheck)
 			c.state.leave()
 		}
 		typeCheck.Leave()
 
 	case *parse.TemplateNode:
+		c.action = node
 		for _, stmt := range c.handleTemplateNode(node, typeCheck) {
 			c.state.addNode(stmt)
 		}
@@ -265,6 +298,161 @@ func (c *converter) convert(node interface{}, typeCheck *simplifier.State) {
 	}
 }
 
+// convertList converts a list of nodes sharing the same scope.
+// The declarations of the lazy operands of and/or calls are deferred,
+// they are converted into the branches of the call.
+func (c *converter) convertList(nodes []parse.Node, typeCheck *simplifier.State) {
+	c.deferLazyOperands(nodes)
+	var converted []parse.Node
+	for _, n := range nodes {
+		if _, ok := c.deferredNodes[n]; ok == false {
+			converted = append(converted, n)
+		}
+	}
+	for i, n := range converted {
+		c.nextNode = nil
+		if i+1 < len(converted) {
+			c.nextNode = converted[i+1]
+		}
+		c.convert(n, typeCheck)
+	}
+	c.nextNode = nil
+}
+
+// deferLazyOperands looks for and/or calls within nodes,
+// for each operand evaluated lazily, it registers the nodes declaring it,
+// so they are not converted before the call.
+// A node already deferred by a nested call stays owned by this nested call.
+func (c *converter) deferLazyOperands(nodes []parse.Node) {
+	for i, n := range nodes {
+		action, ok := n.(*parse.ActionNode)
+		if ok == false || len(action.Pipe.Decl) != 1 || len(action.Pipe.Cmds) != 1 {
+			continue
+		}
+		cmd := action.Pipe.Cmds[0]
+		ident, ok := cmd.Args[0].(*parse.IdentifierNode)
+		if ok == false || (ident.Ident != "and" && ident.Ident != "or") || len(cmd.Args) < 3 {
+			continue
+		}
+		for _, arg := range cmd.Args[2:] {
+			v, ok := arg.(*parse.VariableNode)
+			if ok == false || len(v.Ident) != 1 || isSimplifiedVar(v.Ident[0]) == false {
+				continue
+			}
+			decls := collectDeclarations(nodes[:i], v.Ident[0])
+			if len(decls) == 0 || isReferencedOutside(nodes, decls, action) {
+				continue
+			}
+			c.lazyOperands[v.Ident[0]] = decls
+			for _, d := range decls {
+				if _, ok := c.deferredNodes[d]; ok == false {
+					c.deferredNodes[d] = v.Ident[0]
+				}
+			}
+		}
+	}
+}
+
+// collectDeclarations returns the nodes declaring the variable name,
+// including the declarations of the simplified variables it depends on,
+// in their original order.
+func collectDeclarations(nodes []parse.Node, name string) []parse.Node {
+	for i := len(nodes) - 1; i >= 0; i-- {
+		action, ok := nodes[i].(*parse.ActionNode)
+		if ok == false || len(action.Pipe.Decl) != 1 || action.Pipe.Decl[0].Ident[0] != name {
+			continue
+		}
+		var ret []parse.Node
+		for _, cmd := range action.Pipe.Cmds {
+			for _, arg := range cmd.Args {
+				if v, ok := arg.(*parse.VariableNode); ok && isSimplifiedVar(v.Ident[0]) {
+					ret = append(ret, collectDeclarations(nodes[:i], v.Ident[0])...)
+				}
+			}
+		}
+		return append(ret, action)
+	}
+	return nil
+}
+
+// isReferencedOutside tells if a variable declared by decls
+// is used by a node of the list other than decls and the call.
+func isReferencedOutside(nodes []parse.Node, decls []parse.Node, call parse.Node) bool {
+	refs := map[string]bool{}
+	for _, n := range nodes {
+		if n != call && containsNode(decls, n) == false {
+			collectVarRefs(n, refs)
+		}
+	}
+	for _, d := range decls {
+		if refs[d.(*parse.ActionNode).Pipe.Decl[0].Ident[0]] {
+			return true
+		}
+	}
+	return false
+}
+
+// collectVarRefs collects the names of the variables read or declared within node.
+func collectVarRefs(node parse.Node, names map[string]bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			collectVarRefs(c, names)
+		}
+	case *parse.ActionNode:
+		collectVarRefs(n.Pipe, names)
+	case *parse.TemplateNode:
+		collectVarRefs(n.Pipe, names)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, d := range n.Decl {
+			names[d.Ident[0]] = true
+		}
+		for _, cmd := range n.Cmds {
+			collectVarRefs(cmd, names)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			collectVarRefs(arg, names)
+		}
+	case *parse.ChainNode:
+		collectVarRefs(n.Node, names)
+	case *parse.VariableNode:
+		names[n.Ident[0]] = true
+	case *parse.IfNode:
+		collectVarRefs(&n.BranchNode, names)
+	case *parse.RangeNode:
+		collectVarRefs(&n.BranchNode, names)
+	case *parse.WithNode:
+		collectVarRefs(&n.BranchNode, names)
+	case *parse.BranchNode:
+		collectVarRefs(n.Pipe, names)
+		collectVarRefs(n.List, names)
+		collectVarRefs(n.ElseList, names)
+	}
+}
+
+func containsNode(list []parse.Node, search parse.Node) bool {
+	for _, n := range list {
+		if n == search {
+			return true
+		}
+	}
+	return false
+}
+
+// isSimplifiedVar tells if a variable was introduced by the simplifier.
+func isSimplifiedVar(name string) bool {
+	return simplifiedVarRegexp.MatchString(name)
+}
+
+var simplifiedVarRegexp = regexp.MustCompile(`^\$var[0-9]+$`)
+
 func injectReturnNil(fn *ast.FuncDecl) {
 	n := getStmtsAst(`return nil`)
 	fn.Body.List = append(fn.Body.List, n...)
@@ -294,7 +482,8 @@ func (c *converter) handleActionNode(node *parse.ActionNode, typeCheck *simplifi
 		t, _ := c.getTypesOfCommandNode(cmd, typeCheck)
 		expr := c.handleCommandNode(cmd, typeCheck)
 		if t != nil {
-			ret = c.makeIoWrite(astNodeToString(expr), t)
+			addressable := len(cmd.Args) == 1 && c.isAddressableNode(cmd.Args[0], typeCheck)
+			ret = c.makeValueWrite(astNodeToString(expr), t, addressable)
 		} else {
 			ret = append(ret, &ast.ExprStmt{X: expr})
 		}
@@ -302,6 +491,7 @@ func (c *converter) handleActionNode(node *parse.ActionNode, typeCheck *simplifi
 	} else if len(node.Pipe.Cmds) == 1 { // likely a simple assignment $z := 4.
 		// this case could go into the next one, it would produce an assignement (:=)
 		// but this case is designed spcifically to produce var declaration with its type.
+		c.declareAddressables(node.Pipe.Decl, node.Pipe.Cmds[0], typeCheck)
 		expr := c.handleCommandNode(node.Pipe.Cmds[0], typeCheck)
 		exprType, outTypes := c.getTypesOfCommandNode(node.Pipe.Cmds[0], typeCheck)
 		if len(outTypes) > 0 {
@@ -311,7 +501,8 @@ func (c *converter) handleActionNode(node *parse.ActionNode, typeCheck *simplifi
 			// It is assumed that the second return parameter
 			// is an err of type error.
 			assignWithErr := c.makeAnAssignmentWithErr(node.Pipe.Decl, expr, typeCheck)
-			ret = append(ret, assignWithErr...)
+			callee := node.Pipe.Cmds[0].Args[0]
+			ret = append(ret, c.execErrorReturns(assignWithErr, callee, "error calling "+calleeName(callee))...)
 
 		} else if exprType != nil {
 			// this is a variable declaration,
@@ -323,6 +514,7 @@ func (c *converter) handleActionNode(node *parse.ActionNode, typeCheck *simplifi
 		}
 
 	} else { // likely a complex assignment
+		c.declareAddressables(node.Pipe.Decl, nil, typeCheck)
 		expr := c.handleCommandNode(node.Pipe.Cmds[0], typeCheck)
 		assign := c.makeAnAssignment(node.Pipe.Decl, expr, typeCheck)
 		ret = append(ret, assign)
@@ -337,32 +529,56 @@ func (c *converter) handleOptimizedActionNode(node *parse.ActionNode, typeCheck
 		cmd := node.Pipe.Cmds[0]
 		if ident, ok := cmd.Args[0].(*parse.IdentifierNode); ok { // func call
 
-			// optimize html escaping
-			if len(cmd.Args) == 2 &&
-				ident.Ident == "html" ||
-				ident.Ident == "_html_template_htmlescaper" {
-				argType, _ := c.getTypesOfSomeNode(cmd.Args[1], typeCheck)
-				if argType.Kind() == reflect.String {
+			// optimize boolean logic
+			if len(cmd.Args) > 1 &&
+				(ident.Ident == "and" || ident.Ident == "or") {
+				return c.handleAndOrActionNode(node, typeCheck)
+			}
 
-					alias := c.compiledProgram.addImport("text/template")
-					wStmt := getStmtsAst(c.bwriterName + `.WriteString(iterable)
-    			` + alias + `.HTMLEscape(` + c.writerName + `, ` + c.bwriterName + `.Bytes())
-    			` + c.bwriterName + `.Reset()`)
-					wstring := wStmt[0]
-					fnCall := wstring.(*ast.ExprStmt).X.(*ast.CallExpr)
-					fnCall.Args[0] = c.convertNode(cmd.Args[1], typeCheck)
+			// optimize printing builtins
+			if ident.Ident == "print" ||
+				ident.Ident == "printf" ||
+				ident.Ident == "println" ||
+				ident.Ident == "urlquery" ||
+				ident.Ident == "js" {
+				if pieces, ok := c.getPrintPieces(cmd, typeCheck); ok {
+					return c.handlePrintActionNode(node, pieces, typeCheck)
+				}
+			}
 
-					c.skipNextVarPrint = node.Pipe.Decl[0].Ident[0]
+			if len(cmd.Args) == 2 &&
+				ident.Ident == "not" {
+				argType, _ := c.getTypesOfSomeNode(cmd.Args[1], typeCheck)
+				argExpr := c.convertNode(cmd.Args[1], typeCheck)
+				varDeclStmt := c.makeVarDeclaration(
+					node.Pipe.Decl[0],
+					reflect.TypeOf(true),
+					c.makeNegatedBinaryTest(argExpr, argType),
+					typeCheck)
+				c.varTypes[node.Pipe.Decl[0].Ident[0]] = reflect.TypeOf(true)
+				ret = append(ret, varDeclStmt)
+				return ret
+			}
 
-					c.injectByteWriterPrelude()
+			//-
 
-					ret = append(ret, wStmt...)
-					return ret
+			// optimize html/template and html escaping of strings.
+			if name, ok := streamingEscapers[ident.Ident]; ok && len(cmd.Args) == 2 &&
+				isBuiltinFunc(ident.Ident, c.funcsMap, c.publicIdents) {
+				argType, _ := c.getTypesOfSomeNode(cmd.Args[1], typeCheck)
+				decl := node.Pipe.Decl[0].Ident[0]
+				// typed contents such template.HTML must go through the regular escaper.
+				if argType == reflect.TypeOf("") && c.isPrintedNext(decl) {
+					alias := c.compiledProgram.addImport("github.com/mh-cbon/template-compiler/std/html/template")
+					arg := astNodeToString(c.convertNode(cmd.Args[1], typeCheck))
+					c.skipNextVarPrint = decl
+					return c.execErrorReturns(getStmtsAst(`
+if werr := ` + alias + `.` + name + `(` + c.writerName + `, ` + arg + `); werr != nil {
+  return werr
+}`), c.action, "")
 				}
 			}
 
-			//-
-
 			// 	// they are equivalent funcs which can be replaced in place (almost).
 			// 	if f, ok := optimizedCalls[ident.Ident]; ok {
 			// 		// there may be something to do.
@@ -479,6 +695,366 @@ func (c *converter) handleOptimizedActionNode(node *parse.ActionNode, typeCheck
 	}
 	return ret
 }
+
+// handleAndOrActionNode converts an and/or call into nested if statements,
+// each operand is evaluated only if the previous operands did not decide the result.
+// The result var is typed after its operands if they share the same type,
+// otherwise it is an interface{} receiving the deciding operand.
+func (c *converter) handleAndOrActionNode(node *parse.ActionNode, typeCheck *simplifier.State) []ast.Stmt {
+	cmd := node.Pipe.Cmds[0]
+	isOr := cmd.Args[0].(*parse.IdentifierNode).Ident == "or"
+	operands := cmd.Args[1:]
+
+	resType, _ := c.getTypesOfSomeNode(operands[0], typeCheck)
+	for _, a := range operands[1:] {
+		argType, _ := c.getTypesOfSomeNode(a, typeCheck)
+		if argType != resType {
+			resType = reflect.TypeOf((*interface{})(nil)).Elem()
+			break
+		}
+	}
+
+	decl := node.Pipe.Decl[0]
+	c.varTypes[decl.Ident[0]] = resType
+	resVar := c.convertVariableNode(decl, typeCheck)
+
+	prevExpr := c.convertNode(operands[0], typeCheck)
+	prevType, _ := c.getTypesOfSomeNode(operands[0], typeCheck)
+	ret := []ast.Stmt{c.makeVarDeclaration(decl, resType, prevExpr, typeCheck)}
+
+	body := &ret
+	for _, a := range operands[1:] {
+		// prefer to test the operand itself, it does not need a runtime type check.
+		testExpr, testType := resVar, resType
+		switch prevExpr.(type) {
+		case *ast.Ident, *ast.BasicLit:
+			testExpr, testType = prevExpr, prevType
+		}
+		ifStmt := &ast.IfStmt{Body: &ast.BlockStmt{}}
+		if isOr {
+			ifStmt.Cond = c.makeNegatedBinaryTest(testExpr, testType)
+		} else {
+			ifStmt.Cond = c.makeBinaryTest(testExpr, testType)
+		}
+		*body = append(*body, ifStmt)
+
+		c.state.enter(ifStmt.Body, c.state.dotVar())
+		if v, ok := a.(*parse.VariableNode); ok {
+			for _, n := range c.lazyOperands[v.Ident[0]] {
+				if c.deferredNodes[n] == v.Ident[0] {
+					c.nextNode = nil
+					c.convert(n, typeCheck)
+				}
+			}
+		}
+		prevExpr = c.convertNode(a, typeCheck)
+		prevType, _ = c.getTypesOfSomeNode(a, typeCheck)
+		c.state.addNode(&ast.AssignStmt{
+			Lhs: []ast.Expr{resVar},
+			Tok: token.ASSIGN,
+			Rhs: []ast.Expr{prevExpr},
+		})
+		c.state.leave()
+
+		body = &ifStmt.Body.List
+	}
+	return ret
+}
+
+// printPiece is a part of the output of a printing builtin,
+// it is either a static text, or a value to format.
+type printPiece struct {
+	text     string
+	expr     ast.Expr
+	exprType reflect.Type
+	// quote the value as the %q verb does.
+	quote bool
+	// escaper is the selector of a func applied to a string value, such as url.QueryEscape.
+	escaperPkg  string
+	escaperName string
+}
+
+var (
+	stringerType  = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+	errorType     = reflect.TypeOf((*error)(nil)).Elem()
+	formatterType = reflect.TypeOf((*fmt.Formatter)(nil)).Elem()
+)
+
+// isStaticallyPrintable tells if a value of type t is printed by fmt
+// only according to its kind, so it can be formatted with strconv.
+func isStaticallyPrintable(t reflect.Type) bool {
+	if t == nil ||
+		t.Implements(stringerType) ||
+		t.Implements(errorType) ||
+		t.Implements(formatterType) {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// getPrintPieces splits a call to a printing builtin into the pieces it outputs.
+// It returns false if the types of the arguments are not statically printable,
+// if the call requires a formatting not handled,
+// or if the funcmap overrides the builtin.
+func (c *converter) getPrintPieces(cmd *parse.CommandNode, typeCheck *simplifier.State) ([]printPiece, bool) {
+	ident := cmd.Args[0].(*parse.IdentifierNode).Ident
+	args := cmd.Args[1:]
+	var pieces []printPiece
+
+	if isBuiltinFunc(ident, c.funcsMap, c.publicIdents) == false {
+		return nil, false
+	}
+
+	switch ident {
+	case "urlquery", "js":
+		if len(args) != 1 {
+			return nil, false
+		}
+		argType, out := c.getTypesOfSomeNode(args[0], typeCheck)
+		if len(out) > 0 || isStaticallyPrintable(argType) == false || argType.Kind() != reflect.String {
+			return nil, false
+		}
+		p := printPiece{expr: c.convertNode(args[0], typeCheck), exprType: argType}
+		p.escaperPkg, p.escaperName = "net/url", "QueryEscape"
+		if ident == "js" {
+			p.escaperPkg, p.escaperName = "github.com/mh-cbon/template-compiler/std/text/template", "JSEscapeString"
+		}
+		pieces = append(pieces, p)
+
+	case "printf":
+		if len(args) == 0 {
+			return nil, false
+		}
+		format, ok := args[0].(*parse.StringNode)
+		if ok == false {
+			return nil, false
+		}
+		return c.getPrintfPieces(format.Text, args[1:], typeCheck)
+
+	case "print", "println":
+		// see fmt.Sprint, spaces are added between operands when neither is a string,
+		// fmt.Sprintln always adds spaces and a newline.
+		prevString := false
+		for i, a := range args {
+			argType, out := c.getTypesOfSomeNode(a, typeCheck)
+			if len(out) > 0 || isStaticallyPrintable(argType) == false {
+				return nil, false
+			}
+			isString := argType.Kind() == reflect.String
+			if i > 0 && (ident == "println" || (isString == false && prevString == false)) {
+				pieces = append(pieces, printPiece{text: " "})
+			}
+			pieces = append(pieces, printPiece{expr: c.convertNode(a, typeCheck), exprType: argType})
+			prevString = isString
+		}
+		if ident == "println" {
+			pieces = append(pieces, printPiece{text: "\n"})
+		}
+	}
+	return mergePrintPieces(pieces), true
+}
+
+// getPrintfPieces splits a printf format into the pieces it outputs.
+// Only the verbs %v %s %d %t %q without flags, width or precision are handled.
+func (c *converter) getPrintfPieces(format string, args []parse.Node, typeCheck *simplifier.State) ([]printPiece, bool) {
+	var pieces []printPiece
+	argNum := 0
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			pieces = append(pieces, printPiece{text: format[i : i+1]})
+			continue
+		}
+		i++
+		if i >= len(format) {
+			return nil, false
+		}
+		verb := format[i]
+		if verb == '%' {
+			pieces = append(pieces, printPiece{text: "%"})
+			continue
+		}
+		if argNum >= len(args) {
+			return nil, false
+		}
+		argType, out := c.getTypesOfSomeNode(args[argNum], typeCheck)
+		if len(out) > 0 || isStaticallyPrintable(argType) == false {
+			return nil, false
+		}
+		switch verb {
+		case 'v':
+		case 's', 'q':
+			if argType.Kind() != reflect.String {
+				return nil, false
+			}
+		case 't':
+			if argType.Kind() != reflect.Bool {
+				return nil, false
+			}
+		case 'd':
+			if isIntegerKind(argType.Kind()) == false {
+				return nil, false
+			}
+		default:
+			return nil, false
+		}
+		pieces = append(pieces, printPiece{
+			expr:     c.convertNode(args[argNum], typeCheck),
+			exprType: argType,
+			quote:    verb == 'q',
+		})
+		argNum++
+	}
+	if argNum != len(args) {
+		return nil, false
+	}
+	return mergePrintPieces(pieces), true
+}
+
+// mergePrintPieces merges consecutive static texts.
+func mergePrintPieces(pieces []printPiece) []printPiece {
+	var ret []printPiece
+	for _, p := range pieces {
+		if p.expr == nil && len(ret) > 0 && ret[len(ret)-1].expr == nil {
+			ret[len(ret)-1].text += p.text
+			continue
+		}
+		ret = append(ret, p)
+	}
+	return ret
+}
+
+func isIntegerKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// handlePrintActionNode converts a call to a printing builtin.
+// If the result is printed right after, the pieces are written directly to the writer,
+// otherwise the result var is declared as the concatenation of the pieces.
+func (c *converter) handlePrintActionNode(node *parse.ActionNode, pieces []printPiece, typeCheck *simplifier.State) []ast.Stmt {
+	var ret []ast.Stmt
+	decl := node.Pipe.Decl[0]
+	if c.isPrintedNext(decl.Ident[0]) {
+		for _, p := range pieces {
+			ret = append(ret, c.makePieceWrite(p)...)
+		}
+		if len(ret) > 0 {
+			c.skipNextVarPrint = decl.Ident[0]
+			return ret
+		}
+	}
+	exprs := []string{}
+	for _, p := range pieces {
+		exprs = append(exprs, c.makePieceString(p))
+	}
+	if len(exprs) == 0 {
+		exprs = append(exprs, `""`)
+	}
+	expr := getStmtsAst(strings.Join(exprs, " + "))[0].(*ast.ExprStmt).X
+	c.varTypes[decl.Ident[0]] = reflect.TypeOf("")
+	ret = append(ret, c.makeVarDeclaration(decl, reflect.TypeOf(""), expr, typeCheck))
+	return ret
+}
+
+// isPrintedNext tells if the next node to convert only prints the simplified variable name.
+func (c *converter) isPrintedNext(name string) bool {
+	if isSimplifiedVar(name) == false {
+		return false
+	}
+	action, ok := c.nextNode.(*parse.ActionNode)
+	if ok == false || len(action.Pipe.Decl) > 0 || len(action.Pipe.Cmds) != 1 || len(action.Pipe.Cmds[0].Args) != 1 {
+		return false
+	}
+	v, ok := action.Pipe.Cmds[0].Args[0].(*parse.VariableNode)
+	return ok && len(v.Ident) == 1 && v.Ident[0] == name
+}
+
+// makePieceString returns the go expression of the string value of a piece.
+func (c *converter) makePieceString(p printPiece) string {
+	if p.expr == nil {
+		return fmt.Sprintf("%q", p.text)
+	}
+	expr := astNodeToString(p.expr)
+	if p.escaperName != "" {
+		alias := c.compiledProgram.addImport(p.escaperPkg)
+		return alias + "." + p.escaperName + "(" + convertToBasicType(expr, p.exprType) + ")"
+	}
+	if p.exprType.Kind() == reflect.String {
+		if p.quote {
+			strconvalias := c.compiledProgram.addImport("strconv")
+			return strconvalias + ".Quote(" + convertToBasicType(expr, p.exprType) + ")"
+		}
+		return convertToBasicType(expr, p.exprType)
+	}
+	strconvalias := c.compiledProgram.addImport("strconv")
+	switch p.exprType.Kind() {
+	case reflect.Bool:
+		return strconvalias + ".FormatBool(" + convertToBasicType(expr, p.exprType) + ")"
+	case reflect.Int:
+		return strconvalias + ".Itoa(" + convertToBasicType(expr, p.exprType) + ")"
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconvalias + ".FormatInt(int64(" + expr + "), 10)"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconvalias + ".FormatUint(uint64(" + expr + "), 10)"
+	case reflect.Float32:
+		return strconvalias + ".FormatFloat(float64(" + expr + "), 'g', -1, 32)"
+	}
+	return strconvalias + ".FormatFloat(float64(" + expr + "), 'g', -1, 64)"
+}
+
+// makePieceWrite returns the statements to write a piece to the writer.
+// Numbers, booleans and quoted strings are appended to a scratch buffer before being written.
+func (c *converter) makePieceWrite(p printPiece) []ast.Stmt {
+	if p.expr == nil {
+		builtinName := c.compiledProgram.addBuiltintText(p.text)
+		return c.makeIoWrite(builtinName, reflect.TypeOf([]byte{}))
+	}
+	if p.escaperName != "" || (p.exprType.Kind() == reflect.String && p.quote == false) {
+		return c.makeIoWrite(c.makePieceString(p), reflect.TypeOf(""))
+	}
+	expr := astNodeToString(p.expr)
+	buf := c.abufferName
+	strconvalias := c.compiledProgram.addImport("strconv")
+	appendCall := ""
+	switch p.exprType.Kind() {
+	case reflect.String:
+		appendCall = strconvalias + ".AppendQuote(" + buf + "[:0], " + convertToBasicType(expr, p.exprType) + ")"
+	case reflect.Bool:
+		appendCall = strconvalias + ".AppendBool(" + buf + "[:0], " + convertToBasicType(expr, p.exprType) + ")"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		appendCall = strconvalias + ".AppendInt(" + buf + "[:0], int64(" + expr + "), 10)"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		appendCall = strconvalias + ".AppendUint(" + buf + "[:0], uint64(" + expr + "), 10)"
+	case reflect.Float32:
+		appendCall = strconvalias + ".AppendFloat(" + buf + "[:0], float64(" + expr + "), 'g', -1, 32)"
+	default:
+		appendCall = strconvalias + ".AppendFloat(" + buf + "[:0], float64(" + expr + "), 'g', -1, 64)"
+	}
+	c.injectAppendBufferPrelude()
+	ret := getStmtsAst(buf + ` = ` + appendCall)
+	return append(ret, c.makeIoWrite(buf, reflect.TypeOf([]byte{}))...)
+}
+
+// convertToBasicType converts an expression of a named type to its underlying basic type.
+func convertToBasicType(expr string, t reflect.Type) string {
+	basic := t.Kind().String()
+	if t.String() == basic {
+		return expr
+	}
+	return basic + "(" + expr + ")"
+}
+
 func (c *converter) handleIfNode(node *parse.IfNode, typeCheck *simplifier.State) *ast.IfStmt {
 	if len(node.Pipe.Decl) > 0 {
 		err := fmt.Errorf(
@@ -519,6 +1095,10 @@ func (c *converter) handleTemplateNode(node *parse.TemplateNode, typeCheck *simp
 		}
 	}
 
+	if l, ok := c.compiledProgram.getLinkedTemplate(node.Name); ok && c.compiledProgram.recursive[c.tree.Name][node.Name] == false {
+		return c.handleLinkedTemplateNode(node, l, typeCheck)
+	}
+
 	expr := ", nil"
 	if node.Pipe != nil {
 		exprStmt := c.handleCommandNode(node.Pipe.Cmds[0], typeCheck)
@@ -553,6 +1133,11 @@ func (c *converter) handleRangeNode(node *parse.RangeNode, typeCheck *simplifier
 	}
 	ret.X = c.convertNode(node.BranchNode.Pipe.Cmds[0].Args[0], typeCheck)
 
+	if len(decl) == 2 {
+		c.addressables[decl[0].Ident[0][1:]] = false
+	}
+	c.addressables[dotVarName] = c.isRangeElemAddressable(node.BranchNode.Pipe.Cmds[0].Args[0], typeCheck)
+
 	return ret, dotVarName
 }
 func (c *converter) handleRangeElseNode(node *parse.RangeNode, typeCheck *simplifier.State) *ast.IfStmt {
@@ -580,6 +1165,7 @@ func (c *converter) handleWithNode(node *parse.WithNode, typeCheck *simplifier.S
 		Body: &ast.BlockStmt{},
 	}
 	if len(node.Pipe.Decl) > 0 {
+		c.declareAddressables(node.Pipe.Decl, node.Pipe.Cmds[0], typeCheck)
 		expr := c.handleCommandNode(node.Pipe.Cmds[0], typeCheck)
 		assign := &ast.AssignStmt{}
 		assign.Tok = token.DEFINE
@@ -592,7 +1178,7 @@ func (c *converter) handleWithNode(node *parse.WithNode, typeCheck *simplifier.S
 		}
 		ifStmt.Init = assign
 		varToTest := c.convertNode(node.Pipe.Decl[0], typeCheck)
-		typeToTest := typeCheck.GetVar(node.Pipe.Decl[0].Ident[0])
+		typeToTest := c.getVarType(typeCheck, node.Pipe.Decl[0].Ident[0])
 		ifStmt.Cond = c.makeBinaryTest(varToTest, typeToTest)
 		dotVarName = node.Pipe.Decl[0].Ident[0]
 
@@ -687,6 +1273,76 @@ func (c *converter) makeVarDeclaration(decl *parse.VariableNode, exprType reflec
 	return &ast.DeclStmt{Decl: astDecl}
 }
 
+// declareAddressables records if the variables declared by a pipeline hold a value
+// the interpreter can address, cmd is nil when the value is the result of several commands.
+func (c *converter) declareAddressables(decls []*parse.VariableNode, cmd *parse.CommandNode, typeCheck *simplifier.State) {
+	addressable := len(decls) == 1 && cmd != nil && len(cmd.Args) == 1 &&
+		c.isAddressableNode(cmd.Args[0], typeCheck)
+	for _, decl := range decls {
+		c.addressables[decl.Ident[0][1:]] = addressable
+	}
+}
+
+// isAddressableNode tells if the interpreter evaluates node to an addressable value,
+// that is a value reached through a pointer or a slice element.
+func (c *converter) isAddressableNode(node parse.Node, typeCheck *simplifier.State) bool {
+	switch x := node.(type) {
+	case *parse.DotNode:
+		return c.addressables[c.state.dotVar()]
+
+	case *parse.FieldNode:
+		return isAddressablePath(c.addressables[c.state.dotVar()], typeCheck.Dot(), x.Ident)
+
+	case *parse.VariableNode:
+		varType := c.getVarType(typeCheck, x.Ident[0])
+		return isAddressablePath(c.addressables[x.Ident[0][1:]], varType, x.Ident[1:])
+	}
+	return false
+}
+
+// isRangeElemAddressable tells if the interpreter can address the elements
+// of the value of node when it ranges over them.
+func (c *converter) isRangeElemAddressable(node parse.Node, typeCheck *simplifier.State) bool {
+	t, _ := c.getTypesOfSomeNode(node, typeCheck)
+	if t == nil {
+		return false
+	}
+	addressable := c.isAddressableNode(node, typeCheck)
+	if t.Kind() == reflect.Ptr {
+		addressable, t = true, t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Slice:
+		return true
+	case reflect.Array:
+		return addressable
+	}
+	return false
+}
+
+// isAddressablePath tells if the value of the fields path of a value of type t is addressable,
+// addressable tells if the value of type t is addressable.
+// The results of methods and the values of maps are not addressable.
+func isAddressablePath(addressable bool, t reflect.Type, path []string) bool {
+	for _, name := range path {
+		if t == nil {
+			return false
+		}
+		if t.Kind() == reflect.Ptr {
+			addressable, t = true, t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return false
+		}
+		f, ok := t.FieldByName(name)
+		if ok == false {
+			return false
+		}
+		t = f.Type
+	}
+	return addressable
+}
+
 // Identify and returns the value type of the command node.
 func (c *converter) getTypesOfCommandNode(node *parse.CommandNode, typeCheck *simplifier.State) (reflect.Type, []reflect.Type) {
 	return c.getTypesOfSomeNode(node.Args[0], typeCheck)
@@ -717,7 +1373,7 @@ func (c *converter) getTypesOfSomeNode(node parse.Node, typeCheck *simplifier.St
 		}
 
 	case *parse.VariableNode:
-		y := typeCheck.GetVar(x.Ident[0])
+		y := c.getVarType(typeCheck, x.Ident[0])
 
 		if typeCheck.IsMethodPath(x.Ident[1:], y) {
 			methType := typeCheck.ReflectPath(x.Ident[1:], y)
@@ -772,6 +1428,15 @@ func (c *converter) getTypesOfSomeNode(node parse.Node, typeCheck *simplifier.St
 	}
 	return ret, out
 }
+
+// getVarType returns the type of a template variable,
+// the types of the variables declared by the optimized calls take precedence over the type checker.
+func (c *converter) getVarType(typeCheck *simplifier.State, name string) reflect.Type {
+	if t, ok := c.varTypes[name]; ok {
+		return t
+	}
+	return typeCheck.GetVar(name)
+}
 func (c *converter) getFunc(name string) (interface{}, bool) {
 	if x, ok := c.funcsMap[name]; ok {
 		return x, ok
@@ -854,10 +1519,22 @@ func (c *converter) makeBinaryTest(expr ast.Expr, exprType reflect.Type) ast.Exp
 		ret.Op = token.NEQ
 		ret.Y = &ast.BasicLit{Kind: token.INT, Value: `0`}
 
-	case reflect.Float32, reflect.Float64:
+	case reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
 		ret.Op = token.NEQ
 		ret.Y = &ast.BasicLit{Kind: token.INT, Value: `0`}
 
+	case reflect.Ptr, reflect.Chan, reflect.Func:
+		ret.Op = token.NEQ
+		ret.Y = &ast.Ident{Name: "nil"}
+
+	case reflect.Interface:
+		// the truth depends on the runtime value.
+		alias := c.compiledProgram.addImport("github.com/mh-cbon/template-compiler/std/text/template")
+		f := getStmtsAst(alias + `.Truth(x)`)[0]
+		truthCall := f.(*ast.ExprStmt).X.(*ast.CallExpr)
+		truthCall.Args[0] = expr
+		return truthCall
+
 	case reflect.Bool:
 		// a bool expr, return it as is
 		return expr
@@ -886,6 +1563,24 @@ func (c *converter) makeBinaryTest(expr ast.Expr, exprType reflect.Type) ast.Exp
 	}
 	return ret
 }
+
+// creates the negation of the binary test of an expression, such as a == b, for example.
+func (c *converter) makeNegatedBinaryTest(expr ast.Expr, exprType reflect.Type) ast.Expr {
+	test := c.makeBinaryTest(expr, exprType)
+	switch x := test.(type) {
+	case *ast.BinaryExpr:
+		if x.Op == token.NEQ || x.Op == token.GTR {
+			x.Op = token.EQL
+			return x
+		}
+		return &ast.UnaryExpr{Op: token.NOT, X: &ast.ParenExpr{X: x}}
+	case *ast.Ident:
+		if x.Name == "true" {
+			return &ast.Ident{Name: "false"}
+		}
+	}
+	return &ast.UnaryExpr{Op: token.NOT, X: test}
+}
 func (c *converter) convertNode(node parse.Node, typeCheck *simplifier.State) ast.Expr {
 	var ret ast.Expr
 	switch x := node.(type) {
@@ -986,10 +1681,22 @@ func (c *converter) convertIdentifierNode(node *parse.IdentifierNode) ast.Stmt {
 		out = out[0 : len(out)-1]
 	}
 
+	c.requireFunc(node.Ident, `func (`+in+`) (`+out+`)`)
 	return getStmtsAst(
 		`t.GetFuncs()["` + node.Ident + `"].(func (` + in + `) (` + out + `))()`,
 	)[0]
 }
+
+// requireFunc registers the func name of type fnType looked up at runtime by the template,
+// the generated init declares it to the registry.
+func (c *converter) requireFunc(name string, fnType string) {
+	funcs, ok := c.compiledProgram.requiredFuncs[c.tree.Name]
+	if ok == false {
+		funcs = map[string]string{}
+		c.compiledProgram.requiredFuncs[c.tree.Name] = funcs
+	}
+	funcs[name] = astNodeToString(stringToExpr(fnType))
+}
 func (c *converter) convertFieldNodeMethod(node *parse.FieldNode, typeCheck *simplifier.State) ast.Expr {
 	return c.convertFieldNode(node, typeCheck)
 }
@@ -1049,48 +1756,119 @@ func (c *converter) convertVariableNode(node *parse.VariableNode, typeCheck *sim
 			}
 		}
 	}
-	ismethod := typeCheck.IsMethodPath(node.Ident[1:], typeCheck.GetVar(node.Ident[0]))
+	ismethod := typeCheck.IsMethodPath(node.Ident[1:], c.getVarType(typeCheck, node.Ident[0]))
 	if ismethod {
 		// the last ast.SelectorExpr needs to be embeded with a CallExpr
 		ret = &ast.CallExpr{Fun: ret}
 	}
 	return ret
 }
-func (c *converter) injectByteWriterPrelude() {
-	hasByteWriterPrelude := false
-	if len(c.fn.Body.List) > 0 {
-		if x, ok := c.fn.Body.List[0].(*ast.DeclStmt); ok {
-			if xx, okk := x.Decl.(*ast.GenDecl); okk {
-				if y, okkk := xx.Specs[0].(*ast.ValueSpec); okkk {
-					hasByteWriterPrelude = y.Names[0].Name == c.bwriterName
-				}
+
+// injectAppendBufferPrelude declares the scratch buffer used to append formatted values.
+func (c *converter) injectAppendBufferPrelude() {
+	if c.hasPrelude(c.abufferName) == false {
+		bufVar := getStmtsAst(`var ` + c.abufferName + ` []byte`)
+		c.fn.Body.List = append(bufVar, c.fn.Body.List...)
+	}
+}
+
+// hasPrelude tells if the function starts with a declaration of the variable name.
+func (c *converter) hasPrelude(name string) bool {
+	for _, stmt := range c.fn.Body.List {
+		x, ok := stmt.(*ast.DeclStmt)
+		if ok == false {
+			return false
+		}
+		if xx, okk := x.Decl.(*ast.GenDecl); okk {
+			if y, okkk := xx.Specs[0].(*ast.ValueSpec); okkk && y.Names[0].Name == name {
+				return true
 			}
 		}
 	}
-	if hasByteWriterPrelude == false {
-		alias := c.compiledProgram.addImport("bytes")
-		bufVar := getStmtsAst(`var ` + c.bwriterName + ` ` + alias + `.Buffer`)
-		c.fn.Body.List = append(bufVar, c.fn.Body.List...)
-	}
+	return false
 }
 
 // func makeWriteErrorDecl() ast.Stmt {
 // 	return getStmtsAst(`var writeErr error`)[0]
 // }
 
-func makePrelude(dataQualifier string) []ast.Stmt {
+// makePrelude types the input data,
+// the function returns an error if the input data is not of the configured type.
+func makePrelude(dataQualifier, fmtalias, tplName, dataType string) []ast.Stmt {
+	errFormat := fmt.Sprintf(
+		"template: %v: unexpected data type %%T, wants %v",
+		strings.Replace(tplName, "%", "%%", -1), dataType)
 	return getStmtsAst(`
 var data ` + dataQualifier + `
 if d, ok := indata.(` + dataQualifier + `); ok {
   data = d
+} else if indata != nil {
+  return ` + fmtalias + `.Errorf(` + fmt.Sprintf("%q", errFormat) + `, indata)
 }`)
 }
 
-func makeByteWriterPrelude(wname, bwname string) []ast.Stmt {
-	return getStmtsAst(`var ` + bwname + ` bytes.Buffer`)
+// implementsPrintMethod tells if fmt prints a value of type t with its Error or String method.
+func implementsPrintMethod(t reflect.Type) bool {
+	return t.Implements(errorType) || t.Implements(stringerType)
+}
+
+// makeMethodWriteCall returns the write call of a value
+// using the method fmt would call to print it.
+// The pointer-receiver methods are used when the value is addressable,
+// just like the interpreter prints the address of the values it can address.
+// It returns false if the value must be printed with fmt.
+//
+// The Error method is preferred as fmt does.
+func (c *converter) makeMethodWriteCall(expr string, exprType reflect.Type, addressable bool) (string, bool) {
+	t := exprType
+	if t.Kind() == reflect.Interface {
+		// the dynamic type may print differently.
+		return "", false
+	}
+	if implementsPrintMethod(t) == false {
+		if addressable == false ||
+			t.Kind() == reflect.Ptr ||
+			implementsPrintMethod(reflect.PtrTo(t)) == false {
+			return "", false
+		}
+		// go takes the address of the value for us.
+		t = reflect.PtrTo(t)
+	}
+	if t.Implements(formatterType) {
+		return "", false
+	}
+	ioalias := c.compiledProgram.addImport("io")
+	if t.Implements(errorType) {
+		return ioalias + ".WriteString(w, " + expr + ".Error())", true
+	}
+	return ioalias + ".WriteString(w, " + expr + ".String())", true
 }
 
 func (c *converter) makeIoWrite(expr string, exprType reflect.Type) []ast.Stmt {
+	return c.makeValueWrite(expr, exprType, false)
+}
+
+// makeValueWrite returns the statements writing a value,
+// addressable tells if the interpreter can address the value.
+func (c *converter) makeValueWrite(expr string, exprType reflect.Type, addressable bool) []ast.Stmt {
+	if writeCall, ok := c.makeMethodWriteCall(expr, exprType, addressable); ok {
+		if exprType.Kind() == reflect.Ptr {
+			// let fmt handles nil pointers.
+			fmtalias := c.compiledProgram.addImport("fmt")
+			return c.execErrorReturns(getStmtsAst(`
+if ` + expr + ` == nil {
+  if _, werr := ` + fmtalias + `.Fprintf(w, "%v", ` + expr + `); werr!=nil{
+    return werr
+  }
+} else if _, werr := ` + writeCall + `; werr!=nil{
+  return werr
+}`), c.action, "")
+		}
+		return c.execErrorReturns(getStmtsAst(`
+if _, werr := ` + writeCall + `; werr!=nil{
+  return werr
+}`), c.action, "")
+	}
 	writeCall := ""
 	ioalias := c.compiledProgram.addImport("io")
 	switch exprType.Kind() {
@@ -1131,10 +1909,6 @@ func (c *converter) makeIoWrite(expr string, exprType reflect.Type) []ast.Stmt {
 			writeCall = "w.Write(" + expr + ")"
 
 		default:
-			// todo: if the type implements a Byter{Byte()[]byte},
-			// or Writer{Write(to) len, err}
-			// or Stringer{String() string}
-			// make use of those.
 			fmtalias := c.compiledProgram.addImport("fmt")
 			writeCall = fmtalias + ".Fprintf(w, \"%v\", " + expr + ")"
 		}
@@ -1149,10 +1923,10 @@ func (c *converter) makeIoWrite(expr string, exprType reflect.Type) []ast.Stmt {
 			exprType, exprType.Kind())
 		panic(err)
 	}
-	return getStmtsAst(`
+	return c.execErrorReturns(getStmtsAst(`
 if _, werr := ` + writeCall + `; werr!=nil{
   return werr
-}`)
+}`), c.action, "")
 }
 
 func mustBeExportedTypes(some []reflect.Type) (reflect.Type, bool) {
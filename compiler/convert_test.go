@@ -53,6 +53,29 @@ type TemplateData struct {
 	SomeInterface         interface{}
 	SomeTemplateData      *TemplateData
 	SomeTemplateDataSlice []*TemplateData
+	SomeStringer          TemplateStringer
+	SomeStringerSlice     []TemplateStringer
+	SomeValueStringer     TemplateValueStringer
+}
+
+// TemplateStringer implements fmt.Stringer with a pointer receiver,
+// a TemplateStringer value is printed with its String method only when it is addressable.
+type TemplateStringer struct{}
+
+func (t *TemplateStringer) String() string {
+	return "stringer!"
+}
+
+// TemplateValueStringer implements fmt.Stringer with a value receiver,
+// fmt ignores its Bytes method.
+type TemplateValueStringer [2]byte
+
+func (t TemplateValueStringer) String() string {
+	return "value stringer!"
+}
+
+func (t TemplateValueStringer) Bytes() []byte {
+	return t[:]
 }
 
 func (t TemplateData) MethodHello() string {
@@ -120,11 +143,11 @@ return nil
 			},
 		},
 		TestData{
-			tplstr:    `{{$y := "Hello!"}}{{$y}}`,
-			dataValue: TemplateData{},
+			tplstr:         `{{$y := "Hello!"}}{{$y}}`,
+			dataValue:      TemplateData{},
+			expectBuiltins: map[string]string{"Hello!": "builtin0"},
 			expectCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface {}) error {
-  var tplY string = "Hello!"
-  if _, werr := io.WriteString(w, tplY); werr != nil {
+  if _, werr := w.Write(builtin0); werr != nil {
     return werr
   }
   return nil
@@ -134,33 +157,23 @@ return nil
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
 			},
 			expectHTMLCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
-  var bw bytes.Buffer
-  var tplY string = "Hello!"
-  bw.WriteString(tplY)
-  template.HTMLEscape(w, bw.Bytes())
-  bw.Reset()
+  if _, werr := w.Write(builtin0); werr != nil {
+    return werr
+  }
   return nil
 }`,
 			expectHTMLImports: []string{
 				"io",
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
-				"text/template",
-				"bytes",
 			},
 		},
 		TestData{
 			tplstr:         `{{if true}}true{{else}}false{{end}}`,
 			dataValue:      TemplateData{},
-			expectBuiltins: map[string]string{"true": "builtin0", "false": "builtin1"},
+			expectBuiltins: map[string]string{"true": "builtin0"},
 			expectCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface {}) error {
-  if true {
-    if _, werr := w.Write(builtin0); werr != nil {
-      return werr
-    }
-  } else {
-    if _, werr := w.Write(builtin1); werr != nil {
-      return werr
-    }
+  if _, werr := w.Write(builtin0); werr != nil {
+    return werr
   }
   return nil
 }`,
@@ -169,14 +182,8 @@ return nil
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
 			},
 			expectHTMLCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface {}) error {
-  if true {
-    if _, werr := w.Write(builtin0); werr != nil {
-      return werr
-    }
-  } else {
-    if _, werr := w.Write(builtin1); werr != nil {
-      return werr
-    }
+  if _, werr := w.Write(builtin0); werr != nil {
+    return werr
   }
   return nil
 }`,
@@ -210,6 +217,8 @@ return nil
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var var0 string = data.SomeString
   if _, werr := io.WriteString(w, var0); werr != nil {
@@ -344,20 +353,21 @@ return nil
 				"fmt",
 			},
 			expectHTMLCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface {}) error {
-  var bw bytes.Buffer
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var var1 string = data.SomeString
-  bw.WriteString(var1)
-  template.HTMLEscape(w, bw.Bytes())
-  bw.Reset()
+  if werr := template.HTMLEscaperTo(w, var1); werr != nil {
+    return werr
+  }
   if _, werr := w.Write(builtin0); werr != nil {
     return werr
   }
   var var3 int = data.SomeInt
-  var var2 string = aliastemplate.HTMLEscaper(var3)
+  var var2 string = template.HTMLEscaper(var3)
   if _, werr := io.WriteString(w, var2); werr != nil {
     return werr
   }
@@ -365,7 +375,7 @@ return nil
     return werr
   }
   var var5 bool = data.SomeBool
-  var var4 string = aliastemplate.HTMLEscaper(var5)
+  var var4 string = template.HTMLEscaper(var5)
   if _, werr := io.WriteString(w, var4); werr != nil {
     return werr
   }
@@ -373,7 +383,7 @@ return nil
     return werr
   }
   var var7 int8 = data.SomeInt8
-  var var6 string = aliastemplate.HTMLEscaper(var7)
+  var var6 string = template.HTMLEscaper(var7)
   if _, werr := io.WriteString(w, var6); werr != nil {
     return werr
   }
@@ -381,7 +391,7 @@ return nil
     return werr
   }
   var var9 int16 = data.SomeInt16
-  var var8 string = aliastemplate.HTMLEscaper(var9)
+  var var8 string = template.HTMLEscaper(var9)
   if _, werr := io.WriteString(w, var8); werr != nil {
     return werr
   }
@@ -389,7 +399,7 @@ return nil
     return werr
   }
   var var11 int32 = data.SomeInt32
-  var var10 string = aliastemplate.HTMLEscaper(var11)
+  var var10 string = template.HTMLEscaper(var11)
   if _, werr := io.WriteString(w, var10); werr != nil {
     return werr
   }
@@ -397,7 +407,7 @@ return nil
     return werr
   }
   var var13 int64 = data.SomeInt64
-  var var12 string = aliastemplate.HTMLEscaper(var13)
+  var var12 string = template.HTMLEscaper(var13)
   if _, werr := io.WriteString(w, var12); werr != nil {
     return werr
   }
@@ -405,7 +415,7 @@ return nil
     return werr
   }
   var var15 uint = data.SomeUint
-  var var14 string = aliastemplate.HTMLEscaper(var15)
+  var var14 string = template.HTMLEscaper(var15)
   if _, werr := io.WriteString(w, var14); werr != nil {
     return werr
   }
@@ -413,7 +423,7 @@ return nil
     return werr
   }
   var var17 uint8 = data.SomeUint8
-  var var16 string = aliastemplate.HTMLEscaper(var17)
+  var var16 string = template.HTMLEscaper(var17)
   if _, werr := io.WriteString(w, var16); werr != nil {
     return werr
   }
@@ -421,7 +431,7 @@ return nil
     return werr
   }
   var var19 uint16 = data.SomeUint16
-  var var18 string = aliastemplate.HTMLEscaper(var19)
+  var var18 string = template.HTMLEscaper(var19)
   if _, werr := io.WriteString(w, var18); werr != nil {
     return werr
   }
@@ -429,7 +439,7 @@ return nil
     return werr
   }
   var var21 uint32 = data.SomeUint32
-  var var20 string = aliastemplate.HTMLEscaper(var21)
+  var var20 string = template.HTMLEscaper(var21)
   if _, werr := io.WriteString(w, var20); werr != nil {
     return werr
   }
@@ -437,7 +447,7 @@ return nil
     return werr
   }
   var var23 uint64 = data.SomeUint64
-  var var22 string = aliastemplate.HTMLEscaper(var23)
+  var var22 string = template.HTMLEscaper(var23)
   if _, werr := io.WriteString(w, var22); werr != nil {
     return werr
   }
@@ -445,7 +455,7 @@ return nil
     return werr
   }
   var var25 float32 = data.SomeFloat32
-  var var24 string = aliastemplate.HTMLEscaper(var25)
+  var var24 string = template.HTMLEscaper(var25)
   if _, werr := io.WriteString(w, var24); werr != nil {
     return werr
   }
@@ -453,7 +463,7 @@ return nil
     return werr
   }
   var var27 float64 = data.SomeFloat64
-  var var26 string = aliastemplate.HTMLEscaper(var27)
+  var var26 string = template.HTMLEscaper(var27)
   if _, werr := io.WriteString(w, var26); werr != nil {
     return werr
   }
@@ -461,7 +471,7 @@ return nil
     return werr
   }
   var var29 int32 = data.SomeRune
-  var var28 string = aliastemplate.HTMLEscaper(var29)
+  var var28 string = template.HTMLEscaper(var29)
   if _, werr := io.WriteString(w, var28); werr != nil {
     return werr
   }
@@ -469,7 +479,7 @@ return nil
     return werr
   }
   var var31 uint8 = data.SomeByte
-  var var30 string = aliastemplate.HTMLEscaper(var31)
+  var var30 string = template.HTMLEscaper(var31)
   if _, werr := io.WriteString(w, var30); werr != nil {
     return werr
   }
@@ -477,7 +487,7 @@ return nil
     return werr
   }
   var var33 []uint8 = data.SomeByteSlice
-  var var32 string = aliastemplate.HTMLEscaper(var33)
+  var var32 string = template.HTMLEscaper(var33)
   if _, werr := io.WriteString(w, var32); werr != nil {
     return werr
   }
@@ -485,7 +495,7 @@ return nil
     return werr
   }
   var var35 []int32 = data.SomeRuneSlice
-  var var34 string = aliastemplate.HTMLEscaper(var35)
+  var var34 string = template.HTMLEscaper(var35)
   if _, werr := io.WriteString(w, var34); werr != nil {
     return werr
   }
@@ -493,11 +503,10 @@ return nil
 }`,
 			expectHTMLImports: []string{
 				"io",
-				"bytes",
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
 				"github.com/mh-cbon/template-compiler/compiler",
-				"text/template",
-				"aliastemplate:github.com/mh-cbon/template-compiler/std/html/template",
+				"github.com/mh-cbon/template-compiler/std/html/template",
+				"fmt",
 			},
 		},
 		TestData{
@@ -507,6 +516,8 @@ return nil
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var var0 []uint8 = data.SomeByteSlice
   for _, iterable := range var0 {
@@ -521,11 +532,14 @@ return nil
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
 				"github.com/mh-cbon/template-compiler/compiler",
 				"strconv",
+				"fmt",
 			},
 			expectHTMLCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var var0 []uint8 = data.SomeByteSlice
   for _, iterable := range var0 {
@@ -541,6 +555,7 @@ return nil
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
 				"github.com/mh-cbon/template-compiler/compiler",
 				"github.com/mh-cbon/template-compiler/std/html/template",
+				"fmt",
 			},
 		},
 		TestData{
@@ -550,6 +565,8 @@ return nil
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var var0 []uint8 = data.SomeByteSlice
   for tplI, tplV := range var0 {
@@ -564,11 +581,14 @@ return nil
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
 				"github.com/mh-cbon/template-compiler/compiler",
 				"strconv",
+				"fmt",
 			},
 			expectHTMLCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var var0 []uint8 = data.SomeByteSlice
   for tplI, tplV := range var0 {
@@ -584,6 +604,7 @@ return nil
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
 				"github.com/mh-cbon/template-compiler/compiler",
 				"github.com/mh-cbon/template-compiler/std/html/template",
+				"fmt",
 			},
 		},
 		TestData{
@@ -597,6 +618,8 @@ return nil
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var var0 []*compiler.TemplateData = data.SomeTemplateDataSlice
   for tplI, tplV := range var0 {
@@ -619,11 +642,15 @@ return nil
 				"io",
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
 				"github.com/mh-cbon/template-compiler/compiler",
+				"fmt",
+				"fmt",
 			},
 			expectHTMLCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var var0 []*compiler.TemplateData = data.SomeTemplateDataSlice
   for tplI, tplV := range var0 {
@@ -663,6 +690,8 @@ Hello else branch!
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var var0 []*compiler.TemplateData = data.SomeTemplateDataSlice
   for tplI, tplV := range var0 {
@@ -681,11 +710,15 @@ Hello else branch!
 				"io",
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
 				"github.com/mh-cbon/template-compiler/compiler",
+				"fmt",
+				"fmt",
 			},
 			expectHTMLCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var var0 []*compiler.TemplateData = data.SomeTemplateDataSlice
   for tplI, tplV := range var0 {
@@ -721,6 +754,8 @@ Hello without branch!
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var var0 compiler.TemplateData = data
   {
@@ -740,11 +775,15 @@ Hello without branch!
 				"io",
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
 				"github.com/mh-cbon/template-compiler/compiler",
+				"fmt",
+				"fmt",
 			},
 			expectHTMLCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var var0 compiler.TemplateData = data
   {
@@ -774,6 +813,8 @@ Hello without branch!
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var var0 compiler.TemplateData = data
   {
@@ -795,35 +836,37 @@ Hello without branch!
 				"io",
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
 				"github.com/mh-cbon/template-compiler/compiler",
+				"fmt",
 			},
 			expectHTMLCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
-  var bw bytes.Buffer
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var var0 compiler.TemplateData = data
   {
     if true {
       var var2 string = var0.SomeString
-      bw.WriteString(var2)
-      template.HTMLEscape(w, bw.Bytes())
-      bw.Reset()
+      if werr := template.HTMLEscaperTo(w, var2); werr != nil {
+        return werr
+      }
     } else {
       var var4 string = data.SomeString
-      bw.WriteString(var4)
-      template.HTMLEscape(w, bw.Bytes())
-      bw.Reset()
+      if werr := template.HTMLEscaperTo(w, var4); werr != nil {
+        return werr
+      }
     }
   }
   return nil
 }`,
 			expectHTMLImports: []string{
 				"io",
-				"bytes",
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
 				"github.com/mh-cbon/template-compiler/compiler",
-				"text/template",
+				"github.com/mh-cbon/template-compiler/std/html/template",
+				"fmt",
 			},
 		},
 		TestData{
@@ -833,6 +876,8 @@ Hello without branch!
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var var0 string = data.SomeString
   if var0 != "" {
@@ -846,11 +891,15 @@ Hello without branch!
 				"io",
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
 				"github.com/mh-cbon/template-compiler/compiler",
+				"fmt",
+				"fmt",
 			},
 			expectHTMLCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var var0 string = data.SomeString
   if var0 != "" {
@@ -891,6 +940,8 @@ Hello without branch!
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var var0 string = data.SomeString
   if var0 != "" {
@@ -1003,11 +1054,15 @@ Hello without branch!
 				"io",
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
 				"github.com/mh-cbon/template-compiler/compiler",
+				"fmt",
+				"fmt",
 			},
 			expectHTMLCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var var0 string = data.SomeString
   if var0 != "" {
@@ -1168,6 +1223,8 @@ Hello without branch!
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var var0 string = data.MethodHello()
   if _, werr := io.WriteString(w, var0); werr != nil {
@@ -1179,25 +1236,27 @@ Hello without branch!
 				"io",
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
 				"github.com/mh-cbon/template-compiler/compiler",
+				"fmt",
 			},
 			expectHTMLCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
-  var bw bytes.Buffer
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var var1 string = data.MethodHello()
-  bw.WriteString(var1)
-  template.HTMLEscape(w, bw.Bytes())
-  bw.Reset()
+  if werr := template.HTMLEscaperTo(w, var1); werr != nil {
+    return werr
+  }
   return nil
 }`,
 			expectHTMLImports: []string{
 				"io",
-				"bytes",
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
 				"github.com/mh-cbon/template-compiler/compiler",
-				"text/template",
+				"github.com/mh-cbon/template-compiler/std/html/template",
+				"fmt",
 			},
 		},
 		TestData{
@@ -1207,6 +1266,8 @@ Hello without branch!
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var var0 string = data.MethodArgHello("me")
   if _, werr := io.WriteString(w, var0); werr != nil {
@@ -1218,25 +1279,27 @@ Hello without branch!
 				"io",
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
 				"github.com/mh-cbon/template-compiler/compiler",
+				"fmt",
 			},
 			expectHTMLCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
-  var bw bytes.Buffer
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var var0 string = data.MethodArgHello("me")
-  bw.WriteString(var0)
-  template.HTMLEscape(w, bw.Bytes())
-  bw.Reset()
+  if werr := template.HTMLEscaperTo(w, var0); werr != nil {
+    return werr
+  }
   return nil
 }`,
 			expectHTMLImports: []string{
 				"io",
-				"bytes",
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
 				"github.com/mh-cbon/template-compiler/compiler",
-				"text/template",
+				"github.com/mh-cbon/template-compiler/std/html/template",
+				"fmt",
 			},
 		},
 		TestData{
@@ -1246,6 +1309,8 @@ Hello without branch!
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var var0 string = data.MethodArgHello2("me", "you")
   if _, werr := io.WriteString(w, var0); werr != nil {
@@ -1257,25 +1322,27 @@ Hello without branch!
 				"io",
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
 				"github.com/mh-cbon/template-compiler/compiler",
+				"fmt",
 			},
 			expectHTMLCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
-  var bw bytes.Buffer
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var var0 string = data.MethodArgHello2("me", "you")
-  bw.WriteString(var0)
-  template.HTMLEscape(w, bw.Bytes())
-  bw.Reset()
+  if werr := template.HTMLEscaperTo(w, var0); werr != nil {
+    return werr
+  }
   return nil
 }`,
 			expectHTMLImports: []string{
 				"io",
-				"bytes",
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
 				"github.com/mh-cbon/template-compiler/compiler",
-				"text/template",
+				"github.com/mh-cbon/template-compiler/std/html/template",
+				"fmt",
 			},
 		},
 		TestData{
@@ -1285,6 +1352,8 @@ Hello without branch!
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var0, err := data.MethodArgHelloMultipleReturn("me", "you")
   if err != nil {
@@ -1299,28 +1368,30 @@ Hello without branch!
 				"io",
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
 				"github.com/mh-cbon/template-compiler/compiler",
+				"fmt",
 			},
 			expectHTMLCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
-  var bw bytes.Buffer
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var0, err := data.MethodArgHelloMultipleReturn("me", "you")
   if err != nil {
     return err
   }
-  bw.WriteString(var0)
-  template.HTMLEscape(w, bw.Bytes())
-  bw.Reset()
+  if werr := template.HTMLEscaperTo(w, var0); werr != nil {
+    return werr
+  }
   return nil
 }`,
 			expectHTMLImports: []string{
 				"io",
-				"bytes",
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
 				"github.com/mh-cbon/template-compiler/compiler",
-				"text/template",
+				"github.com/mh-cbon/template-compiler/std/html/template",
+				"fmt",
 			},
 		},
 		TestData{
@@ -1330,6 +1401,8 @@ Hello without branch!
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var tplX compiler.TemplateData = data
   var var0 string = tplX.MethodHello()
@@ -1342,26 +1415,28 @@ Hello without branch!
 				"io",
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
 				"github.com/mh-cbon/template-compiler/compiler",
+				"fmt",
 			},
 			expectHTMLCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
-  var bw bytes.Buffer
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var tplX compiler.TemplateData = data
   var var1 string = tplX.MethodHello()
-  bw.WriteString(var1)
-  template.HTMLEscape(w, bw.Bytes())
-  bw.Reset()
+  if werr := template.HTMLEscaperTo(w, var1); werr != nil {
+    return werr
+  }
   return nil
 }`,
 			expectHTMLImports: []string{
 				"io",
-				"bytes",
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
 				"github.com/mh-cbon/template-compiler/compiler",
-				"text/template",
+				"github.com/mh-cbon/template-compiler/std/html/template",
+				"fmt",
 			},
 		},
 		TestData{
@@ -1371,6 +1446,8 @@ Hello without branch!
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var tplX compiler.TemplateData = data
   var var0 string = tplX.MethodArgHello("me")
@@ -1383,26 +1460,28 @@ Hello without branch!
 				"io",
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
 				"github.com/mh-cbon/template-compiler/compiler",
+				"fmt",
 			},
 			expectHTMLCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
-  var bw bytes.Buffer
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var tplX compiler.TemplateData = data
   var var0 string = tplX.MethodArgHello("me")
-  bw.WriteString(var0)
-  template.HTMLEscape(w, bw.Bytes())
-  bw.Reset()
+  if werr := template.HTMLEscaperTo(w, var0); werr != nil {
+    return werr
+  }
   return nil
 }`,
 			expectHTMLImports: []string{
 				"io",
-				"bytes",
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
 				"github.com/mh-cbon/template-compiler/compiler",
-				"text/template",
+				"github.com/mh-cbon/template-compiler/std/html/template",
+				"fmt",
 			},
 		},
 		TestData{
@@ -1412,6 +1491,8 @@ Hello without branch!
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var tplX compiler.TemplateData = data
   var var0 string = tplX.MethodArgHello2("me", "you")
@@ -1424,26 +1505,28 @@ Hello without branch!
 				"io",
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
 				"github.com/mh-cbon/template-compiler/compiler",
+				"fmt",
 			},
 			expectHTMLCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
-  var bw bytes.Buffer
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var tplX compiler.TemplateData = data
   var var0 string = tplX.MethodArgHello2("me", "you")
-  bw.WriteString(var0)
-  template.HTMLEscape(w, bw.Bytes())
-  bw.Reset()
+  if werr := template.HTMLEscaperTo(w, var0); werr != nil {
+    return werr
+  }
   return nil
 }`,
 			expectHTMLImports: []string{
 				"io",
-				"bytes",
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
 				"github.com/mh-cbon/template-compiler/compiler",
-				"text/template",
+				"github.com/mh-cbon/template-compiler/std/html/template",
+				"fmt",
 			},
 		},
 		TestData{
@@ -1453,6 +1536,8 @@ Hello without branch!
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var tplX compiler.TemplateData = data
   var0, err := tplX.MethodArgHelloMultipleReturn("me", "you")
@@ -1468,29 +1553,31 @@ Hello without branch!
 				"io",
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
 				"github.com/mh-cbon/template-compiler/compiler",
+				"fmt",
 			},
 			expectHTMLCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
-  var bw bytes.Buffer
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var tplX compiler.TemplateData = data
   var0, err := tplX.MethodArgHelloMultipleReturn("me", "you")
   if err != nil {
     return err
   }
-  bw.WriteString(var0)
-  template.HTMLEscape(w, bw.Bytes())
-  bw.Reset()
+  if werr := template.HTMLEscaperTo(w, var0); werr != nil {
+    return werr
+  }
   return nil
 }`,
 			expectHTMLImports: []string{
 				"io",
-				"bytes",
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
 				"github.com/mh-cbon/template-compiler/compiler",
-				"text/template",
+				"github.com/mh-cbon/template-compiler/std/html/template",
+				"fmt",
 			},
 		},
 		TestData{
@@ -1512,18 +1599,16 @@ Hello without branch!
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
 			},
 			expectHTMLCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
-  var bw bytes.Buffer
   var var1 string = t.GetFuncs()["up"].(func(string) string)("rr")
-  bw.WriteString(var1)
-  template.HTMLEscape(w, bw.Bytes())
-  bw.Reset()
+  if werr := template.HTMLEscaperTo(w, var1); werr != nil {
+    return werr
+  }
   return nil
 }`,
 			expectHTMLImports: []string{
 				"io",
-				"bytes",
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
-				"text/template",
+				"github.com/mh-cbon/template-compiler/std/html/template",
 			},
 		},
 		TestData{
@@ -1545,18 +1630,16 @@ Hello without branch!
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
 			},
 			expectHTMLCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
-  var bw bytes.Buffer
   var var1 string = t.GetFuncs()["split"].(func(string, string) string)("rr", "r")
-  bw.WriteString(var1)
-  template.HTMLEscape(w, bw.Bytes())
-  bw.Reset()
+  if werr := template.HTMLEscaperTo(w, var1); werr != nil {
+    return werr
+  }
   return nil
 }`,
 			expectHTMLImports: []string{
 				"io",
-				"bytes",
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
-				"text/template",
+				"github.com/mh-cbon/template-compiler/std/html/template",
 			},
 		},
 		TestData{
@@ -1581,21 +1664,19 @@ Hello without branch!
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
 			},
 			expectHTMLCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
-  var bw bytes.Buffer
   var1, err := t.GetFuncs()["fnerr"].(func(string) (string, error))("r")
   if err != nil {
     return err
   }
-  bw.WriteString(var1)
-  template.HTMLEscape(w, bw.Bytes())
-  bw.Reset()
+  if werr := template.HTMLEscaperTo(w, var1); werr != nil {
+    return werr
+  }
   return nil
 }`,
 			expectHTMLImports: []string{
 				"io",
-				"bytes",
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
-				"text/template",
+				"github.com/mh-cbon/template-compiler/std/html/template",
 			},
 		},
 		TestData{
@@ -1605,6 +1686,8 @@ Hello without branch!
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var var0 interface {} = data.SomeInterface
   if _, werr := fmt.Fprintf(w, "%v", var0); werr != nil {
@@ -1622,6 +1705,8 @@ Hello without branch!
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var var1 interface{} = data.SomeInterface
   var var0 string = template.HTMLEscaper(var1)
@@ -1635,6 +1720,187 @@ Hello without branch!
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
 				"github.com/mh-cbon/template-compiler/compiler",
 				"github.com/mh-cbon/template-compiler/std/html/template",
+				"fmt",
+			},
+		},
+		TestData{
+			tplstr:    `{{.SomeStringer}}`,
+			dataValue: TemplateData{},
+			expectCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
+  var data compiler.TemplateData
+  if d, ok := indata.(compiler.TemplateData); ok {
+    data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
+  }
+  var var0 compiler.TemplateStringer = data.SomeStringer
+  if _, werr := fmt.Fprintf(w, "%v", var0); werr != nil {
+    return werr
+  }
+  return nil
+}`,
+			expectImports: []string{
+				"io",
+				"github.com/mh-cbon/template-compiler/std/text/template/parse",
+				"github.com/mh-cbon/template-compiler/compiler",
+				"fmt",
+			},
+			expectHTMLCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
+  var data compiler.TemplateData
+  if d, ok := indata.(compiler.TemplateData); ok {
+    data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
+  }
+  var var1 compiler.TemplateStringer = data.SomeStringer
+  var var0 string = template.HTMLEscaper(var1)
+  if _, werr := io.WriteString(w, var0); werr != nil {
+    return werr
+  }
+  return nil
+}`,
+			expectHTMLImports: []string{
+				"io",
+				"github.com/mh-cbon/template-compiler/std/text/template/parse",
+				"github.com/mh-cbon/template-compiler/compiler",
+				"github.com/mh-cbon/template-compiler/std/html/template",
+				"fmt",
+			},
+		},
+		TestData{
+			tplstr:    `{{.SomeStringer}}`,
+			dataValue: &TemplateData{},
+			expectCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
+  var data *compiler.TemplateData
+  if d, ok := indata.(*compiler.TemplateData); ok {
+    data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants *compiler.TemplateData", indata)
+  }
+  var var0 compiler.TemplateStringer = data.SomeStringer
+  if _, werr := io.WriteString(w, var0.String()); werr != nil {
+    return werr
+  }
+  return nil
+}`,
+			expectImports: []string{
+				"io",
+				"github.com/mh-cbon/template-compiler/std/text/template/parse",
+				"github.com/mh-cbon/template-compiler/compiler",
+				"fmt",
+			},
+			expectHTMLCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
+  var data *compiler.TemplateData
+  if d, ok := indata.(*compiler.TemplateData); ok {
+    data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants *compiler.TemplateData", indata)
+  }
+  var var1 compiler.TemplateStringer = data.SomeStringer
+  var var0 string = template.HTMLEscaper(var1)
+  if _, werr := io.WriteString(w, var0); werr != nil {
+    return werr
+  }
+  return nil
+}`,
+			expectHTMLImports: []string{
+				"io",
+				"github.com/mh-cbon/template-compiler/std/text/template/parse",
+				"github.com/mh-cbon/template-compiler/compiler",
+				"github.com/mh-cbon/template-compiler/std/html/template",
+				"fmt",
+			},
+		},
+		TestData{
+			tplstr:    `{{range .SomeStringerSlice}}{{.}}{{end}}`,
+			dataValue: TemplateData{},
+			expectCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
+  var data compiler.TemplateData
+  if d, ok := indata.(compiler.TemplateData); ok {
+    data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
+  }
+  var var0 []compiler.TemplateStringer = data.SomeStringerSlice
+  for _, iterable := range var0 {
+    if _, werr := io.WriteString(w, iterable.String()); werr != nil {
+      return werr
+    }
+  }
+  return nil
+}`,
+			expectImports: []string{
+				"io",
+				"github.com/mh-cbon/template-compiler/std/text/template/parse",
+				"github.com/mh-cbon/template-compiler/compiler",
+				"fmt",
+			},
+			expectHTMLCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
+  var data compiler.TemplateData
+  if d, ok := indata.(compiler.TemplateData); ok {
+    data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
+  }
+  var var0 []compiler.TemplateStringer = data.SomeStringerSlice
+  for _, iterable := range var0 {
+    var var1 string = template.HTMLEscaper(iterable)
+    if _, werr := io.WriteString(w, var1); werr != nil {
+      return werr
+    }
+  }
+  return nil
+}`,
+			expectHTMLImports: []string{
+				"io",
+				"github.com/mh-cbon/template-compiler/std/text/template/parse",
+				"github.com/mh-cbon/template-compiler/compiler",
+				"github.com/mh-cbon/template-compiler/std/html/template",
+				"fmt",
+			},
+		},
+		TestData{
+			tplstr:    `{{.SomeValueStringer}}`,
+			dataValue: TemplateData{},
+			expectCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
+  var data compiler.TemplateData
+  if d, ok := indata.(compiler.TemplateData); ok {
+    data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
+  }
+  var var0 compiler.TemplateValueStringer = data.SomeValueStringer
+  if _, werr := io.WriteString(w, var0.String()); werr != nil {
+    return werr
+  }
+  return nil
+}`,
+			expectImports: []string{
+				"io",
+				"github.com/mh-cbon/template-compiler/std/text/template/parse",
+				"github.com/mh-cbon/template-compiler/compiler",
+				"fmt",
+			},
+			expectHTMLCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
+  var data compiler.TemplateData
+  if d, ok := indata.(compiler.TemplateData); ok {
+    data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
+  }
+  var var1 compiler.TemplateValueStringer = data.SomeValueStringer
+  var var0 string = template.HTMLEscaper(var1)
+  if _, werr := io.WriteString(w, var0); werr != nil {
+    return werr
+  }
+  return nil
+}`,
+			expectHTMLImports: []string{
+				"io",
+				"github.com/mh-cbon/template-compiler/std/text/template/parse",
+				"github.com/mh-cbon/template-compiler/compiler",
+				"github.com/mh-cbon/template-compiler/std/html/template",
+				"fmt",
 			},
 		},
 		TestData{
@@ -1654,6 +1920,8 @@ Hello without branch!
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var var0 interface{} = funcmap.BrowsePropertyPath(data, "SomeInterface.SomeInterface")
   if _, werr := fmt.Fprintf(w, "%v", var0); werr != nil {
@@ -1672,6 +1940,8 @@ Hello without branch!
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var var1 interface{} = funcmap.BrowsePropertyPath(data, "SomeInterface.SomeInterface")
   var var0 string = template.HTMLEscaper(var1)
@@ -1686,6 +1956,7 @@ Hello without branch!
 				"github.com/mh-cbon/template-compiler/compiler",
 				"github.com/mh-cbon/template-compiler/std/html/template",
 				"github.com/mh-cbon/template-tree-simplifier/funcmap",
+				"fmt",
 			},
 		},
 		TestData{
@@ -1705,6 +1976,8 @@ Hello without branch!
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var tplX interface{} = data.SomeInterface
   var var0 interface{} = funcmap.BrowsePropertyPath(tplX, "SomeInterface")
@@ -1724,6 +1997,8 @@ Hello without branch!
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var tplX interface{} = data.SomeInterface
   var var1 interface{} = funcmap.BrowsePropertyPath(tplX, "SomeInterface")
@@ -1739,6 +2014,7 @@ Hello without branch!
 				"github.com/mh-cbon/template-compiler/compiler",
 				"github.com/mh-cbon/template-compiler/std/html/template",
 				"github.com/mh-cbon/template-tree-simplifier/funcmap",
+				"fmt",
 			},
 		},
 		TestData{
@@ -1758,6 +2034,8 @@ Hello without branch!
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var tplX interface{} = data.SomeInterface
   var var0 interface{} = funcmap.BrowsePropertyPath(tplX, "MethodHello")
@@ -1777,6 +2055,8 @@ Hello without branch!
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var tplX interface{} = data.SomeInterface
   var var1 interface{} = funcmap.BrowsePropertyPath(tplX, "MethodHello")
@@ -1792,6 +2072,7 @@ Hello without branch!
 				"github.com/mh-cbon/template-compiler/compiler",
 				"github.com/mh-cbon/template-tree-simplifier/funcmap",
 				"github.com/mh-cbon/template-compiler/std/html/template",
+				"fmt",
 			},
 		},
 		TestData{
@@ -1812,6 +2093,8 @@ Hello without branch!
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var tplX interface{} = data.SomeInterface
   var var0 interface{} = funcmap.BrowsePropertyPath(tplX, "MethodArgHello2", "me", "you")
@@ -1831,6 +2114,8 @@ Hello without branch!
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var tplX interface{} = data.SomeInterface
   var var0 interface{} = funcmap.BrowsePropertyPath(tplX, "MethodArgHello2", "me", "you")
@@ -1846,6 +2131,7 @@ Hello without branch!
 				"github.com/mh-cbon/template-compiler/compiler",
 				"github.com/mh-cbon/template-tree-simplifier/funcmap",
 				"github.com/mh-cbon/template-compiler/std/html/template",
+				"fmt",
 			},
 		},
 		TestData{
@@ -1886,36 +2172,75 @@ Hello without branch!
 			},
 		},
 		TestData{
-			tplstr:    `{{html "rr"}}`,
-			dataValue: TemplateData{SomeInterface: TemplateData{}},
+			tplstr:         `{{html "rr"}}`,
+			dataValue:      TemplateData{SomeInterface: TemplateData{}},
+			expectBuiltins: map[string]string{"rr": "builtin0"},
 			funcs: map[string]interface{}{
 				"browsePropertyPath": func(x interface{}, p string, args ...interface{}) interface{} { return nil },
 			},
 			expectCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface {}) error {
-  var bw bytes.Buffer
-  bw.WriteString("rr")
-  template.HTMLEscape(w, bw.Bytes())
-  bw.Reset()
+  if _, werr := w.Write(builtin0); werr != nil {
+    return werr
+  }
   return nil
 }`,
 			expectImports: []string{
 				"io",
-				"bytes",
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
-				"text/template",
 			},
 			expectHTMLCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
-  var bw bytes.Buffer
-  bw.WriteString("rr")
-  template.HTMLEscape(w, bw.Bytes())
-  bw.Reset()
+  if _, werr := w.Write(builtin0); werr != nil {
+    return werr
+  }
   return nil
 }`,
 			expectHTMLImports: []string{
 				"io",
-				"bytes",
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
-				"text/template",
+			},
+		},
+		TestData{
+			tplstr:    `{{html .SomeString}}`,
+			dataValue: TemplateData{},
+			expectCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
+  var data compiler.TemplateData
+  if d, ok := indata.(compiler.TemplateData); ok {
+    data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
+  }
+  var var1 string = data.SomeString
+  if werr := template.HTMLBuiltinEscaperTo(w, var1); werr != nil {
+    return werr
+  }
+  return nil
+}`,
+			expectImports: []string{
+				"io",
+				"github.com/mh-cbon/template-compiler/std/text/template/parse",
+				"github.com/mh-cbon/template-compiler/compiler",
+				"github.com/mh-cbon/template-compiler/std/html/template",
+				"fmt",
+			},
+			expectHTMLCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
+  var data compiler.TemplateData
+  if d, ok := indata.(compiler.TemplateData); ok {
+    data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
+  }
+  var var1 string = data.SomeString
+  if werr := template.HTMLBuiltinEscaperTo(w, var1); werr != nil {
+    return werr
+  }
+  return nil
+}`,
+			expectHTMLImports: []string{
+				"io",
+				"github.com/mh-cbon/template-compiler/std/text/template/parse",
+				"github.com/mh-cbon/template-compiler/compiler",
+				"github.com/mh-cbon/template-compiler/std/html/template",
+				"fmt",
 			},
 		},
 		TestData{
@@ -1928,6 +2253,8 @@ Hello without branch!
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var var0 []*compiler.TemplateData = data.SomeTemplateDataSlice
   var var1 int = len(var0)
@@ -1941,11 +2268,14 @@ Hello without branch!
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
 				"github.com/mh-cbon/template-compiler/compiler",
 				"strconv",
+				"fmt",
 			},
 			expectHTMLCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var var0 []*compiler.TemplateData = data.SomeTemplateDataSlice
   var var2 int = len(var0)
@@ -1960,6 +2290,7 @@ Hello without branch!
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
 				"github.com/mh-cbon/template-compiler/compiler",
 				"github.com/mh-cbon/template-compiler/std/html/template",
+				"fmt",
 			},
 		},
 		TestData{
@@ -1969,7 +2300,89 @@ Hello without branch!
 				"browsePropertyPath": func(x interface{}, p string, args ...interface{}) interface{} { return nil },
 			},
 			expectCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
-  var var0 bool = true == true
+  return nil
+}`,
+			expectImports: []string{
+				"io",
+				"github.com/mh-cbon/template-compiler/std/text/template/parse",
+			},
+			expectHTMLCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
+  return nil
+}`,
+			expectHTMLImports: []string{
+				"io",
+				"github.com/mh-cbon/template-compiler/std/text/template/parse",
+			},
+		},
+		TestData{
+			tplstr:    `{{if and .SomeBool .SomeString}}{{end}}`,
+			dataValue: TemplateData{},
+			expectCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
+  var data compiler.TemplateData
+  if d, ok := indata.(compiler.TemplateData); ok {
+    data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
+  }
+  var var1 bool = data.SomeBool
+  var var0 interface{} = var1
+  if var1 {
+    var var2 string = data.SomeString
+    var0 = var2
+  }
+  if template.Truth(var0) {
+  }
+  return nil
+}`,
+			expectImports: []string{
+				"io",
+				"github.com/mh-cbon/template-compiler/std/text/template/parse",
+				"github.com/mh-cbon/template-compiler/compiler",
+				"github.com/mh-cbon/template-compiler/std/text/template",
+				"fmt",
+				"fmt",
+			},
+			expectHTMLCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
+  var data compiler.TemplateData
+  if d, ok := indata.(compiler.TemplateData); ok {
+    data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
+  }
+  var var1 bool = data.SomeBool
+  var var0 interface{} = var1
+  if var1 {
+    var var2 string = data.SomeString
+    var0 = var2
+  }
+  if template.Truth(var0) {
+  }
+  return nil
+}`,
+			expectHTMLImports: []string{
+				"io",
+				"github.com/mh-cbon/template-compiler/std/text/template/parse",
+				"github.com/mh-cbon/template-compiler/compiler",
+				"github.com/mh-cbon/template-compiler/std/text/template",
+			},
+		},
+		TestData{
+			tplstr:    `{{if or .SomeBool (not .SomeBool)}}{{end}}`,
+			dataValue: TemplateData{},
+			expectCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
+  var data compiler.TemplateData
+  if d, ok := indata.(compiler.TemplateData); ok {
+    data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
+  }
+  var var1 bool = data.SomeBool
+  var var0 bool = var1
+  if !var1 {
+    var var3 bool = data.SomeBool
+    var var2 bool = !var3
+    var0 = var2
+  }
   if var0 {
   }
   return nil
@@ -1977,9 +2390,24 @@ Hello without branch!
 			expectImports: []string{
 				"io",
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
+				"github.com/mh-cbon/template-compiler/compiler",
+				"fmt",
+				"fmt",
 			},
 			expectHTMLCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
-  var var0 bool = true == true
+  var data compiler.TemplateData
+  if d, ok := indata.(compiler.TemplateData); ok {
+    data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
+  }
+  var var1 bool = data.SomeBool
+  var var0 bool = var1
+  if !var1 {
+    var var3 bool = data.SomeBool
+    var var2 bool = !var3
+    var0 = var2
+  }
   if var0 {
   }
   return nil
@@ -1987,6 +2415,215 @@ Hello without branch!
 			expectHTMLImports: []string{
 				"io",
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
+				"github.com/mh-cbon/template-compiler/compiler",
+			},
+		},
+		TestData{
+			tplstr:    `{{printf "%d-%s" .SomeInt .SomeString}}`,
+			dataValue: TemplateData{},
+			expectBuiltins: map[string]string{
+				"-": "builtin0",
+			},
+			expectCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
+  var bb []byte
+  var data compiler.TemplateData
+  if d, ok := indata.(compiler.TemplateData); ok {
+    data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
+  }
+  var var1 int = data.SomeInt
+  var var2 string = data.SomeString
+  bb = strconv.AppendInt(bb[:0], int64(var1), 10)
+  if _, werr := w.Write(bb); werr != nil {
+    return werr
+  }
+  if _, werr := w.Write(builtin0); werr != nil {
+    return werr
+  }
+  if _, werr := io.WriteString(w, var2); werr != nil {
+    return werr
+  }
+  return nil
+}`,
+			expectImports: []string{
+				"io",
+				"github.com/mh-cbon/template-compiler/std/text/template/parse",
+				"github.com/mh-cbon/template-compiler/compiler",
+				"strconv",
+				"fmt",
+			},
+			expectHTMLCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
+  var data compiler.TemplateData
+  if d, ok := indata.(compiler.TemplateData); ok {
+    data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
+  }
+  var var2 int = data.SomeInt
+  var var3 string = data.SomeString
+  var var1 string = strconv.Itoa(var2) + "-" + var3
+  if werr := template.HTMLEscaperTo(w, var1); werr != nil {
+    return werr
+  }
+  return nil
+}`,
+			expectHTMLImports: []string{
+				"io",
+				"github.com/mh-cbon/template-compiler/std/text/template/parse",
+				"github.com/mh-cbon/template-compiler/compiler",
+				"strconv",
+				"github.com/mh-cbon/template-compiler/std/html/template",
+				"fmt",
+			},
+		},
+		TestData{
+			tplstr:    `{{urlquery .SomeString}}`,
+			dataValue: TemplateData{},
+			expectCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
+  var data compiler.TemplateData
+  if d, ok := indata.(compiler.TemplateData); ok {
+    data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
+  }
+  var var1 string = data.SomeString
+  if _, werr := io.WriteString(w, url.QueryEscape(var1)); werr != nil {
+    return werr
+  }
+  return nil
+}`,
+			expectImports: []string{
+				"io",
+				"github.com/mh-cbon/template-compiler/std/text/template/parse",
+				"github.com/mh-cbon/template-compiler/compiler",
+				"net/url",
+				"fmt",
+			},
+			expectHTMLCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
+  var data compiler.TemplateData
+  if d, ok := indata.(compiler.TemplateData); ok {
+    data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
+  }
+  var var2 string = data.SomeString
+  var var1 string = url.QueryEscape(var2)
+  if werr := template.HTMLEscaperTo(w, var1); werr != nil {
+    return werr
+  }
+  return nil
+}`,
+			expectHTMLImports: []string{
+				"io",
+				"github.com/mh-cbon/template-compiler/std/text/template/parse",
+				"github.com/mh-cbon/template-compiler/compiler",
+				"net/url",
+				"github.com/mh-cbon/template-compiler/std/html/template",
+				"fmt",
+			},
+		},
+		TestData{
+			tplstr:    `{{urlquery .SomeString}}`,
+			dataValue: TemplateData{},
+			funcs: map[string]interface{}{
+				"urlquery": func(args ...interface{}) string { return "" },
+			},
+			funcsMapPublic: []map[string]string{
+				map[string]string{
+					"FuncName": "urlquery",
+					"Sel":      "funcs.Query",
+					"Pkg":      "github.com/mh-cbon/template-compiler/demo/funcs",
+				},
+			},
+			expectCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
+  var data compiler.TemplateData
+  if d, ok := indata.(compiler.TemplateData); ok {
+    data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
+  }
+  var var1 string = data.SomeString
+  var var0 string = funcs.Query(var1)
+  if _, werr := io.WriteString(w, var0); werr != nil {
+    return werr
+  }
+  return nil
+}`,
+			expectImports: []string{
+				"io",
+				"github.com/mh-cbon/template-compiler/std/text/template/parse",
+				"github.com/mh-cbon/template-compiler/compiler",
+				"github.com/mh-cbon/template-compiler/demo/funcs",
+				"fmt",
+			},
+			expectHTMLCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
+  var data compiler.TemplateData
+  if d, ok := indata.(compiler.TemplateData); ok {
+    data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
+  }
+  var var2 string = data.SomeString
+  var var1 string = funcs.Query(var2)
+  if werr := template.HTMLEscaperTo(w, var1); werr != nil {
+    return werr
+  }
+  return nil
+}`,
+			expectHTMLImports: []string{
+				"io",
+				"github.com/mh-cbon/template-compiler/std/text/template/parse",
+				"github.com/mh-cbon/template-compiler/compiler",
+				"github.com/mh-cbon/template-compiler/demo/funcs",
+				"github.com/mh-cbon/template-compiler/std/html/template",
+				"fmt",
+			},
+		},
+		TestData{
+			tplstr:    `{{js .SomeString}}`,
+			dataValue: TemplateData{SomeString: "<a href='x'>&=</a>"},
+			expectCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
+  var data compiler.TemplateData
+  if d, ok := indata.(compiler.TemplateData); ok {
+    data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
+  }
+  var var1 string = data.SomeString
+  if _, werr := io.WriteString(w, template.JSEscapeString(var1)); werr != nil {
+    return werr
+  }
+  return nil
+}`,
+			expectImports: []string{
+				"io",
+				"github.com/mh-cbon/template-compiler/std/text/template/parse",
+				"github.com/mh-cbon/template-compiler/compiler",
+				"github.com/mh-cbon/template-compiler/std/text/template",
+				"fmt",
+			},
+			expectHTMLCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
+  var data compiler.TemplateData
+  if d, ok := indata.(compiler.TemplateData); ok {
+    data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
+  }
+  var var2 string = data.SomeString
+  var var1 string = template.JSEscapeString(var2)
+  if werr := aliastemplate.HTMLEscaperTo(w, var1); werr != nil {
+    return werr
+  }
+  return nil
+}`,
+			expectHTMLImports: []string{
+				"io",
+				"github.com/mh-cbon/template-compiler/std/text/template/parse",
+				"github.com/mh-cbon/template-compiler/compiler",
+				"github.com/mh-cbon/template-compiler/std/text/template",
+				"github.com/mh-cbon/template-compiler/std/html/template",
+				"fmt",
 			},
 		},
 		TestData{
@@ -2006,13 +2643,14 @@ Hello else branch!
 				"\nHello range branch!\n": "builtin0",
 				"\n": "builtin1",
 				"\nHello else branch!\n": "builtin2",
-				" if branch ":            "builtin3",
-				" else branch ":          "builtin4",
+				"\n if branch \n":        "builtin3",
 			},
 			expectCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var var0 []*compiler.TemplateData = data.SomeTemplateDataSlice
   for tplI, tplV := range var0 {
@@ -2037,19 +2675,7 @@ Hello else branch!
     return werr
   }
   var tplYShadow0 bool = true
-  if _, werr := w.Write(builtin1); werr != nil {
-    return werr
-  }
-  if tplYShadow0 {
-    if _, werr := w.Write(builtin3); werr != nil {
-      return werr
-    }
-  } else {
-    if _, werr := w.Write(builtin4); werr != nil {
-      return werr
-    }
-  }
-  if _, werr := w.Write(builtin1); werr != nil {
+  if _, werr := w.Write(builtin3); werr != nil {
     return werr
   }
   {
@@ -2073,11 +2699,14 @@ Hello else branch!
 				"strconv",
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
 				"github.com/mh-cbon/template-compiler/compiler",
+				"fmt",
 			},
 			expectHTMLCompiledFn: `func fn0(t parse.Templater, w io.Writer, indata interface{}) error {
   var data compiler.TemplateData
   if d, ok := indata.(compiler.TemplateData); ok {
     data = d
+  } else if indata != nil {
+    return fmt.Errorf("template: : unexpected data type %T, wants compiler.TemplateData", indata)
   }
   var var0 []*compiler.TemplateData = data.SomeTemplateDataSlice
   for tplI, tplV := range var0 {
@@ -2102,19 +2731,7 @@ Hello else branch!
     return werr
   }
   var tplYShadow0 bool = true
-  if _, werr := w.Write(builtin1); werr != nil {
-    return werr
-  }
-  if tplYShadow0 {
-    if _, werr := w.Write(builtin3); werr != nil {
-      return werr
-    }
-  } else {
-    if _, werr := w.Write(builtin4); werr != nil {
-      return werr
-    }
-  }
-  if _, werr := w.Write(builtin1); werr != nil {
+  if _, werr := w.Write(builtin3); werr != nil {
     return werr
   }
   {
@@ -2140,6 +2757,7 @@ Hello else branch!
 				"github.com/mh-cbon/template-compiler/std/text/template/parse",
 				"github.com/mh-cbon/template-compiler/compiler",
 				"github.com/mh-cbon/template-compiler/std/html/template",
+				"fmt",
 			},
 		},
 	}
@@ -2513,3 +3131,69 @@ func strExists(s string, in []string) bool {
 	}
 	return false
 }
+
+type ReferencedOutsideTestData struct {
+	tplstr string
+	// the index of the node calling the variable
+	call int
+	// the variable declared before the call
+	name     string
+	expected bool
+}
+
+func TestIsReferencedOutside(t *testing.T) {
+
+	allTestData := []ReferencedOutsideTestData{
+		ReferencedOutsideTestData{
+			tplstr:   `{{$var0 := .A}}{{if and .B $var0}}{{end}}`,
+			call:     1,
+			name:     "$var0",
+			expected: false,
+		},
+		ReferencedOutsideTestData{
+			tplstr:   `{{$var0 := .A}}{{if and .B $var0}}{{end}}{{"$var0"}}`,
+			call:     1,
+			name:     "$var0",
+			expected: false,
+		},
+		ReferencedOutsideTestData{
+			tplstr:   `{{$var1 := .A}}{{$var10 := .C}}{{if and .B $var1}}{{end}}{{$var10}}`,
+			call:     2,
+			name:     "$var1",
+			expected: false,
+		},
+		ReferencedOutsideTestData{
+			tplstr:   `{{$var0 := .A}}{{if and .B $var0}}{{end}}{{$var0.Name}}`,
+			call:     1,
+			name:     "$var0",
+			expected: true,
+		},
+		ReferencedOutsideTestData{
+			tplstr:   `{{$var0 := .A}}{{if and .B $var0}}{{end}}{{range .C}}{{template "x" $var0}}{{end}}`,
+			call:     1,
+			name:     "$var0",
+			expected: true,
+		},
+		ReferencedOutsideTestData{
+			tplstr:   `{{$var0 := .A}}{{$var1 := not $var0}}{{if and .B $var1}}{{end}}{{$var0}}`,
+			call:     2,
+			name:     "$var1",
+			expected: true,
+		},
+	}
+
+	for i, testData := range allTestData {
+		tree := parse.New("")
+		tree.Mode = parse.SkipFuncCheck
+		if _, err := tree.Parse(testData.tplstr, "", "", map[string]*parse.Tree{}); err != nil {
+			t.Errorf("Test(%v): Expected to parse the template, but got an error=%v", i, err)
+			continue
+		}
+		nodes := tree.Root.Nodes
+		decls := collectDeclarations(nodes[:testData.call], testData.name)
+		got := isReferencedOutside(nodes, decls, nodes[testData.call])
+		if got != testData.expected {
+			t.Errorf("Test(%v): Unexpected result for %v in %v, expected=%v got=%v", i, testData.name, testData.tplstr, testData.expected, got)
+		}
+	}
+}
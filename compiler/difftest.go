@@ -0,0 +1,237 @@
+package compiler
+
+import (
+	"fmt"
+	"go/build"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/mh-cbon/template-compiler/compiled"
+)
+
+// testPath returns the path of the generated tests for the program written at outPath,
+// gen.go becomes gen_test.go.
+func testPath(outPath string) string {
+	return strings.TrimSuffix(outPath, ".go") + "_test.go"
+}
+
+// defaultFuncsMap are the funcmaps already declared into the interpreted templates.
+var defaultFuncsMap = []string{
+	"text/template:builtins",
+	"github.com/mh-cbon/template-tree-simplifier/funcmap:tplFunc",
+	"github.com/mh-cbon/template-compiler/std/html/template:publicFuncMap",
+}
+
+// testProgram holds the imports of the generated tests.
+type testProgram struct {
+	pkgPath string
+	imports []string
+	aliases map[string]string
+	names   map[string]bool
+}
+
+// uniqueName returns a test func name not yet declared into the tests.
+func (p *testProgram) uniqueName(name string) string {
+	ret := name
+	for n := 1; p.names[ret]; n++ {
+		ret = fmt.Sprintf("%v%v", name, n)
+	}
+	p.names[ret] = true
+	return ret
+}
+
+// addImport imports pkgpath into the tests, it returns its alias.
+func (p *testProgram) addImport(pkgpath string, alias string) string {
+	if x, ok := p.aliases[pkgpath]; ok {
+		return x
+	}
+	if alias == "" {
+		alias = filepath.Base(pkgpath)
+	}
+	okAlias := alias
+	for i := 1; p.isCollidingAlias(okAlias); i++ {
+		okAlias = fmt.Sprintf("%v%v", alias, i)
+	}
+	p.aliases[pkgpath] = okAlias
+	p.imports = append(p.imports, pkgpath)
+	return okAlias
+}
+
+// isCollidingAlias tells if given alias is already used by an import.
+func (p *testProgram) isCollidingAlias(alias string) bool {
+	for _, a := range p.aliases {
+		if a == alias {
+			return true
+		}
+	}
+	return false
+}
+
+// funcsMapExpr returns the expression of the funcmap target pkgpath:variable
+// as the type FuncMap of the package alias tplPkg.
+// It returns an error when the variable is not accessible from the tests.
+func (p *testProgram) funcsMapExpr(target string, tplPkg string) (string, error) {
+	pkgpath, variable, err := resolveFuncsMap(target, p.pkgPath, "the tests")
+	if err != nil {
+		return "", err
+	}
+	if pkgpath == "" {
+		return fmt.Sprintf("%v.FuncMap(%v)", tplPkg, variable), nil
+	}
+	alias := p.addImport(pkgpath, "")
+	return fmt.Sprintf("%v.FuncMap(%v.%v)", tplPkg, alias, variable), nil
+}
+
+// resolveFuncsMap splits the funcmap target pkgpath:variable,
+// the returned pkgpath is empty when the variable is declared into the package pkgPath.
+// It returns an error when the variable is not accessible from the package pkgPath,
+// described by from.
+func resolveFuncsMap(target, pkgPath, from string) (string, string, error) {
+	i := strings.LastIndex(target, ":")
+	if i < 0 {
+		return "", "", fmt.Errorf("the funcmap %v is not a pkgpath:variable target", target)
+	}
+	pkgpath, variable := target[:i], target[i+1:]
+	if pkgpath == pkgPath {
+		return "", variable, nil
+	}
+	if variable == "" || unicode.IsUpper([]rune(variable)[0]) == false {
+		return "", "", fmt.Errorf("the funcmap %v is not accessible from %v", target, from)
+	}
+	return pkgpath, variable, nil
+}
+
+// generateDiffTest generates the test of the templates defined into the file f,
+// it compares the compiled and the interpreted outputs over their samples.
+func (p *testProgram) generateDiffTest(varName string, i int, f TemplateFileToCompile, jit string, funcsErr error) string {
+	testName := p.uniqueName("TestCompiled" + exportedIdent(f.name))
+	test := ""
+	test += fmt.Sprintf("// %v compares the compiled and the interpreted template %q.\n", testName, f.name)
+	test += fmt.Sprintf("func %v(t *testing.T) {\n", testName)
+	if funcsErr != nil {
+		test += fmt.Sprintf("\tt.Skip(%q)\n", funcsErr.Error())
+		test += "}\n\n"
+		return test
+	}
+	test += fmt.Sprintf("\tjit, err := %v\n", jit)
+	test += "\tif err != nil {\n\t\tt.Fatal(err)\n\t}\n"
+	for _, name := range f.names() {
+		test += fmt.Sprintf(
+			"\ttestCompiledTemplate(t, jit, %q, %v.Templates[%v].Samples(%q))\n",
+			name, varName, i, name,
+		)
+	}
+	test += "}\n\n"
+	return test
+}
+
+// generateTestProgram generates the tests comparing the output of each compiled template
+// with the output of the forked interpreter over the samples of the configuration,
+// and the fuzz tests of the templates with a struct data type.
+// When the benchmarks are enabled, it generates the benchmarks
+// of each compiled template and its interpreted template.
+func (c *CompiledTemplatesProgram) generateTestProgram(config *compiled.Configuration) string {
+	p := &testProgram{aliases: map[string]string{}, names: map[string]bool{}}
+	if pkg, err := build.Default.ImportDir(filepath.Dir(config.OutPath), build.FindOnly); err == nil {
+		p.pkgPath = pkg.ImportPath
+	}
+	if config.Tests {
+		p.addImport("bytes", "")
+		p.addImport("io", "")
+	}
+	if config.Benchmarks {
+		p.addImport("io/ioutil", "")
+	}
+	p.addImport("testing", "")
+
+	tests := ""
+	for i, t := range c.templates {
+		var tplPkg string
+		if t.HTML {
+			tplPkg = p.addImport("github.com/mh-cbon/template-compiler/std/html/template", "html")
+		} else {
+			tplPkg = p.addImport("github.com/mh-cbon/template-compiler/std/text/template", "text")
+		}
+		funcs := ""
+		var funcsErr error
+		for _, target := range append(append([]string{}, config.FuncsMap...), t.FuncsMap...) {
+			if containsStr(defaultFuncsMap, target) {
+				continue
+			}
+			expr, err := p.funcsMapExpr(target, tplPkg)
+			if err != nil {
+				funcsErr = err
+				break
+			}
+			funcs += fmt.Sprintf(".Funcs(%v)", expr)
+		}
+		for _, f := range t.files {
+			jit := fmt.Sprintf("%v.New(%q)%v.Parse(%q)", tplPkg, f.name, funcs, f.content)
+			if f.path != "" {
+				jit = fmt.Sprintf("%v.New(%q)%v.ParseFiles(%q)", tplPkg, f.name, funcs, f.path)
+			}
+			if config.Tests {
+				tests += p.generateDiffTest(c.varName, i, f, jit, funcsErr)
+			}
+			for _, name := range f.names() {
+				samples := fmt.Sprintf("%v.Templates[%v].Samples(%q)", c.varName, i, name)
+				dataConf, _ := t.getDataConfiguration(name)
+				data, _ := t.getData(name)
+				fields := fuzzFields(reflect.TypeOf(data))
+				if config.Tests && funcsErr == nil && dataConf.DataTypeName != "" && len(fields) > 0 {
+					fuzzName := p.uniqueName("FuzzCompiled" + exportedIdent(name))
+					tests += p.generateFuzzTest(fuzzName, name, jit, samples, dataConf, fields)
+				}
+				if config.Benchmarks {
+					tests += p.generateBenchmarks(c.varName, name, jit, samples, funcsErr)
+				}
+			}
+		}
+	}
+
+	program := fmt.Sprintf("package %v\n\n", config.OutPkg)
+	program += fmt.Sprintf("//golint:ignore\n\n")
+	program += fmt.Sprintf("import (\n")
+	// the std packages first, then the others.
+	for _, std := range []bool{true, false} {
+		if std == false {
+			program += "\n"
+		}
+		for _, pkgpath := range p.imports {
+			if strings.Contains(strings.Split(pkgpath, "/")[0], ".") == std {
+				continue
+			}
+			if alias := p.aliases[pkgpath]; alias != filepath.Base(pkgpath) {
+				program += fmt.Sprintf("\t%v %q\n", alias, pkgpath)
+			} else {
+				program += fmt.Sprintf("\t%q\n", pkgpath)
+			}
+		}
+	}
+	program += fmt.Sprintf(")\n\n")
+	if config.Tests {
+		program += fmt.Sprintf(`// testCompiledTemplate renders the template name with the compiled template and the interpreted template,
+// for each sample, it fails if they do not both succeed with the same output, or both fail.
+func testCompiledTemplate(t *testing.T, jit interface {
+	ExecuteTemplate(io.Writer, string, interface{}) error
+}, name string, samples []interface{}) {
+	t.Helper()
+	for i, data := range samples {
+		var want, got bytes.Buffer
+		wantErr := jit.ExecuteTemplate(&want, name, data)
+		gotErr := %v.MustGet(name).Execute(&got, data)
+		if (wantErr == nil) != (gotErr == nil) {
+			t.Errorf("%%v: sample %%v: the interpreted template error=%%v, the compiled template error=%%v", name, i, wantErr, gotErr)
+		} else if wantErr == nil && want.String() != got.String() {
+			t.Errorf("%%v: sample %%v: unexpected output\ninterpreted=%%q\ncompiled=%%q", name, i, want.String(), got.String())
+		}
+	}
+}
+
+`, c.varName)
+	}
+	program += tests
+	return formatGoCode(program)
+}
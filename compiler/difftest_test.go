@@ -0,0 +1,225 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+	"text/template/parse"
+
+	"github.com/mh-cbon/template-compiler/compiled"
+	"github.com/mh-cbon/template-compiler/demo/data"
+)
+
+type DiffTestData struct {
+	config *compiled.Configuration
+	// the templates by their file path, or name for a content, and their defined template names
+	files map[string][]string
+	// the code expected to be found in the tests program
+	expected []string
+	// the code expected not to be found in the tests program
+	unexpected []string
+}
+
+func TestGenerateTestProgram(t *testing.T) {
+
+	allTestData := []DiffTestData{
+		DiffTestData{
+			config: &compiled.Configuration{
+				OutPath: "gen.go",
+				OutPkg:  "main",
+				Tests:   true,
+				Templates: []compiled.TemplateConfiguration{
+					compiled.TemplateConfiguration{HTML: true},
+				},
+			},
+			files: map[string][]string{
+				"templates/a.tpl": []string{"a.tpl", "x"},
+			},
+			expected: []string{
+				`html "github.com/mh-cbon/template-compiler/std/html/template"`,
+				`func TestCompiledATpl(t *testing.T) {`,
+				`jit, err := html.New("a.tpl").ParseFiles("templates/a.tpl")`,
+				`testCompiledTemplate(t, jit, "a.tpl", xx.Templates[0].Samples("a.tpl"))`,
+				`testCompiledTemplate(t, jit, "x", xx.Templates[0].Samples("x"))`,
+				`gotErr := xx.MustGet(name).Execute(&got, data)`,
+			},
+			unexpected: []string{
+				`std/text/template`,
+				`func FuzzCompiled`,
+			},
+		},
+		DiffTestData{
+			config: &compiled.Configuration{
+				OutPath: "gen.go",
+				OutPkg:  "main",
+				Tests:   true,
+				Templates: []compiled.TemplateConfiguration{
+					compiled.TemplateConfiguration{
+						HTML: true,
+						TemplatesData: map[string]interface{}{
+							"*": &data.MyTemplateData{},
+						},
+						TemplatesDataConfiguration: map[string]compiled.DataConfiguration{
+							"*": compiled.DataConfiguration{
+								IsPtr:        true,
+								DataTypeName: "MyTemplateData",
+								PkgPath:      "github.com/mh-cbon/template-compiler/demo/data",
+							},
+						},
+					},
+				},
+			},
+			files: map[string][]string{
+				"templates/a.tpl": []string{"a.tpl"},
+			},
+			expected: []string{
+				`"github.com/mh-cbon/template-compiler/demo/data"`,
+				`func FuzzCompiledATpl(f *testing.F) {
+	jit, err := html.New("a.tpl").ParseFiles("templates/a.tpl")
+	if err != nil {
+		f.Fatal(err)
+	}
+	samples := xx.Templates[0].Samples("a.tpl")
+	for i, sample := range samples {
+		p, ok := sample.(*data.MyTemplateData)
+		if ok == false || p == nil {
+			f.Fatalf("a.tpl: sample %v: unexpected data %#v, wants a non nil *data.MyTemplateData", i, sample)
+		}
+		d := *p
+		f.Add(d.Some)
+	}
+	f.Fuzz(func(t *testing.T, v0 string) {
+		var fuzzed []interface{}
+		for i, sample := range samples {
+			p, ok := sample.(*data.MyTemplateData)
+			if ok == false || p == nil {
+				t.Fatalf("a.tpl: sample %v: unexpected data %#v, wants a non nil *data.MyTemplateData", i, sample)
+			}
+			d := *p
+			d.Some = v0
+			fuzzed = append(fuzzed, &d)
+		}
+		testCompiledTemplate(t, jit, "a.tpl", fuzzed)
+	})
+}`,
+			},
+		},
+		DiffTestData{
+			config: &compiled.Configuration{
+				OutPath:  "gen.go",
+				OutPkg:   "gen",
+				Tests:    true,
+				FuncsMap: []string{"text/template:builtins"},
+				Templates: []compiled.TemplateConfiguration{
+					compiled.TemplateConfiguration{
+						FuncsMap: []string{"github.com/mh-cbon/template-compiler/demo/data:Funcs"},
+					},
+				},
+			},
+			files: map[string][]string{
+				"notafile": []string{"notafile"},
+			},
+			expected: []string{
+				`text "github.com/mh-cbon/template-compiler/std/text/template"`,
+				`"github.com/mh-cbon/template-compiler/demo/data"`,
+				`jit, err := text.New("notafile").Funcs(text.FuncMap(data.Funcs)).Parse("hello {{.}}")`,
+			},
+			unexpected: []string{
+				`builtins`,
+			},
+		},
+		DiffTestData{
+			config: &compiled.Configuration{
+				OutPath: "gen.go",
+				OutPkg:  "gen",
+				Tests:   true,
+				Templates: []compiled.TemplateConfiguration{
+					compiled.TemplateConfiguration{
+						FuncsMap: []string{"github.com/mh-cbon/template-compiler/demo/data:funcs"},
+					},
+				},
+			},
+			files: map[string][]string{
+				"notafile": []string{"notafile"},
+			},
+			expected: []string{
+				`t.Skip("the funcmap github.com/mh-cbon/template-compiler/demo/data:funcs is not accessible from the tests")`,
+			},
+			unexpected: []string{
+				`jit, err :=`,
+			},
+		},
+		DiffTestData{
+			config: &compiled.Configuration{
+				OutPath:    "gen.go",
+				OutPkg:     "gen",
+				Benchmarks: true,
+				Templates: []compiled.TemplateConfiguration{
+					compiled.TemplateConfiguration{},
+				},
+			},
+			files: map[string][]string{
+				"notafile": []string{"notafile"},
+			},
+			expected: []string{
+				`"io/ioutil"`,
+				`func BenchmarkCompiledNotafile(b *testing.B) {
+	tpl := xx.MustGet("notafile")
+	samples := xx.Templates[0].Samples("notafile")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		tpl.Execute(ioutil.Discard, samples[n%len(samples)])
+	}
+}`,
+				`func BenchmarkInterpretedNotafile(b *testing.B) {
+	jit, err := text.New("notafile").Parse("hello {{.}}")
+	if err != nil {
+		b.Fatal(err)
+	}
+	samples := xx.Templates[0].Samples("notafile")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		jit.ExecuteTemplate(ioutil.Discard, "notafile", samples[n%len(samples)])
+	}
+}`,
+			},
+			unexpected: []string{
+				`func TestCompiled`,
+				`testCompiledTemplate`,
+				`"bytes"`,
+			},
+		},
+	}
+
+	for i, testData := range allTestData {
+		tpl := makeTemplateToCompile(testData.config.Templates[0])
+		for path, names := range testData.files {
+			f := TemplateFileToCompile{tplsTree: map[string]*parse.Tree{}}
+			if strings.Contains(path, "/") {
+				f.name = names[0]
+				f.path = path
+			} else {
+				f.name = path
+				f.content = "hello {{.}}"
+			}
+			for _, name := range names {
+				f.tplsTree[name] = nil
+			}
+			tpl.files = append(tpl.files, f)
+		}
+		c := NewCompiledTemplatesProgram("xx")
+		c.templates = []*TemplateToCompile{tpl}
+		program := c.generateTestProgram(testData.config)
+		for _, e := range testData.expected {
+			if strings.Contains(program, e) == false {
+				t.Errorf("Test(%v): Expected to find %v in the tests program\n%v", i, e, program)
+			}
+		}
+		for _, e := range testData.unexpected {
+			if strings.Contains(program, e) {
+				t.Errorf("Test(%v): Unexpected %v in the tests program\n%v", i, e, program)
+			}
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package compiler
+
+import (
+	"fmt"
+	"go/ast"
+	"text/template/parse"
+)
+
+// execErrorAt is the location of the action returning an error.
+type execErrorAt struct {
+	// name is the name of the template being executed.
+	name string
+	// location is the template name and the line:col of the action.
+	location string
+	// context is the action.
+	context string
+	// prefix describes the failed call, such error calling Method.
+	prefix string
+}
+
+// execErrorReturns registers the errors returned by stmts to be reported
+// at the location of node, see addExecErrors.
+func (c *converter) execErrorReturns(stmts []ast.Stmt, node parse.Node, prefix string) []ast.Stmt {
+	if node == nil {
+		return stmts
+	}
+	location, context := c.tree.ErrorContext(node)
+	if _, ok := node.(*parse.TextNode); ok && len(context) > 20 {
+		// long texts are not duplicated into the program.
+		context = fmt.Sprintf("%.20s...", context)
+	}
+	at := execErrorAt{name: c.tree.Name, location: location, context: context, prefix: prefix}
+	for _, stmt := range stmts {
+		ast.Inspect(stmt, func(n ast.Node) bool {
+			if ret, ok := n.(*ast.ReturnStmt); ok && len(ret.Results) == 1 {
+				c.compiledProgram.execErrors[ret] = at
+			}
+			return true
+		})
+	}
+	return stmts
+}
+
+// calleeName returns the name of the method or the func called by node.
+func calleeName(node parse.Node) string {
+	switch n := node.(type) {
+	case *parse.FieldNode:
+		return n.Ident[len(n.Ident)-1]
+	case *parse.VariableNode:
+		return n.Ident[len(n.Ident)-1]
+	case *parse.ChainNode:
+		return n.Field[len(n.Field)-1]
+	case *parse.IdentifierNode:
+		return n.Ident
+	}
+	return node.String()
+}
+
+// addExecErrors wraps the errors returned by the actions of the converted template functions
+// into a template.ExecError, formatted such as the interpreter reports them:
+// template: c.tpl:12:5: executing "name" at <.Method>: error calling Method: ...
+// The errors of the template calls are returned unchanged.
+func (c *CompiledTemplatesProgram) addExecErrors() {
+	if len(c.execErrors) == 0 {
+		return
+	}
+	alias := c.addImport("github.com/mh-cbon/template-compiler/std/text/template")
+	fmtalias := ""
+	for _, at := range c.execErrors {
+		if at.prefix != "" {
+			fmtalias = c.addImport("fmt")
+		}
+	}
+	for ret, at := range c.execErrors {
+		err := astNodeToString(ret.Results[0])
+		if at.prefix != "" {
+			err = fmt.Sprintf("%v.Errorf(%q, %v)", fmtalias, at.prefix+": %w", err)
+		}
+		ret.Results[0] = stringToExpr(fmt.Sprintf("%v.NewExecError(%q, %q, %q, %v)",
+			alias, at.name, at.location, at.context, err))
+	}
+	c.execErrors = map[*ast.ReturnStmt]execErrorAt{}
+}
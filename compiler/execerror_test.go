@@ -0,0 +1,62 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mh-cbon/template-compiler/compiled"
+	"github.com/mh-cbon/template-compiler/demo/data"
+)
+
+type ExecErrorTestData struct {
+	templates []compiled.TemplateConfiguration
+	// the code expected to be found in the program
+	expected []string
+}
+
+func TestAddExecErrors(t *testing.T) {
+
+	allTestData := []ExecErrorTestData{
+		ExecErrorTestData{
+			templates: []compiled.TemplateConfiguration{
+				compiled.TemplateConfiguration{
+					TemplateName:    "page",
+					TemplateContent: "hello\n{{.Some}}",
+					TemplatesData:   map[string]interface{}{"*": data.MyTemplateData{}},
+				},
+			},
+			expected: []string{
+				`return template.NewExecError("page", "page:1:0", "hello\n", werr)`,
+				`return template.NewExecError("page", "page:2:2", "{{.Some}}", werr)`,
+			},
+		},
+		ExecErrorTestData{
+			templates: []compiled.TemplateConfiguration{
+				compiled.TemplateConfiguration{
+					TemplateName:    "page",
+					TemplateContent: `{{define "row"}}{{$x := .MethodArgHelloMultipleReturn "a" "b"}}{{end}}{{template "row" .}}`,
+					TemplatesData:   map[string]interface{}{"*": TemplateData{}},
+				},
+			},
+			expected: []string{
+				`return template.NewExecError("row", "page:1:24", ".MethodArgHelloMultipleReturn", fmt.Errorf("error calling MethodArgHelloMultipleReturn: %w", err))`,
+				`		if werr := fnpage_rowData(t, w, data); werr != nil {
+			return werr
+		}`,
+			},
+		},
+	}
+
+	for i, testData := range allTestData {
+		program, err := compileLinkTestData(testData.templates)
+		if err != nil {
+			t.Errorf("Test(%v): unexpected error %v", i, err)
+			continue
+		}
+		for _, expected := range testData.expected {
+			if strings.Contains(program, expected) == false {
+				t.Errorf("Test(%v): expected to find\n%v\nin\n%v", i, expected, program)
+			}
+		}
+	}
+}
@@ -0,0 +1,592 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+	"text/template/parse"
+
+	htmltemplate "github.com/mh-cbon/template-compiler/std/html/template"
+	texttemplate "github.com/mh-cbon/template-compiler/std/text/template"
+)
+
+// foldFunc evaluates a call of a template func with constant arguments,
+// it returns false when the call can not be evaluated at compile time.
+type foldFunc func(args ...interface{}) (interface{}, bool)
+
+// foldFuncs are the template funcs evaluated at compile time,
+// their calls are evaluated only when the funcmap does not override them.
+var foldFuncs = map[string]foldFunc{
+	"html":     foldEscaper(texttemplate.HTMLEscaper),
+	"js":       foldEscaper(texttemplate.JSEscaper),
+	"urlquery": foldEscaper(texttemplate.URLQueryEscaper),
+	"print":    func(args ...interface{}) (interface{}, bool) { return fmt.Sprint(args...), true },
+	"println":  func(args ...interface{}) (interface{}, bool) { return fmt.Sprintln(args...), true },
+	"printf":   foldPrintf,
+	"len":      foldLen,
+	"not":      foldNot,
+	"and":      foldAnd,
+	"or":       foldOr,
+	"eq":       foldEq,
+	"ne":       foldNe,
+	"lt":       foldCompare(func(c int) bool { return c < 0 }),
+	"le":       foldCompare(func(c int) bool { return c <= 0 }),
+	"gt":       foldCompare(func(c int) bool { return c > 0 }),
+	"ge":       foldCompare(func(c int) bool { return c >= 0 }),
+
+	"_html_template_attrescaper":     foldEscaper(htmltemplate.AttrEscaper),
+	"_html_template_commentescaper":  foldEscaper(htmltemplate.CommentEscaper),
+	"_html_template_cssescaper":      foldEscaper(htmltemplate.CSSEscaper),
+	"_html_template_cssvaluefilter":  foldEscaper(htmltemplate.CSSValueFilter),
+	"_html_template_htmlnamefilter":  foldEscaper(htmltemplate.HTMLNameFilter),
+	"_html_template_htmlescaper":     foldEscaper(htmltemplate.HTMLTextEscaper),
+	"_html_template_jsregexpescaper": foldEscaper(htmltemplate.JSRegexpEscaper),
+	"_html_template_jsstrescaper":    foldEscaper(htmltemplate.JSStrEscaper),
+	"_html_template_jsvalescaper":    foldEscaper(htmltemplate.JSValEscaper),
+	"_html_template_nospaceescaper":  foldEscaper(htmltemplate.HTMLNospaceEscaper),
+	"_html_template_rcdataescaper":   foldEscaper(htmltemplate.RcdataEscaper),
+	"_html_template_urlescaper":      foldEscaper(htmltemplate.URLEscaper),
+	"_html_template_urlfilter":       foldEscaper(htmltemplate.URLFilter),
+	"_html_template_urlnormalizer":   foldEscaper(htmltemplate.URLNormalizer),
+}
+
+// foldVar is a template variable declaration met while folding.
+// deps are the constant variables its value was computed from,
+// refs counts the references left in the tree,
+// folded counts the references evaluated at compile time.
+type foldVar struct {
+	node     *parse.ActionNode
+	value    interface{}
+	constant bool
+	deps     []*foldVar
+	refs     int
+	folded   int
+}
+
+// folder holds the state of the constant folding of a tree.
+type folder struct {
+	assigned     map[string]bool
+	scopes       []map[string]*foldVar
+	consts       []*foldVar
+	deps         []*foldVar
+	funcs        map[string]interface{}
+	publicIdents []map[string]string
+}
+
+// foldConstants evaluates the constant expressions of the tree at compile time.
+// The prints of constant values become text,
+// the if nodes with a constant condition are replaced by the branch taken,
+// the declarations of constant variables are removed once all their references were folded,
+// and the consecutive text nodes are merged.
+// funcs and publicIdents are the funcmap of the tree.
+func foldConstants(tree *parse.Tree, funcs map[string]interface{}, publicIdents []map[string]string) {
+	if tree == nil || tree.Root == nil {
+		return
+	}
+	f := &folder{assigned: map[string]bool{}, funcs: funcs, publicIdents: publicIdents}
+	collectAssignedVars(tree.Root, f.assigned)
+	f.foldList(tree.Root)
+
+	// the users of a declaration are always declared after it.
+	removed := map[parse.Node]bool{}
+	for i := len(f.consts) - 1; i >= 0; i-- {
+		v := f.consts[i]
+		remove := v.refs == 0 && v.folded > 0
+		if remove {
+			removed[v.node] = true
+		}
+		for _, d := range v.deps {
+			if remove {
+				d.folded++
+			} else {
+				d.refs++
+			}
+		}
+	}
+	cleanTextNodes(tree.Root, removed)
+}
+
+// foldList folds the nodes of a list within a new variable scope.
+func (f *folder) foldList(list *parse.ListNode) {
+	if list == nil {
+		return
+	}
+	f.scopes = append(f.scopes, map[string]*foldVar{})
+	list.Nodes = f.foldNodes(list.Nodes)
+	f.scopes = f.scopes[:len(f.scopes)-1]
+}
+
+func (f *folder) foldNodes(nodes []parse.Node) []parse.Node {
+	ret := make([]parse.Node, 0, len(nodes))
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case *parse.ActionNode:
+			node = f.foldAction(n)
+		case *parse.IfNode:
+			if branch, ok := f.foldIf(n); ok {
+				ret = append(ret, branch...)
+				continue
+			}
+		case *parse.RangeNode:
+			f.foldBranch(&n.BranchNode)
+		case *parse.WithNode:
+			f.foldBranch(&n.BranchNode)
+		case *parse.TemplateNode:
+			f.markRefs(n.Pipe)
+		}
+		ret = append(ret, node)
+	}
+	return ret
+}
+
+// foldAction evaluates a declaration or a print,
+// the print of a constant value is returned as a text node.
+func (f *folder) foldAction(n *parse.ActionNode) parse.Node {
+	pipe := n.Pipe
+	if pipe.IsAssign == false && len(pipe.Decl) == 1 && len(pipe.Decl[0].Ident) == 1 {
+		name := pipe.Decl[0].Ident[0]
+		v := &foldVar{node: n}
+		if f.assigned[name] == false {
+			v.value, v.constant = f.evalPipe(pipe)
+			v.deps = f.deps
+		}
+		if v.constant {
+			f.consts = append(f.consts, v)
+		} else {
+			f.markRefs(pipe)
+		}
+		f.declare(name, v)
+		return n
+	}
+	if len(pipe.Decl) == 0 {
+		if value, ok := f.evalPipe(pipe); ok {
+			f.credit()
+			return &parse.TextNode{NodeType: parse.NodeText, Pos: n.Pos, Text: []byte(fmt.Sprint(value))}
+		}
+	}
+	f.markRefs(pipe)
+	if pipe.IsAssign == false {
+		for _, d := range pipe.Decl {
+			f.declare(d.Ident[0], &foldVar{node: n})
+		}
+	}
+	return n
+}
+
+// foldIf returns the nodes of the branch taken by an if node with a constant condition.
+// The branch is kept within the if node when it declares template variables,
+// they would leak into the parent scope of the function.
+func (f *folder) foldIf(n *parse.IfNode) ([]parse.Node, bool) {
+	if len(n.Pipe.Decl) == 0 {
+		if value, ok := f.evalPipe(n.Pipe); ok {
+			branch := n.ElseList
+			if isTrue(value) {
+				branch = n.List
+			}
+			if branch == nil {
+				f.credit()
+				return nil, true
+			}
+			if declaresTemplateVars(branch) == false {
+				f.credit()
+				f.foldList(branch)
+				return branch.Nodes, true
+			}
+		}
+	}
+	f.foldBranch(&n.BranchNode)
+	return nil, false
+}
+
+func (f *folder) foldBranch(n *parse.BranchNode) {
+	f.markRefs(n.Pipe)
+	f.scopes = append(f.scopes, map[string]*foldVar{})
+	for _, d := range n.Pipe.Decl {
+		f.declare(d.Ident[0], &foldVar{})
+	}
+	f.foldList(n.List)
+	f.foldList(n.ElseList)
+	f.scopes = f.scopes[:len(f.scopes)-1]
+}
+
+func (f *folder) declare(name string, v *foldVar) {
+	f.scopes[len(f.scopes)-1][name] = v
+}
+
+func (f *folder) lookup(name string) *foldVar {
+	for i := len(f.scopes) - 1; i >= 0; i-- {
+		if v, ok := f.scopes[i][name]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
+// credit records the variables of the last evaluation as folded.
+func (f *folder) credit() {
+	for _, d := range f.deps {
+		d.folded++
+	}
+	f.deps = nil
+}
+
+// markRefs records the variables referenced by the commands of a pipe.
+func (f *folder) markRefs(pipe *parse.PipeNode) {
+	if pipe == nil {
+		return
+	}
+	for _, cmd := range pipe.Cmds {
+		for _, arg := range cmd.Args {
+			f.markNodeRefs(arg)
+		}
+	}
+}
+
+func (f *folder) markNodeRefs(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.VariableNode:
+		if v := f.lookup(n.Ident[0]); v != nil {
+			v.refs++
+		}
+	case *parse.PipeNode:
+		f.markRefs(n)
+	case *parse.ChainNode:
+		f.markNodeRefs(n.Node)
+	}
+}
+
+// evalPipe evaluates a pipe at compile time,
+// the constant variables it reads are collected into f.deps.
+func (f *folder) evalPipe(pipe *parse.PipeNode) (interface{}, bool) {
+	f.deps = nil
+	return f.evalCommands(pipe)
+}
+
+func (f *folder) evalCommands(pipe *parse.PipeNode) (interface{}, bool) {
+	if pipe == nil || len(pipe.Cmds) == 0 {
+		return nil, false
+	}
+	var value interface{}
+	for i, cmd := range pipe.Cmds {
+		var final []interface{}
+		if i > 0 {
+			final = []interface{}{value}
+		}
+		v, ok := f.evalCommand(cmd, final)
+		if ok == false {
+			return nil, false
+		}
+		value = v
+	}
+	return value, true
+}
+
+func (f *folder) evalCommand(cmd *parse.CommandNode, final []interface{}) (interface{}, bool) {
+	if ident, ok := cmd.Args[0].(*parse.IdentifierNode); ok {
+		fn, ok := foldFuncs[ident.Ident]
+		if ok == false || isBuiltinFunc(ident.Ident, f.funcs, f.publicIdents) == false {
+			return nil, false
+		}
+		var args []interface{}
+		for _, arg := range cmd.Args[1:] {
+			v, ok := f.evalArg(arg)
+			if ok == false {
+				return nil, false
+			}
+			args = append(args, v)
+		}
+		return fn(append(args, final...)...)
+	}
+	if len(cmd.Args) != 1 || len(final) > 0 {
+		return nil, false
+	}
+	return f.evalArg(cmd.Args[0])
+}
+
+func (f *folder) evalArg(node parse.Node) (interface{}, bool) {
+	switch n := node.(type) {
+	case *parse.StringNode:
+		return n.Text, true
+	case *parse.BoolNode:
+		return n.True, true
+	case *parse.NumberNode:
+		return constantNumber(n)
+	case *parse.VariableNode:
+		if len(n.Ident) != 1 {
+			return nil, false
+		}
+		v := f.lookup(n.Ident[0])
+		if v == nil || v.constant == false {
+			return nil, false
+		}
+		f.deps = append(f.deps, v)
+		return v.value, true
+	case *parse.PipeNode:
+		if len(n.Decl) > 0 {
+			return nil, false
+		}
+		return f.evalCommands(n)
+	}
+	return nil, false
+}
+
+// constantNumber returns the value of a number given to a func as an interface{},
+// copied from template/exec.go idealConstant.
+func constantNumber(n *parse.NumberNode) (interface{}, bool) {
+	switch {
+	case n.IsComplex:
+		return nil, false
+	case n.IsFloat && isHexConstant(n.Text) == false && strings.HasPrefix(n.Text, "'") == false &&
+		strings.ContainsAny(n.Text, ".eEpP"):
+		return n.Float64, true
+	case n.IsInt:
+		if int64(int(n.Int64)) != n.Int64 {
+			return nil, false
+		}
+		return int(n.Int64), true
+	}
+	return nil, false
+}
+
+// declaresTemplateVars tells if a list directly declares variables of the template,
+// the variables introduced by the simplifier have unique names.
+func declaresTemplateVars(list *parse.ListNode) bool {
+	for _, node := range list.Nodes {
+		if n, ok := node.(*parse.ActionNode); ok && n.Pipe.IsAssign == false {
+			for _, d := range n.Pipe.Decl {
+				if isSimplifiedVar(d.Ident[0]) == false {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// collectAssignedVars collects the names of the variables assigned with =,
+// their values are not constant.
+func collectAssignedVars(node parse.Node, names map[string]bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			collectAssignedVars(c, names)
+		}
+	case *parse.ActionNode:
+		collectAssignedVars(n.Pipe, names)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		if n.IsAssign {
+			for _, d := range n.Decl {
+				names[d.Ident[0]] = true
+			}
+		}
+		for _, cmd := range n.Cmds {
+			for _, arg := range cmd.Args {
+				collectAssignedVars(arg, names)
+			}
+		}
+	case *parse.IfNode:
+		collectAssignedVars(&n.BranchNode, names)
+	case *parse.RangeNode:
+		collectAssignedVars(&n.BranchNode, names)
+	case *parse.WithNode:
+		collectAssignedVars(&n.BranchNode, names)
+	case *parse.BranchNode:
+		collectAssignedVars(n.Pipe, names)
+		collectAssignedVars(n.List, names)
+		collectAssignedVars(n.ElseList, names)
+	}
+}
+
+// cleanTextNodes removes the nodes of a list,
+// then merges the consecutive text nodes and removes the empty ones.
+func cleanTextNodes(list *parse.ListNode, removed map[parse.Node]bool) {
+	if list == nil {
+		return
+	}
+	ret := list.Nodes[:0]
+	for _, node := range list.Nodes {
+		if removed[node] {
+			continue
+		}
+		switch n := node.(type) {
+		case *parse.TextNode:
+			if len(n.Text) == 0 {
+				continue
+			}
+			if prev, ok := lastTextNode(ret); ok {
+				prev.Text = append(append([]byte{}, prev.Text...), n.Text...)
+				continue
+			}
+		case *parse.IfNode:
+			cleanTextNodes(n.List, removed)
+			cleanTextNodes(n.ElseList, removed)
+		case *parse.RangeNode:
+			cleanTextNodes(n.List, removed)
+			cleanTextNodes(n.ElseList, removed)
+		case *parse.WithNode:
+			cleanTextNodes(n.List, removed)
+			cleanTextNodes(n.ElseList, removed)
+		}
+		ret = append(ret, node)
+	}
+	list.Nodes = ret
+}
+
+func lastTextNode(nodes []parse.Node) (*parse.TextNode, bool) {
+	if len(nodes) == 0 {
+		return nil, false
+	}
+	n, ok := nodes[len(nodes)-1].(*parse.TextNode)
+	return n, ok
+}
+
+// isTrue reports whether a constant value is true,
+// copied from template/exec.go isTrue.
+func isTrue(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case int:
+		return v != 0
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	}
+	return value != nil
+}
+
+func foldEscaper(fn func(args ...interface{}) string) foldFunc {
+	return func(args ...interface{}) (interface{}, bool) {
+		return fn(args...), true
+	}
+}
+
+func foldPrintf(args ...interface{}) (interface{}, bool) {
+	if len(args) == 0 {
+		return nil, false
+	}
+	format, ok := args[0].(string)
+	if ok == false {
+		return nil, false
+	}
+	return fmt.Sprintf(format, args[1:]...), true
+}
+
+func foldLen(args ...interface{}) (interface{}, bool) {
+	if len(args) != 1 {
+		return nil, false
+	}
+	s, ok := args[0].(string)
+	return len(s), ok
+}
+
+func foldNot(args ...interface{}) (interface{}, bool) {
+	if len(args) != 1 {
+		return nil, false
+	}
+	return !isTrue(args[0]), true
+}
+
+func foldAnd(args ...interface{}) (interface{}, bool) {
+	if len(args) == 0 {
+		return nil, false
+	}
+	for _, a := range args[:len(args)-1] {
+		if isTrue(a) == false {
+			return a, true
+		}
+	}
+	return args[len(args)-1], true
+}
+
+func foldOr(args ...interface{}) (interface{}, bool) {
+	if len(args) == 0 {
+		return nil, false
+	}
+	for _, a := range args[:len(args)-1] {
+		if isTrue(a) {
+			return a, true
+		}
+	}
+	return args[len(args)-1], true
+}
+
+// foldEq evaluates eq for operands of the same basic type,
+// the other comparisons are left to the runtime.
+func foldEq(args ...interface{}) (interface{}, bool) {
+	if len(args) < 2 {
+		return nil, false
+	}
+	for _, a := range args[1:] {
+		if _, ok := compareConstants(args[0], a); ok == false {
+			return nil, false
+		}
+	}
+	for _, a := range args[1:] {
+		if args[0] == a {
+			return true, true
+		}
+	}
+	return false, true
+}
+
+func foldNe(args ...interface{}) (interface{}, bool) {
+	if len(args) != 2 {
+		return nil, false
+	}
+	if _, ok := compareConstants(args[0], args[1]); ok == false {
+		return nil, false
+	}
+	return args[0] != args[1], true
+}
+
+func foldCompare(test func(c int) bool) foldFunc {
+	return func(args ...interface{}) (interface{}, bool) {
+		if len(args) != 2 {
+			return nil, false
+		}
+		if _, ok := args[0].(bool); ok {
+			return nil, false
+		}
+		c, ok := compareConstants(args[0], args[1])
+		if ok == false {
+			return nil, false
+		}
+		return test(c), true
+	}
+}
+
+// compareConstants compares two constants of the same type.
+func compareConstants(a, b interface{}) (int, bool) {
+	switch x := a.(type) {
+	case int:
+		y, ok := b.(int)
+		return compareOrdered(x < y, x > y), ok
+	case float64:
+		y, ok := b.(float64)
+		return compareOrdered(x < y, x > y), ok
+	case string:
+		y, ok := b.(string)
+		return compareOrdered(x < y, x > y), ok
+	case bool:
+		_, ok := b.(bool)
+		return 0, ok
+	}
+	return 0, false
+}
+
+func compareOrdered(less, greater bool) int {
+	if less {
+		return -1
+	}
+	if greater {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,134 @@
+package compiler
+
+import (
+	"testing"
+	"text/template"
+	"text/template/parse"
+)
+
+type FoldTestData struct {
+	tplstr   string
+	expected string
+	// the funcs overriding the builtins
+	funcs template.FuncMap
+	// the list of func map with a public identifier
+	funcsMapPublic []map[string]string
+}
+
+func TestFoldConstants(t *testing.T) {
+
+	allTestData := []FoldTestData{
+		FoldTestData{
+			tplstr:   `{{"-"}}a{{1}}b{{true}}{{1.5}}`,
+			expected: `-a1btrue1.5`,
+		},
+		FoldTestData{
+			tplstr:   `{{$var0 := html "<b>"}}{{$var0}}`,
+			expected: `&lt;b&gt;`,
+		},
+		FoldTestData{
+			tplstr:   `{{$var0 := _html_template_htmlescaper "<a title='x'>"}}{{$var0}}`,
+			expected: `&lt;a title=&#39;x&#39;&gt;`,
+		},
+		FoldTestData{
+			tplstr:   `{{"a" | printf "%s-%s" "b"}} {{printf "%d-%s" 1 "a"}} {{print 1 2}}`,
+			expected: `b-a 1-a 1 2`,
+		},
+		FoldTestData{
+			tplstr:   `{{and 1 0}} {{or "" "b"}} {{not true}} {{len "abc"}} {{lt 1 2}} {{ge "a" "b"}} {{eq 1 2 1}}`,
+			expected: `0 b false 3 true false true`,
+		},
+		FoldTestData{
+			tplstr:   `{{if true}}a{{else}}b{{end}}c`,
+			expected: `ac`,
+		},
+		FoldTestData{
+			tplstr:   `{{if false}}a{{end}}c{{if false}}a{{else if 1}}d{{end}}`,
+			expected: `cd`,
+		},
+		FoldTestData{
+			tplstr:   `{{$var0 := eq 1 1}}{{if $var0}}yes{{end}}`,
+			expected: `yes`,
+		},
+		FoldTestData{
+			tplstr:   `{{$y := "x"}}{{$y}}{{with $y}}{{.}}{{end}}`,
+			expected: `{{$y := "x"}}x{{with $y}}{{.}}{{end}}`,
+		},
+		FoldTestData{
+			tplstr:   `{{$y := "x"}}{{$var0 := html $y}}{{$var0}}{{range $var0}}{{end}}`,
+			expected: `{{$y := "x"}}{{$var0 := html $y}}x{{range $var0}}{{end}}`,
+		},
+		FoldTestData{
+			tplstr:   `{{_html_template_htmlescaper "<a+b>\x00"}}`,
+			expected: "&lt;a&#43;b&gt;\uFFFD",
+		},
+		FoldTestData{
+			tplstr:   `{{js "<a href='x'>&=</a>"}}`,
+			expected: `\x3Ca href=\'x\'\x3E&=\x3C/a\x3E`,
+		},
+		FoldTestData{
+			tplstr:   `{{$y := 1}}{{$y = 2}}{{$y}}`,
+			expected: `{{$y := 1}}{{$y = 2}}{{$y}}`,
+		},
+		FoldTestData{
+			tplstr:   `{{$int := 4}}`,
+			expected: `{{$int := 4}}`,
+		},
+		FoldTestData{
+			tplstr:   `{{if true}}{{$x := 1}}{{$x}}{{end}}`,
+			expected: `{{if true}}1{{end}}`,
+		},
+		FoldTestData{
+			tplstr:   `{{eq 1 1.0}}{{.Name}}{{up "a"}}{{1i}}`,
+			expected: `{{eq 1 1.0}}{{.Name}}{{up "a"}}{{1i}}`,
+		},
+		FoldTestData{
+			tplstr:   `{{$var0 := .Items}}{{$var1 := len $var0}}{{$var1}}`,
+			expected: `{{$var0 := .Items}}{{$var1 := len $var0}}{{$var1}}`,
+		},
+		FoldTestData{
+			tplstr:   `{{range .Items}}a{{"b"}}{{else}}{{if true}}c{{end}}d{{end}}`,
+			expected: `{{range .Items}}ab{{else}}cd{{end}}`,
+		},
+		FoldTestData{
+			tplstr:   `{{$var0 := html "<b>"}}{{$var0}}`,
+			expected: `{{$var0 := html "<b>"}}{{$var0}}`,
+			funcs: map[string]interface{}{
+				"html": func(s string) string { return s },
+			},
+		},
+		FoldTestData{
+			tplstr:   `{{$var0 := len "ab"}}{{$var0}}{{$var1 := print "a"}}{{$var1}}`,
+			expected: `{{$var0 := len "ab"}}{{$var0}}{{$var1 := print "a"}}{{$var1}}`,
+			funcs: map[string]interface{}{
+				"len":   func(item interface{}) (int, error) { return 0, nil },
+				"print": func(a ...interface{}) string { return "" },
+			},
+			funcsMapPublic: []map[string]string{
+				map[string]string{"FuncName": "len", "Sel": "funcs.Len", "Pkg": "github.com/mh-cbon/template-compiler/demo/funcs"},
+				map[string]string{"FuncName": "print", "Sel": "funcs.Print", "Pkg": "github.com/mh-cbon/template-compiler/demo/funcs"},
+			},
+		},
+	}
+
+	for i, testData := range allTestData {
+		tree := parse.New("")
+		tree.Mode = parse.SkipFuncCheck
+		if _, err := tree.Parse(testData.tplstr, "", "", map[string]*parse.Tree{}); err != nil {
+			t.Errorf("Test(%v): Expected to parse the template, but got an error=%v", i, err)
+			continue
+		}
+		funcs := map[string]interface{}{}
+		for k, v := range htmlFuncsExport {
+			funcs[k] = v
+		}
+		for k, v := range testData.funcs {
+			funcs[k] = v
+		}
+		publicIdents := append(testData.funcsMapPublic, htmlPublicIdents...)
+		foldConstants(tree, funcs, publicIdents)
+		if got := tree.Root.String(); got != testData.expected {
+			t.Errorf("Test(%v): Unexpected folded template\nexpected=%v\ngot     =%v", i, testData.expected, got)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package compiler
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/mh-cbon/template-compiler/compiled"
+)
+
+// maxFuzzDepth limits the depth of the nested structs browsed for fuzz fields.
+const maxFuzzDepth = 3
+
+// fuzzField is a field of a template data type generated by the go fuzzer.
+type fuzzField struct {
+	// path is the selector of the field, such Some or Inner.Some
+	path string
+	// typ is the name of the field type, such string or int
+	typ string
+}
+
+// fuzzFields returns the exported string, number and bool fields of the struct typ,
+// including the fields of its nested structs.
+func fuzzFields(typ reflect.Type) []fuzzField {
+	if typ == nil {
+		return nil
+	}
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return appendFuzzFields(nil, typ, "", 0)
+}
+
+func appendFuzzFields(fields []fuzzField, typ reflect.Type, prefix string, depth int) []fuzzField {
+	if typ.Kind() != reflect.Struct || depth > maxFuzzDepth {
+		return fields
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" { // unexported
+			continue
+		}
+		path := prefix + field.Name
+		if field.Type.Kind() == reflect.Struct {
+			fields = appendFuzzFields(fields, field.Type, path+".", depth+1)
+			continue
+		}
+		// the fuzzer generates the predeclared types only.
+		if field.Type.PkgPath() != "" {
+			continue
+		}
+		switch field.Type.Kind() {
+		case reflect.String, reflect.Bool,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			fields = append(fields, fuzzField{path: path, typ: field.Type.Name()})
+		}
+	}
+	return fields
+}
+
+// generateFuzzTest generates a fuzz test of the template name,
+// the fuzzed values are set on the fields of each sample,
+// then the compiled and the interpreted template outputs are compared.
+func (p *testProgram) generateFuzzTest(
+	fuzzName, name, jit, samples string,
+	dataConf compiled.DataConfiguration,
+	fields []fuzzField,
+) string {
+	dataQualifier := dataConf.DataTypeName
+	if dataConf.PkgPath != "" && dataConf.PkgPath != p.pkgPath {
+		dataQualifier = fmt.Sprintf("%v.%v", p.addImport(dataConf.PkgPath, ""), dataConf.DataTypeName)
+	}
+	fuzzed := "d"
+	if dataConf.IsPtr {
+		fuzzed = "&d"
+	}
+
+	params := []string{"t *testing.T"}
+	seeds := []string{}
+	sets := ""
+	for i, f := range fields {
+		params = append(params, fmt.Sprintf("v%v %v", i, f.typ))
+		seeds = append(seeds, "d."+f.path)
+		sets += fmt.Sprintf("\t\t\td.%v = v%v\n", f.path, i)
+	}
+
+	test := ""
+	test += fmt.Sprintf("// %v compares the compiled and the interpreted template %q over fuzzed data.\n", fuzzName, name)
+	test += fmt.Sprintf("func %v(f *testing.F) {\n", fuzzName)
+	test += fmt.Sprintf("\tjit, err := %v\n", jit)
+	test += "\tif err != nil {\n\t\tf.Fatal(err)\n\t}\n"
+	test += fmt.Sprintf("\tsamples := %v\n", samples)
+	test += "\tfor i, sample := range samples {\n"
+	test += fuzzSample("\t\t", "f", name, dataQualifier, dataConf.IsPtr)
+	test += fmt.Sprintf("\t\tf.Add(%v)\n", strings.Join(seeds, ", "))
+	test += "\t}\n"
+	test += fmt.Sprintf("\tf.Fuzz(func(%v) {\n", strings.Join(params, ", "))
+	test += "\t\tvar fuzzed []interface{}\n"
+	test += "\t\tfor i, sample := range samples {\n"
+	test += fuzzSample("\t\t\t", "t", name, dataQualifier, dataConf.IsPtr)
+	test += sets
+	test += fmt.Sprintf("\t\t\tfuzzed = append(fuzzed, %v)\n", fuzzed)
+	test += "\t\t}\n"
+	test += fmt.Sprintf("\t\ttestCompiledTemplate(t, jit, %q, fuzzed)\n", name)
+	test += "\t})\n"
+	test += "}\n\n"
+	return test
+}
+
+// fuzzSample generates the statements to declare d, the copy of the data of the i-th sample,
+// tb fails when the sample is not of the data type of the template name.
+func fuzzSample(indent, tb, name, dataQualifier string, isPtr bool) string {
+	wants := dataQualifier
+	if isPtr {
+		wants = "a non nil *" + dataQualifier
+	}
+	failure := fmt.Sprintf("%v: sample %%v: unexpected data %%#v, wants %v", strings.Replace(name, "%", "%%", -1), wants)
+	code := ""
+	if isPtr {
+		code += fmt.Sprintf("%vp, ok := sample.(*%v)\n", indent, dataQualifier)
+		code += fmt.Sprintf("%vif ok == false || p == nil {\n", indent)
+	} else {
+		code += fmt.Sprintf("%vd, ok := sample.(%v)\n", indent, dataQualifier)
+		code += fmt.Sprintf("%vif ok == false {\n", indent)
+	}
+	code += fmt.Sprintf("%v\t%v.Fatalf(%q, i, sample)\n", indent, tb, failure)
+	code += fmt.Sprintf("%v}\n", indent)
+	if isPtr {
+		code += fmt.Sprintf("%vd := *p\n", indent)
+	}
+	return code
+}
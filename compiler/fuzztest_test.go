@@ -0,0 +1,60 @@
+package compiler
+
+import (
+	"reflect"
+	"testing"
+)
+
+type FuzzFieldsTestData struct {
+	data     interface{}
+	expected []fuzzField
+}
+
+type fuzzInner struct {
+	Name  string
+	Count uint8
+}
+
+type fuzzNamed string
+
+type fuzzData struct {
+	Title   string
+	Visible bool
+	Price   float64
+	Inner   fuzzInner
+	Ptr     *fuzzInner
+	Items   []string
+	Named   fuzzNamed
+	private int
+}
+
+func TestFuzzFields(t *testing.T) {
+
+	allTestData := []FuzzFieldsTestData{
+		FuzzFieldsTestData{
+			data:     nil,
+			expected: nil,
+		},
+		FuzzFieldsTestData{
+			data:     "string",
+			expected: nil,
+		},
+		FuzzFieldsTestData{
+			data: &fuzzData{},
+			expected: []fuzzField{
+				fuzzField{path: "Title", typ: "string"},
+				fuzzField{path: "Visible", typ: "bool"},
+				fuzzField{path: "Price", typ: "float64"},
+				fuzzField{path: "Inner.Name", typ: "string"},
+				fuzzField{path: "Inner.Count", typ: "uint8"},
+			},
+		},
+	}
+
+	for i, testData := range allTestData {
+		got := fuzzFields(reflect.TypeOf(testData.data))
+		if reflect.DeepEqual(got, testData.expected) == false {
+			t.Errorf("Test(%v): Unexpected fuzz fields expected=%v, got=%v", i, testData.expected, got)
+		}
+	}
+}
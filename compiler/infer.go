@@ -0,0 +1,315 @@
+package compiler
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template/parse"
+	"unicode"
+
+	"github.com/mh-cbon/template-compiler/compiled"
+)
+
+// callSite is a {{template}} call with an argument.
+type callSite struct {
+	tree *parse.Tree
+	node *parse.TemplateNode
+	// dataType is the static type of the argument, it is nil when it is unknown.
+	dataType reflect.Type
+}
+
+// inferTemplatesData infers the data type of the templates defined into the file
+// without a data configuration, from the static types of the data passed by their calls.
+// The explicit configuration of TemplatesData takes precedence.
+// When a template is called with different types, it returns an error,
+// when the type of a call is unknown, the template keeps the data type of "*".
+func inferTemplatesData(mainName string, trees map[string]*parse.Tree, t *TemplateToCompile) error {
+	types := map[string]reflect.Type{}
+	pending := map[string]bool{}
+	for name := range trees {
+		if _, ok := t.TemplatesData[name]; ok || name == mainName {
+			data, err := t.getData(name)
+			if err != nil {
+				return err
+			}
+			types[name] = reflect.TypeOf(data)
+		} else {
+			pending[name] = true
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		sites := map[string][]callSite{}
+		calledByPending := map[string]bool{}
+		for _, name := range sortedTreeNames(trees) {
+			if pending[name] {
+				// the calls of a pending template wait for its type,
+				// except its recursive calls.
+				w := &dataTypeWalker{tree: trees[name], funcs: t.FuncsExport, sites: map[string][]callSite{}}
+				w.walk(trees[name].Root, nil, map[string]reflect.Type{})
+				for callee := range w.sites {
+					calledByPending[callee] = calledByPending[callee] || callee != name
+				}
+				continue
+			}
+			w := &dataTypeWalker{tree: trees[name], funcs: t.FuncsExport, sites: sites}
+			w.walk(trees[name].Root, types[name], map[string]reflect.Type{"$": types[name]})
+		}
+		for _, name := range sortedTreeNames(trees) {
+			if pending[name] == false || calledByPending[name] || len(sites[name]) == 0 {
+				continue
+			}
+			dataType, err := inferDataType(name, sites[name])
+			if err != nil {
+				return err
+			}
+			delete(pending, name)
+			changed = true
+			if dataType == nil {
+				data, err := t.getData(name)
+				if err != nil {
+					return err
+				}
+				types[name] = reflect.TypeOf(data)
+				continue
+			}
+			types[name] = dataType
+			data := reflect.Zero(dataType).Interface()
+			if t.TemplatesData == nil {
+				t.TemplatesData = map[string]interface{}{}
+			}
+			if t.TemplatesDataConfiguration == nil {
+				t.TemplatesDataConfiguration = map[string]compiled.DataConfiguration{}
+			}
+			t.TemplatesData[name] = data
+			t.TemplatesDataConfiguration[name] = compiled.MakeDataConfiguration(data)
+		}
+	}
+	return nil
+}
+
+// inferDataType returns the data type passed by the calls of the template name,
+// it is nil when a type is unknown or can not be imported.
+// It returns an error if the calls pass different types.
+func inferDataType(name string, sites []callSite) (reflect.Type, error) {
+	var ret reflect.Type
+	for _, s := range sites {
+		if s.dataType == nil || isImportableType(s.dataType) == false {
+			return nil, nil
+		}
+		if ret == nil {
+			ret = s.dataType
+		}
+	}
+	for _, s := range sites {
+		if s.dataType != ret {
+			conflicts := []string{}
+			for _, s := range sites {
+				location, _ := s.tree.ErrorContext(s.node)
+				conflicts = append(conflicts, fmt.Sprintf("%v at %v", s.dataType, location))
+			}
+			return nil, fmt.Errorf(
+				"Conflicting data types for the template %q: %v, declare its data type into TemplatesData",
+				name, strings.Join(conflicts, ", "))
+		}
+	}
+	return ret, nil
+}
+
+// isImportableType tells if the type t can be declared into the compiled program,
+// it must be a builtin type or an exported type not declared into a main package.
+func isImportableType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Name() == "" {
+		return false
+	}
+	if t.PkgPath() == "" {
+		return true
+	}
+	return t.PkgPath() != "main" && unicode.IsUpper([]rune(t.Name())[0])
+}
+
+// sortedTreeNames returns the names of the trees sorted asc.
+func sortedTreeNames(trees map[string]*parse.Tree) []string {
+	names := []string{}
+	for name := range trees {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// dataTypeWalker browses a template tree to collect the static types
+// of the data passed to its {{template}} calls.
+type dataTypeWalker struct {
+	tree  *parse.Tree
+	funcs map[string]interface{}
+	sites map[string][]callSite
+}
+
+// walk browses node with the type of dot, and the types of the variables in scope.
+func (w *dataTypeWalker) walk(node parse.Node, dot reflect.Type, vars map[string]reflect.Type) {
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node == nil {
+			return
+		}
+		for _, n := range node.Nodes {
+			w.walk(n, dot, vars)
+		}
+
+	case *parse.ActionNode:
+		w.declare(node.Pipe, w.pipeType(node.Pipe, dot, vars), vars)
+
+	case *parse.IfNode:
+		w.walk(node.List, dot, copyVarTypes(vars))
+		w.walk(node.ElseList, dot, copyVarTypes(vars))
+
+	case *parse.RangeNode:
+		scope := copyVarTypes(vars)
+		var key, elem reflect.Type
+		if t := w.pipeType(node.Pipe, dot, vars); t != nil {
+			switch t.Kind() {
+			case reflect.Slice, reflect.Array:
+				key, elem = reflect.TypeOf(0), t.Elem()
+			case reflect.Map:
+				key, elem = t.Key(), t.Elem()
+			case reflect.Chan:
+				elem = t.Elem()
+			}
+		}
+		if len(node.Pipe.Decl) == 1 {
+			scope[node.Pipe.Decl[0].Ident[0]] = elem
+		} else if len(node.Pipe.Decl) == 2 {
+			scope[node.Pipe.Decl[0].Ident[0]] = key
+			scope[node.Pipe.Decl[1].Ident[0]] = elem
+		}
+		w.walk(node.List, elem, scope)
+		w.walk(node.ElseList, dot, copyVarTypes(vars))
+
+	case *parse.WithNode:
+		scope := copyVarTypes(vars)
+		t := w.pipeType(node.Pipe, dot, vars)
+		w.declare(node.Pipe, t, scope)
+		w.walk(node.List, t, scope)
+		w.walk(node.ElseList, dot, copyVarTypes(vars))
+
+	case *parse.TemplateNode:
+		if node.Pipe != nil {
+			w.sites[node.Name] = append(w.sites[node.Name], callSite{
+				tree:     w.tree,
+				node:     node,
+				dataType: w.pipeType(node.Pipe, dot, vars),
+			})
+		}
+	}
+}
+
+// declare sets the type of the variables declared by the pipe.
+func (w *dataTypeWalker) declare(pipe *parse.PipeNode, t reflect.Type, vars map[string]reflect.Type) {
+	if pipe == nil {
+		return
+	}
+	for _, decl := range pipe.Decl {
+		vars[decl.Ident[0]] = t
+	}
+}
+
+// pipeType returns the type of the pipe, the type of its last command.
+func (w *dataTypeWalker) pipeType(pipe *parse.PipeNode, dot reflect.Type, vars map[string]reflect.Type) reflect.Type {
+	if pipe == nil || len(pipe.Cmds) == 0 {
+		return nil
+	}
+	cmd := pipe.Cmds[len(pipe.Cmds)-1]
+	switch arg := cmd.Args[0].(type) {
+	case *parse.IdentifierNode:
+		fn, ok := w.funcs[arg.Ident]
+		if ok == false || fn == nil || reflect.TypeOf(fn).Kind() != reflect.Func || reflect.TypeOf(fn).NumOut() == 0 {
+			return nil
+		}
+		return reflect.TypeOf(fn).Out(0)
+	}
+	return w.nodeType(cmd.Args[0], dot, vars)
+}
+
+// nodeType returns the type of an operand, it is nil when it is unknown.
+func (w *dataTypeWalker) nodeType(node parse.Node, dot reflect.Type, vars map[string]reflect.Type) reflect.Type {
+	switch node := node.(type) {
+	case *parse.DotNode:
+		return dot
+	case *parse.FieldNode:
+		return fieldPathType(dot, node.Ident)
+	case *parse.VariableNode:
+		return fieldPathType(vars[node.Ident[0]], node.Ident[1:])
+	case *parse.ChainNode:
+		return fieldPathType(w.nodeType(node.Node, dot, vars), node.Field)
+	case *parse.PipeNode:
+		return w.pipeType(node, dot, vars)
+	case *parse.StringNode:
+		return reflect.TypeOf("")
+	case *parse.BoolNode:
+		return reflect.TypeOf(true)
+	case *parse.NumberNode:
+		if node.IsInt {
+			return reflect.TypeOf(0)
+		} else if node.IsFloat {
+			return reflect.TypeOf(0.0)
+		}
+	}
+	return nil
+}
+
+// fieldPathType returns the type of the fields, or methods, path from the type t.
+func fieldPathType(t reflect.Type, path []string) reflect.Type {
+	for _, name := range path {
+		if t == nil {
+			return nil
+		}
+		if t.Kind() == reflect.Interface {
+			return nil
+		}
+		m, ok := t.MethodByName(name)
+		if ok == false && t.Kind() != reflect.Ptr {
+			m, ok = reflect.PtrTo(t).MethodByName(name)
+		}
+		if ok {
+			if m.Type.NumOut() == 0 {
+				return nil
+			}
+			t = m.Type.Out(0)
+			continue
+		}
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Struct:
+			f, ok := t.FieldByName(name)
+			if ok == false {
+				return nil
+			}
+			t = f.Type
+		case reflect.Map:
+			t = t.Elem()
+		default:
+			return nil
+		}
+	}
+	if t != nil && t.Kind() == reflect.Interface {
+		return nil
+	}
+	return t
+}
+
+// copyVarTypes returns a copy of the variables types, for a new scope.
+func copyVarTypes(vars map[string]reflect.Type) map[string]reflect.Type {
+	ret := map[string]reflect.Type{}
+	for k, v := range vars {
+		ret[k] = v
+	}
+	return ret
+}
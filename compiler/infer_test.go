@@ -0,0 +1,127 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mh-cbon/template-compiler/compiled"
+	"github.com/mh-cbon/template-compiler/demo/data"
+)
+
+type InferTestData struct {
+	tplstr string
+	data   map[string]interface{}
+	// the data configurations expected for the defined templates,
+	// a nil value means the template is not inferred
+	expected map[string]*compiled.DataConfiguration
+	// the expected error
+	expectedErr string
+}
+
+func TestInferTemplatesData(t *testing.T) {
+
+	allTestData := []InferTestData{
+		InferTestData{
+			tplstr: `{{define "row"}}{{.}}{{end}}{{range .Items}}{{template "row" .}}{{end}}`,
+			data:   map[string]interface{}{"*": data.MyTemplateData{}},
+			expected: map[string]*compiled.DataConfiguration{
+				"row": &compiled.DataConfiguration{DataTypeName: "string", DataType: "string"},
+			},
+		},
+		InferTestData{
+			tplstr: `{{define "row"}}{{.SomeString}}{{end}}{{range $i, $e := .SomeTemplateDataSlice}}{{template "row" $e}}{{end}}`,
+			data:   map[string]interface{}{"*": TemplateData{}},
+			expected: map[string]*compiled.DataConfiguration{
+				"row": &compiled.DataConfiguration{
+					IsPtr:        true,
+					DataTypeName: "TemplateData",
+					PkgPath:      "github.com/mh-cbon/template-compiler/compiler",
+					DataType:     "compiler.TemplateData",
+				},
+			},
+		},
+		InferTestData{
+			tplstr: `{{define "row"}}{{.}}{{end}}{{with .Some}}{{template "row" .}}{{end}}`,
+			data:   map[string]interface{}{"*": data.MyTemplateData{}},
+			expected: map[string]*compiled.DataConfiguration{
+				"row": &compiled.DataConfiguration{DataTypeName: "string", DataType: "string"},
+			},
+		},
+		InferTestData{
+			tplstr: `{{define "a"}}{{template "b" .Some}}{{end}}{{define "b"}}{{.}}{{end}}{{template "a" .}}`,
+			data:   map[string]interface{}{"*": data.MyTemplateData{}},
+			expected: map[string]*compiled.DataConfiguration{
+				"a": &compiled.DataConfiguration{
+					DataTypeName: "MyTemplateData",
+					PkgPath:      "github.com/mh-cbon/template-compiler/demo/data",
+					DataType:     "data.MyTemplateData",
+				},
+				"b": &compiled.DataConfiguration{DataTypeName: "string", DataType: "string"},
+			},
+		},
+		InferTestData{
+			tplstr: `{{define "row"}}{{.}}{{end}}{{template "row" .Some}}`,
+			data:   map[string]interface{}{"*": data.MyTemplateData{}, "row": data.MyTemplateData{}},
+			expected: map[string]*compiled.DataConfiguration{
+				"row": &compiled.DataConfiguration{
+					DataTypeName: "MyTemplateData",
+					PkgPath:      "github.com/mh-cbon/template-compiler/demo/data",
+					DataType:     "data.MyTemplateData",
+				},
+			},
+		},
+		InferTestData{
+			tplstr: `{{define "row"}}{{.}}{{end}}{{template "row" .SomeInterface}}{{template "row" .MethodHello}}`,
+			data:   map[string]interface{}{"*": TemplateData{}},
+			expected: map[string]*compiled.DataConfiguration{
+				"row": nil,
+			},
+		},
+		InferTestData{
+			tplstr: `{{define "row"}}{{.}}{{end}}{{template "row" .MethodItems}}`,
+			data:   map[string]interface{}{"*": data.MyTemplateData{}},
+			expected: map[string]*compiled.DataConfiguration{
+				"row": nil,
+			},
+		},
+		InferTestData{
+			tplstr:      `{{define "row"}}{{.}}{{end}}{{template "row" .Some}}{{template "row" .}}`,
+			data:        map[string]interface{}{"*": data.MyTemplateData{}},
+			expectedErr: `Conflicting data types for the template "row": string at x:1:39, data.MyTemplateData at x:1:63`,
+		},
+	}
+
+	for i, testData := range allTestData {
+		conf := compiled.New("gen.go", []compiled.TemplateConfiguration{
+			compiled.TemplateConfiguration{
+				TemplateName:    "x",
+				TemplateContent: testData.tplstr,
+				TemplatesData:   testData.data,
+			},
+		})
+		tpl := makeTemplateToCompile(conf.Templates[0])
+		err := tpl.prepare()
+		if testData.expectedErr != "" {
+			if err == nil || strings.Contains(err.Error(), testData.expectedErr) == false {
+				t.Errorf("Test(%v): Expected error %q, got %v", i, testData.expectedErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test(%v): Unexpected error %v", i, err)
+			continue
+		}
+		for name, expected := range testData.expected {
+			got, ok := tpl.TemplatesDataConfiguration[name]
+			if expected == nil {
+				if ok {
+					t.Errorf("Test(%v): Unexpected data configuration for %v: %#v", i, name, got)
+				}
+				continue
+			}
+			if ok == false || got != *expected {
+				t.Errorf("Test(%v): Unexpected data configuration for %v\nexpected=%#v\ngot=     %#v", i, name, *expected, got)
+			}
+		}
+	}
+}
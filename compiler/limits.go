@@ -0,0 +1,144 @@
+package compiler
+
+import (
+	"fmt"
+	"go/ast"
+	"text/template/parse"
+)
+
+// static name of the limiter of the compiled template functions.
+const limiterName = "limiter"
+
+// addRangeLimits checks the iterations of the loops of the compiled template function fnName
+// with the limiter of the execution, when it has one.
+// The function starts with limiter, _ := t.(parse.Limiter),
+// each loop counts its iterations and calls limiter.CheckRange("name", iter0).
+func (c *CompiledTemplatesProgram) addRangeLimits(fnName, tplName string) {
+	var fn *ast.FuncDecl
+	for _, f := range c.funcs {
+		if f.Name.Name == fnName {
+			fn = f
+		}
+	}
+	loops := 0
+	fn.Body.List = rangeLimitStmts(fn.Body.List, tplName, &loops)
+	if loops > 0 {
+		alias := c.addImport("github.com/mh-cbon/template-compiler/std/text/template/parse")
+		decl := getStmtsAst(limiterName + `, _ := t.(` + alias + `.Limiter)`)
+		fn.Body.List = append(decl, fn.Body.List...)
+	}
+}
+
+// rangeLimitStmts rewrites a list of statements to check the iterations of its loops,
+// loops is the count of the loops of the function.
+func rangeLimitStmts(list []ast.Stmt, tplName string, loops *int) []ast.Stmt {
+	ret := make([]ast.Stmt, 0, len(list))
+	for _, stmt := range list {
+		var body *ast.BlockStmt
+		switch s := stmt.(type) {
+		case *ast.IfStmt:
+			s.Body.List = rangeLimitStmts(s.Body.List, tplName, loops)
+			switch e := s.Else.(type) {
+			case *ast.BlockStmt:
+				e.List = rangeLimitStmts(e.List, tplName, loops)
+			case *ast.IfStmt:
+				rangeLimitStmts([]ast.Stmt{e}, tplName, loops)
+			}
+		case *ast.RangeStmt:
+			body = s.Body
+		case *ast.ForStmt:
+			body = s.Body
+		case *ast.BlockStmt:
+			s.List = rangeLimitStmts(s.List, tplName, loops)
+		case *ast.SwitchStmt:
+			for _, cc := range s.Body.List {
+				cc.(*ast.CaseClause).Body = rangeLimitStmts(cc.(*ast.CaseClause).Body, tplName, loops)
+			}
+		case *ast.TypeSwitchStmt:
+			for _, cc := range s.Body.List {
+				cc.(*ast.CaseClause).Body = rangeLimitStmts(cc.(*ast.CaseClause).Body, tplName, loops)
+			}
+		}
+		if body != nil {
+			counter := fmt.Sprintf("iter%v", *loops)
+			*loops++
+			body.List = append(getStmtsAst(`
+if `+limiterName+` != nil {
+  `+counter+`++
+  if err := `+limiterName+`.CheckRange(`+fmt.Sprintf("%q", tplName)+`, `+counter+`); err != nil {
+    return err
+  }
+}`), rangeLimitStmts(body.List, tplName, loops)...)
+			ret = append(ret, getStmtsAst(`var `+counter+` int`)...)
+		}
+		ret = append(ret, stmt)
+	}
+	return ret
+}
+
+// recursiveCalls returns the template calls which may recurse,
+// indexed by the caller name, then by the callee name.
+// A call is recursive when the callee calls back its caller, directly or not,
+// such calls remain dynamic so the depth of the execution is limited.
+func recursiveCalls(trees map[string][]*parse.Tree) map[string]map[string]bool {
+	calls := map[string]map[string]bool{}
+	for name, defs := range trees {
+		calls[name] = map[string]bool{}
+		for _, tree := range defs {
+			templateCalls(tree.Root, calls[name])
+		}
+	}
+	ret := map[string]map[string]bool{}
+	for caller, callees := range calls {
+		for callee := range callees {
+			if reaches(calls, callee, caller, map[string]bool{}) {
+				if ret[caller] == nil {
+					ret[caller] = map[string]bool{}
+				}
+				ret[caller][callee] = true
+			}
+		}
+	}
+	return ret
+}
+
+// reaches tells if the template from calls the template to, directly or not.
+func reaches(calls map[string]map[string]bool, from, to string, seen map[string]bool) bool {
+	if from == to {
+		return true
+	}
+	if seen[from] {
+		return false
+	}
+	seen[from] = true
+	for callee := range calls[from] {
+		if reaches(calls, callee, to, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// templateCalls collects the names of the templates called within node.
+func templateCalls(node parse.Node, calls map[string]bool) {
+	switch node := node.(type) {
+	case *parse.ListNode:
+		if node == nil {
+			return
+		}
+		for _, n := range node.Nodes {
+			templateCalls(n, calls)
+		}
+	case *parse.IfNode:
+		templateCalls(node.List, calls)
+		templateCalls(node.ElseList, calls)
+	case *parse.RangeNode:
+		templateCalls(node.List, calls)
+		templateCalls(node.ElseList, calls)
+	case *parse.WithNode:
+		templateCalls(node.List, calls)
+		templateCalls(node.ElseList, calls)
+	case *parse.TemplateNode:
+		calls[node.Name] = true
+	}
+}
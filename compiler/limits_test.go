@@ -0,0 +1,118 @@
+package compiler
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"text/template/parse"
+
+	"github.com/mh-cbon/template-compiler/compiled"
+	"github.com/mh-cbon/template-compiler/demo/data"
+)
+
+type RecursiveCallsTestData struct {
+	tplstr   string
+	expected map[string]map[string]bool
+}
+
+func TestRecursiveCalls(t *testing.T) {
+
+	allTestData := []RecursiveCallsTestData{
+		RecursiveCallsTestData{
+			tplstr:   `{{define "a"}}a{{end}}{{template "a"}}`,
+			expected: map[string]map[string]bool{},
+		},
+		RecursiveCallsTestData{
+			tplstr: `{{define "a"}}{{if .}}{{template "a" .}}{{end}}{{end}}{{template "a"}}`,
+			expected: map[string]map[string]bool{
+				"a": map[string]bool{"a": true},
+			},
+		},
+		RecursiveCallsTestData{
+			tplstr: `{{define "a"}}{{range .}}{{template "b" .}}{{end}}{{end}}{{define "b"}}{{template "a" .}}{{template "c"}}{{end}}{{define "c"}}c{{end}}{{template "a"}}`,
+			expected: map[string]map[string]bool{
+				"a": map[string]bool{"b": true},
+				"b": map[string]bool{"a": true},
+			},
+		},
+	}
+
+	for i, testData := range allTestData {
+		trees := map[string]*parse.Tree{}
+		tree := parse.New("x")
+		if _, err := tree.Parse(testData.tplstr, "", "", trees); err != nil {
+			t.Errorf("Test(%v): Unexpected error %v", i, err)
+			continue
+		}
+		defs := map[string][]*parse.Tree{}
+		for name, tree := range trees {
+			defs[name] = append(defs[name], tree)
+		}
+		got := recursiveCalls(defs)
+		if reflect.DeepEqual(got, testData.expected) == false {
+			t.Errorf("Test(%v): Unexpected recursive calls\nexpected=%v\ngot=     %v", i, testData.expected, got)
+		}
+	}
+}
+
+type RangeLimitsTestData struct {
+	templates []compiled.TemplateConfiguration
+	// the code expected to be found in the program
+	expected []string
+}
+
+func TestAddRangeLimits(t *testing.T) {
+
+	allTestData := []RangeLimitsTestData{
+		RangeLimitsTestData{
+			templates: []compiled.TemplateConfiguration{
+				compiled.TemplateConfiguration{
+					TemplateName:    "page",
+					TemplateContent: `{{range .Items}}<{{.}}>{{end}}`,
+					TemplatesData:   map[string]interface{}{"*": data.MyTemplateData{}},
+				},
+			},
+			expected: []string{
+				`func fnpage(t parse.Templater, w io.Writer, indata interface{}) error {
+	limiter, _ := t.(parse.Limiter)`,
+				`	var iter0 int
+	for _, iterable := range data.Items {
+		if limiter != nil {
+			iter0++
+			if err := limiter.CheckRange("page", iter0); err != nil {
+				return err
+			}
+		}`,
+				`			if err := limiter.CheckRange("page", iter0); err != nil {
+				return dst, err
+			}`,
+			},
+		},
+		RangeLimitsTestData{
+			templates: []compiled.TemplateConfiguration{
+				compiled.TemplateConfiguration{
+					TemplateName:    "page",
+					TemplateContent: `{{define "row"}}{{.Some}}{{template "row" .}}{{end}}{{template "row" .}}`,
+					TemplatesData:   map[string]interface{}{"*": data.MyTemplateData{}},
+				},
+			},
+			expected: []string{
+				`if werr := fnpage_rowData(t, w, data); werr != nil {`,
+				`if werr := t.ExecuteTemplate(w, "row", data); werr != nil {`,
+			},
+		},
+	}
+
+	for i, testData := range allTestData {
+		program, err := compileLinkTestData(testData.templates)
+		if err != nil {
+			t.Errorf("Test(%v): Unexpected error %v", i, err)
+			continue
+		}
+		for _, e := range testData.expected {
+			if strings.Contains(program, e) == false {
+				t.Errorf("Test(%v): Expected to find %v in the program\n%v", i, e, program)
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package compiler
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template/parse"
+)
+
+// lineDirectivePrefix starts the string statements standing for a //line directive
+// until the program is printed.
+const lineDirectivePrefix = "//line "
+
+// lineFile returns the path of the template file tplPath relative to the output program,
+// such it is written into the //line directives.
+func (c *CompiledTemplatesProgram) lineFile(tplPath string) string {
+	outDir, err := filepath.Abs(filepath.Dir(c.outPath))
+	if err != nil {
+		return filepath.ToSlash(tplPath)
+	}
+	abs, err := filepath.Abs(tplPath)
+	if err != nil {
+		return filepath.ToSlash(tplPath)
+	}
+	rel, err := filepath.Rel(outDir, abs)
+	if err != nil {
+		return filepath.ToSlash(tplPath)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// addLineDirective adds the //line directive of node to the current BlockStmt,
+// when the template is read from a file.
+// The directive of a node converted to no statement is replaced by the next one.
+func (c *converter) addLineDirective(node parse.Node) {
+	file, ok := c.compiledProgram.lineFiles[c.tree]
+	if ok == false {
+		return
+	}
+	location, _ := c.tree.ErrorContext(node)
+	location = strings.TrimPrefix(location, c.tree.ParseName+":")
+	i := strings.LastIndex(location, ":")
+	col, _ := strconv.Atoi(location[i+1:])
+	// columns are 1-based in the directives.
+	directive := fmt.Sprintf("%v%v:%v:%v", lineDirectivePrefix, file, location[:i], col+1)
+	stmt := &ast.ExprStmt{X: &ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(directive)}}
+
+	list := c.state.current.body.List
+	if n := len(list); n > 0 && isLineDirective(list[n-1]) {
+		list[n-1] = stmt
+		return
+	}
+	c.state.addNode(stmt)
+}
+
+// isLineDirective tells if stmt stands for a //line directive.
+func isLineDirective(stmt ast.Stmt) bool {
+	e, ok := stmt.(*ast.ExprStmt)
+	if ok == false {
+		return false
+	}
+	lit, ok := e.X.(*ast.BasicLit)
+	return ok && lit.Kind == token.STRING && strings.HasPrefix(lit.Value, `"`+lineDirectivePrefix)
+}
+
+// lineDirectiveStmt matches the printed statement standing for a //line directive.
+var lineDirectiveStmt = regexp.MustCompile(`^\s*"(//line [^"]+)"$`)
+
+// writeLineDirectives writes the //line directives of the printed program,
+// they start at the beginning of their line.
+// At the end of a function using them, the positions are restored
+// to the lines of the program named outName.
+func writeLineDirectives(program string, outName string) string {
+	lines := strings.Split(program, "\n")
+	ret := make([]string, 0, len(lines))
+	directed := false
+	for _, line := range lines {
+		if m := lineDirectiveStmt.FindStringSubmatch(line); m != nil {
+			ret = append(ret, m[1])
+			directed = true
+			continue
+		}
+		ret = append(ret, line)
+		if directed && line == "}" {
+			// the directive sets the position of the line following it.
+			ret = append(ret, fmt.Sprintf("%v%v:%v", lineDirectivePrefix, outName, len(ret)+2))
+			directed = false
+		}
+	}
+	return strings.Join(ret, "\n")
+}
@@ -0,0 +1,124 @@
+package compiler
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/mh-cbon/template-compiler/compiled"
+	"github.com/mh-cbon/template-compiler/demo/data"
+)
+
+type LineDirectivesTestData struct {
+	program  string
+	expected string
+}
+
+func TestWriteLineDirectives(t *testing.T) {
+
+	allTestData := []LineDirectivesTestData{
+		LineDirectivesTestData{
+			program: `package aa
+
+func fn() error {
+	"//line templates/a.tpl:1:1"
+	if var0 {
+		"//line templates/a.tpl:2:5"
+		return nil
+	}
+	return nil
+}
+
+func other() {
+}`,
+			expected: `package aa
+
+func fn() error {
+//line templates/a.tpl:1:1
+	if var0 {
+//line templates/a.tpl:2:5
+		return nil
+	}
+	return nil
+}
+//line gen.go:12
+
+func other() {
+}`,
+		},
+		LineDirectivesTestData{
+			program: `package aa
+
+func fn() error {
+	return "//line is not a directive"
+}`,
+			expected: `package aa
+
+func fn() error {
+	return "//line is not a directive"
+}`,
+		},
+	}
+
+	for i, testData := range allTestData {
+		got := writeLineDirectives(testData.program, "gen.go")
+		if got != testData.expected {
+			t.Errorf("Test(%v): unexpected program\nexpected=%v\ngot=%v", i, testData.expected, got)
+		}
+	}
+}
+
+func TestAddLineDirectives(t *testing.T) {
+	dir, err := ioutil.TempDir("", "line")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Mkdir(filepath.Join(dir, "templates"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	content := "hello\n{{range .Items}}{{.}}{{end}}"
+	if err := ioutil.WriteFile(filepath.Join(dir, "templates", "a.tpl"), []byte(content), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	conf := compiled.New(filepath.Join(dir, "gen.go"), []compiled.TemplateConfiguration{
+		compiled.TemplateConfiguration{
+			TemplatesPath: filepath.Join(dir, "templates", "*.tpl"),
+			TemplatesData: map[string]interface{}{"*": data.MyTemplateData{}},
+		},
+	}).SetPkg("main")
+	c := NewCompiledTemplatesProgram("compiledTemplates")
+	c.outPath = conf.OutPath
+	tpls, err := c.getTemplatesToCompile(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	program, err := c.compileTemplates("main", tpls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		`//line templates/a.tpl:1:1
+	if _, werr := w.Write(builtin0); werr != nil {`,
+		`//line templates/a.tpl:2:9
+	var iter0 int
+	for _, iterable := range data.Items {`,
+		`//line templates/a.tpl:2:19
+		if _, werr := fmt.Fprintf(w, "%v", iterable); werr != nil {`,
+	}
+	for _, e := range expected {
+		if strings.Contains(program, e) == false {
+			t.Errorf("expected to find\n%v\nin\n%v", e, program)
+		}
+	}
+	lines := strings.Split(program, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, "//line gen.go:") && line != "//line gen.go:"+strconv.Itoa(i+2) {
+			t.Errorf("unexpected directive at line %v %v", i+1, line)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package compiler
+
+import (
+	"fmt"
+	"go/ast"
+	"reflect"
+	"text/template/parse"
+
+	"github.com/mh-cbon/template-compiler/compiled"
+	"github.com/mh-cbon/template-tree-simplifier/simplifier"
+)
+
+// linkedTemplate is a compiled template its callers call statically.
+type linkedTemplate struct {
+	name     string
+	fnName   string
+	dataType reflect.Type
+	dataConf compiled.DataConfiguration
+	// dataFnName is the func receiving the typed data, it is declared when a call uses it.
+	dataFnName string
+	// ambiguous is true when the name is defined several times, the calls remain dynamic.
+	ambiguous bool
+}
+
+// linkTemplate registers the func of the template name for the static calls.
+func (c *CompiledTemplatesProgram) linkTemplate(name, fnName string, data interface{}, dataConf compiled.DataConfiguration) {
+	if l, ok := c.linked[name]; ok {
+		l.ambiguous = true
+		return
+	}
+	c.linked[name] = &linkedTemplate{
+		name:     name,
+		fnName:   fnName,
+		dataType: reflect.TypeOf(data),
+		dataConf: dataConf,
+	}
+}
+
+// getLinkedTemplate returns the template name when it can be called statically.
+func (c *CompiledTemplatesProgram) getLinkedTemplate(name string) (*linkedTemplate, bool) {
+	l, ok := c.linked[name]
+	if ok == false || l.ambiguous {
+		return nil, false
+	}
+	return l, true
+}
+
+// handleLinkedTemplateNode converts a template call to a call of the func of the template l.
+// When the argument has the data type of the template, the func receiving the typed data is called,
+// when it is an interface, the func checks the data type at runtime.
+// It panics if the argument can not be the data of the template.
+func (c *converter) handleLinkedTemplateNode(node *parse.TemplateNode, l *linkedTemplate, typeCheck *simplifier.State) []ast.Stmt {
+	fnName := l.fnName
+	expr := "nil"
+	if node.Pipe != nil {
+		expr = astNodeToString(c.handleCommandNode(node.Pipe.Cmds[0], typeCheck))
+		argType, _ := c.getTypesOfCommandNode(node.Pipe.Cmds[0], typeCheck)
+		if l.dataType != nil && argType != nil && argType.Kind() != reflect.Interface {
+			if argType.AssignableTo(l.dataType) == false {
+				err := fmt.Errorf(
+					"converter.handleTemplateNode: template %q wants data of type %v, the call passes %v\n%v",
+					l.name, l.dataType, argType, node)
+				panic(err)
+			}
+			if l.dataFnName == "" {
+				l.dataFnName = c.compiledProgram.makeFuncName(l.fnName + "Data")
+			}
+			fnName = l.dataFnName
+		}
+	}
+
+	return getStmtsAst(`
+if werr := ` + fnName + `(t, ` + c.writerName + `, ` + expr + `); werr != nil {
+  return werr
+}`)
+}
+
+// addDataFuncs declares the funcs receiving the typed data of the templates called statically,
+// such func fnaTplData(t parse.Templater, w io.Writer, data data.MyTemplateData) error.
+// They are a copy of the func of the template without the prelude typing its input data.
+func (c *CompiledTemplatesProgram) addDataFuncs(tpls []*TemplateToCompile) {
+	for _, t := range tpls {
+		for _, f := range t.files {
+			for _, name := range f.names() {
+				l, ok := c.linked[name]
+				if ok == false || l.dataFnName == "" {
+					continue
+				}
+				var src *ast.FuncDecl
+				for _, fn := range c.funcs {
+					if fn.Name.Name == l.fnName {
+						src = fn
+					}
+				}
+				// work on a copy of the function.
+				fn := stringToAst("package aa\n" + astNodeToString(src)).Decls[0].(*ast.FuncDecl)
+				sign := stringToAst(`package aa
+func ` + l.dataFnName + `(t parse.Templater, w io.Writer, data ` + c.getDataQualifier(l.dataConf) + `) error {}`)
+				fn.Name = sign.Decls[0].(*ast.FuncDecl).Name
+				fn.Type = sign.Decls[0].(*ast.FuncDecl).Type
+				fn.Body.List = removeDataPrelude(fn.Body.List)
+				c.funcs = append(c.funcs, fn)
+			}
+		}
+	}
+}
+
+// removeDataPrelude removes the prelude typing the input data from list,
+// the declaration var data ... and the type assertion following it.
+func removeDataPrelude(list []ast.Stmt) []ast.Stmt {
+	for i, stmt := range list {
+		d, ok := stmt.(*ast.DeclStmt)
+		if ok == false {
+			continue
+		}
+		if g, ok := d.Decl.(*ast.GenDecl); ok && len(g.Specs) == 1 {
+			if v, ok := g.Specs[0].(*ast.ValueSpec); ok && len(v.Names) == 1 && v.Names[0].Name == "data" {
+				if i+1 < len(list) {
+					if _, ok := list[i+1].(*ast.IfStmt); ok {
+						return append(list[:i:i], list[i+2:]...)
+					}
+				}
+			}
+		}
+	}
+	return list
+}
@@ -0,0 +1,117 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mh-cbon/template-compiler/compiled"
+	"github.com/mh-cbon/template-compiler/demo/data"
+)
+
+type LinkTestData struct {
+	templates []compiled.TemplateConfiguration
+	// the code expected to be found in the program
+	expected []string
+	// the code expected not to be found in the program
+	unexpected []string
+	// the expected compile error
+	expectedErr string
+}
+
+func TestLinkTemplates(t *testing.T) {
+
+	allTestData := []LinkTestData{
+		LinkTestData{
+			templates: []compiled.TemplateConfiguration{
+				compiled.TemplateConfiguration{
+					TemplateName:    "page",
+					TemplateContent: `{{define "row"}}{{.Some}}{{end}}<{{template "row" .}}>{{template "other"}}`,
+					TemplatesData:   map[string]interface{}{"*": data.MyTemplateData{}},
+				},
+				compiled.TemplateConfiguration{
+					TemplateName:    "other",
+					TemplateContent: `other`,
+					TemplatesData:   map[string]interface{}{"*": nil},
+				},
+			},
+			expected: []string{
+				`fnother(t, w, nil)`,
+				`func fnpage_rowData(t parse.Templater, w io.Writer, data aliasdata.MyTemplateData) error {
+	if _, werr := io.WriteString(w, data.Some); werr != nil {`,
+			},
+			unexpected: []string{
+				// the templates are called by name only when the execution is observed.
+				"\n\tif werr := t.ExecuteTemplate",
+				`func fnotherData`,
+			},
+		},
+		LinkTestData{
+			templates: []compiled.TemplateConfiguration{
+				compiled.TemplateConfiguration{
+					TemplateName:    "a",
+					TemplateContent: `{{define "x"}}a{{end}}{{template "x"}}`,
+					TemplatesData:   map[string]interface{}{"*": nil},
+				},
+				compiled.TemplateConfiguration{
+					TemplateName:    "b",
+					TemplateContent: `{{define "x"}}b{{end}}{{template "x"}}`,
+					TemplatesData:   map[string]interface{}{"*": nil},
+				},
+			},
+			expected: []string{
+				`t.ExecuteTemplate(w, "x", nil)`,
+			},
+		},
+		LinkTestData{
+			templates: []compiled.TemplateConfiguration{
+				compiled.TemplateConfiguration{
+					TemplateName:    "page",
+					TemplateContent: `{{define "row"}}{{.Some}}{{end}}{{template "row" .Some}}`,
+					TemplatesData:   map[string]interface{}{"*": data.MyTemplateData{}, "row": data.MyTemplateData{}},
+				},
+			},
+			expectedErr: `template "row" wants data of type data.MyTemplateData, the call passes string`,
+		},
+	}
+
+	for i, testData := range allTestData {
+		program, err := compileLinkTestData(testData.templates)
+		if testData.expectedErr != "" {
+			if err == nil || strings.Contains(err.Error(), testData.expectedErr) == false {
+				t.Errorf("Test(%v): Expected error %q, got %v", i, testData.expectedErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Test(%v): Unexpected error %v", i, err)
+			continue
+		}
+		for _, e := range testData.expected {
+			if strings.Contains(program, e) == false {
+				t.Errorf("Test(%v): Expected to find %v in the program\n%v", i, e, program)
+			}
+		}
+		for _, e := range testData.unexpected {
+			if strings.Contains(program, e) {
+				t.Errorf("Test(%v): Unexpected %v in the program\n%v", i, e, program)
+			}
+		}
+	}
+}
+
+// compileLinkTestData compiles the templates, it returns the converter panics as an error.
+func compileLinkTestData(templates []compiled.TemplateConfiguration) (program string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	conf := compiled.New("gen.go", templates).SetPkg("main")
+	c := NewCompiledTemplatesProgram("compiledTemplates")
+	tpls, err := c.getTemplatesToCompile(conf)
+	if err != nil {
+		return "", err
+	}
+	return c.compileTemplates("main", tpls)
+}
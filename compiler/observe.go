@@ -0,0 +1,102 @@
+package compiler
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+)
+
+// static name of the observer of the compiled template functions.
+const observerName = "observer"
+
+// addObservedCalls lets the observer of the execution observe the static template calls
+// of the compiled template function fnName. The function starts with observer, _ := t.(parse.Observer),
+// when it is set, the templates are called by name with t.ExecuteTemplate.
+func (c *CompiledTemplatesProgram) addObservedCalls(fnName string) {
+	var fn *ast.FuncDecl
+	for _, f := range c.funcs {
+		if f.Name.Name == fnName {
+			fn = f
+		}
+	}
+	names := map[string]string{}
+	for name, l := range c.linked {
+		names[l.fnName] = name
+		if l.dataFnName != "" {
+			names[l.dataFnName] = name
+		}
+	}
+	calls := 0
+	fn.Body.List = observedCallStmts(fn.Body.List, names, &calls)
+	if calls > 0 {
+		alias := c.addImport("github.com/mh-cbon/template-compiler/std/text/template/parse")
+		decl := getStmtsAst(observerName + `, _ := t.(` + alias + `.Observer)`)
+		fn.Body.List = append(decl, fn.Body.List...)
+	}
+}
+
+// observedCallStmts rewrites a list of statements to call the templates by name when they are observed,
+// calls is the count of the static calls of the function.
+func observedCallStmts(list []ast.Stmt, names map[string]string, calls *int) []ast.Stmt {
+	ret := make([]ast.Stmt, 0, len(list))
+	for _, stmt := range list {
+		switch s := stmt.(type) {
+		case *ast.IfStmt:
+			if name, args, ok := staticTemplateCall(s, names); ok {
+				*calls++
+				stmt = getStmtsAst(`
+if ` + observerName + ` != nil {
+  if werr := t.ExecuteTemplate(` + astNodeToString(args[1]) + `, ` + fmt.Sprintf("%q", name) + `, ` + astNodeToString(args[2]) + `); werr != nil {
+    return werr
+  }
+} else {
+  ` + astNodeToString(s) + `
+}`)[0]
+				break
+			}
+			s.Body.List = observedCallStmts(s.Body.List, names, calls)
+			switch e := s.Else.(type) {
+			case *ast.BlockStmt:
+				e.List = observedCallStmts(e.List, names, calls)
+			case *ast.IfStmt:
+				observedCallStmts([]ast.Stmt{e}, names, calls)
+			}
+		case *ast.RangeStmt:
+			s.Body.List = observedCallStmts(s.Body.List, names, calls)
+		case *ast.ForStmt:
+			s.Body.List = observedCallStmts(s.Body.List, names, calls)
+		case *ast.BlockStmt:
+			s.List = observedCallStmts(s.List, names, calls)
+		case *ast.SwitchStmt:
+			for _, cc := range s.Body.List {
+				cc.(*ast.CaseClause).Body = observedCallStmts(cc.(*ast.CaseClause).Body, names, calls)
+			}
+		case *ast.TypeSwitchStmt:
+			for _, cc := range s.Body.List {
+				cc.(*ast.CaseClause).Body = observedCallStmts(cc.(*ast.CaseClause).Body, names, calls)
+			}
+		}
+		ret = append(ret, stmt)
+	}
+	return ret
+}
+
+// staticTemplateCall tells if s is a static template call such
+// if werr := fnx(t, w, data); werr != nil { return werr },
+// it returns the name of the template and the arguments of the call.
+func staticTemplateCall(s *ast.IfStmt, names map[string]string) (string, []ast.Expr, bool) {
+	init, ok := s.Init.(*ast.AssignStmt)
+	if ok == false || init.Tok != token.DEFINE || len(init.Rhs) != 1 {
+		return "", nil, false
+	}
+	call, ok := init.Rhs[0].(*ast.CallExpr)
+	if ok == false || len(call.Args) != 3 {
+		return "", nil, false
+	}
+	fn, ok := call.Fun.(*ast.Ident)
+	if ok == false {
+		return "", nil, false
+	}
+	name, ok := names[fn.Name]
+	return name, call.Args, ok
+}
@@ -0,0 +1,94 @@
+package compiler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mh-cbon/template-compiler/compiled"
+	"github.com/mh-cbon/template-compiler/demo/data"
+)
+
+type ObservedCallsTestData struct {
+	templates []compiled.TemplateConfiguration
+	// the code expected to be found in the program
+	expected []string
+	// the code expected not to be found in the program
+	unexpected []string
+}
+
+func TestAddObservedCalls(t *testing.T) {
+
+	allTestData := []ObservedCallsTestData{
+		ObservedCallsTestData{
+			templates: []compiled.TemplateConfiguration{
+				compiled.TemplateConfiguration{
+					TemplateName:    "page",
+					TemplateContent: `{{define "row"}}{{.Some}}{{end}}{{if .Some}}{{template "row" .}}{{end}}`,
+					TemplatesData:   map[string]interface{}{"*": data.MyTemplateData{}},
+				},
+			},
+			expected: []string{
+				`func fnpage(t parse.Templater, w io.Writer, indata interface{}) error {
+	observer, _ := t.(parse.Observer)`,
+				`		if observer != nil {
+			if werr := t.ExecuteTemplate(w, "row", data); werr != nil {
+				return werr
+			}
+		} else {
+			if werr := fnpage_rowData(t, w, data); werr != nil {
+				return werr
+			}
+		}`,
+				`		if observer != nil {
+			if werr := t.ExecuteTemplate(w, "row", data); werr != nil {
+				return dst, werr
+			}
+		} else {
+			if werr := fnpage_rowData(t, w, data); werr != nil {
+				return dst, werr
+			}
+		}`,
+			},
+			unexpected: []string{
+				`func fnpage_rowData(t parse.Templater, w io.Writer, data aliasdata.MyTemplateData) error {
+	observer`,
+			},
+		},
+		ObservedCallsTestData{
+			templates: []compiled.TemplateConfiguration{
+				compiled.TemplateConfiguration{
+					TemplateName:    "a",
+					TemplateContent: `{{define "x"}}a{{end}}{{template "x"}}`,
+					TemplatesData:   map[string]interface{}{"*": nil},
+				},
+				compiled.TemplateConfiguration{
+					TemplateName:    "b",
+					TemplateContent: `{{define "x"}}b{{end}}{{template "x"}}`,
+					TemplatesData:   map[string]interface{}{"*": nil},
+				},
+			},
+			// the ambiguous calls are already dynamic.
+			unexpected: []string{
+				`observer`,
+			},
+		},
+	}
+
+	for i, testData := range allTestData {
+		program, err := compileLinkTestData(testData.templates)
+		if err != nil {
+			t.Errorf("Test(%v): Unexpected error %v", i, err)
+			continue
+		}
+		for _, e := range testData.expected {
+			if strings.Contains(program, e) == false {
+				t.Errorf("Test(%v): Expected to find %v in the program\n%v", i, e, program)
+			}
+		}
+		for _, e := range testData.unexpected {
+			if strings.Contains(program, e) {
+				t.Errorf("Test(%v): Unexpected %v in the program\n%v", i, e, program)
+			}
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package compiler
+
+import (
+	"text/template/parse"
+
+	texttemplate "github.com/mh-cbon/template-compiler/std/text/template"
+)
+
+// sizeEstimator estimates the output size of the templates of a file.
+type sizeEstimator struct {
+	trees    map[string]*parse.Tree
+	profile  texttemplate.SizeProfile
+	visiting map[string]bool
+}
+
+// estimateSize returns a static lower bound of the output size of the template name,
+// the texts written whatever the data are summed,
+// the range loops found in the profile add their average observed size.
+func estimateSize(trees map[string]*parse.Tree, name string, profile texttemplate.SizeProfile) int {
+	e := sizeEstimator{trees: trees, profile: profile, visiting: map[string]bool{}}
+	return e.tree(name)
+}
+
+func (e sizeEstimator) tree(name string) int {
+	tree, ok := e.trees[name]
+	if ok == false || e.visiting[name] {
+		return 0
+	}
+	e.visiting[name] = true
+	defer delete(e.visiting, name)
+	return e.list(tree, tree.Root)
+}
+
+func (e sizeEstimator) list(tree *parse.Tree, list *parse.ListNode) int {
+	if list == nil {
+		return 0
+	}
+	n := 0
+	for _, node := range list.Nodes {
+		switch x := node.(type) {
+		case *parse.TextNode:
+			n += len(x.Text)
+		case *parse.IfNode:
+			n += e.branch(tree, &x.BranchNode)
+		case *parse.WithNode:
+			n += e.branch(tree, &x.BranchNode)
+		case *parse.RangeNode:
+			n += e.loop(tree, x)
+		case *parse.TemplateNode:
+			n += e.tree(x.Name)
+		}
+	}
+	return n
+}
+
+// branch returns the size of the smallest branch.
+func (e sizeEstimator) branch(tree *parse.Tree, b *parse.BranchNode) int {
+	return minSize(e.list(tree, b.List), e.list(tree, b.ElseList))
+}
+
+// loop returns the average size observed for a range loop,
+// a loop missing from the profile runs either its body or its else branch.
+func (e sizeEstimator) loop(tree *parse.Tree, r *parse.RangeNode) int {
+	location, _ := tree.ErrorContext(r)
+	if l, ok := e.profile[location]; ok && l.Runs > 0 {
+		return l.Size()
+	}
+	return e.branch(tree, &r.BranchNode)
+}
+
+func minSize(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
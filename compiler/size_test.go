@@ -0,0 +1,66 @@
+package compiler
+
+import (
+	"testing"
+	"text/template/parse"
+
+	texttemplate "github.com/mh-cbon/template-compiler/std/text/template"
+)
+
+type SizeTestData struct {
+	tplstr   string
+	profile  texttemplate.SizeProfile
+	expected int
+}
+
+func TestEstimateSize(t *testing.T) {
+
+	allTestData := []SizeTestData{
+		SizeTestData{
+			tplstr:   `hello{{.Name}}`,
+			expected: 5,
+		},
+		SizeTestData{
+			tplstr:   `a{{if .X}}bb{{else}}c{{end}}d`,
+			expected: 3,
+		},
+		SizeTestData{
+			tplstr:   `a{{if .X}}bb{{end}}{{with .Y}}cc{{else}}dd{{end}}`,
+			expected: 3,
+		},
+		SizeTestData{
+			tplstr:   "a\n{{range .Items}}item{{end}}!",
+			expected: 3,
+		},
+		SizeTestData{
+			tplstr:   "a\n{{range .Items}}item{{else}}none{{end}}!",
+			expected: 7,
+		},
+		SizeTestData{
+			tplstr: "a\n{{range .Items}}item{{end}}!",
+			profile: texttemplate.SizeProfile{
+				"t:2:8": &texttemplate.LoopSize{Runs: 2, Iterations: 6, Bytes: 24},
+			},
+			expected: 15,
+		},
+		SizeTestData{
+			tplstr:   `{{define "x"}}xx{{end}}a{{template "x"}}{{template "y"}}`,
+			expected: 3,
+		},
+		SizeTestData{
+			tplstr:   `{{define "r"}}r{{template "r"}}{{end}}{{template "r"}}`,
+			expected: 1,
+		},
+	}
+
+	for i, testData := range allTestData {
+		trees, err := parse.Parse("t", testData.tplstr, "", "")
+		if err != nil {
+			t.Errorf("Test(%v): Expected to parse the template, but got an error=%v", i, err)
+			continue
+		}
+		if got := estimateSize(trees, "t", testData.profile); got != testData.expected {
+			t.Errorf("Test(%v): Unexpected size estimation expected=%v, got=%v", i, testData.expected, got)
+		}
+	}
+}
@@ -6,36 +6,64 @@ import (
 	"io"
 	"github.com/mh-cbon/template-compiler/std/text/template/parse"
 	aliasdata "github.com/mh-cbon/template-compiler/demo/data"
-	"text/template"
-	aliastemplate "github.com/mh-cbon/template-compiler/std/html/template"
-	"bytes"
+	"github.com/mh-cbon/template-compiler/std/html/template"
 	"fmt"
+	aliastemplate "github.com/mh-cbon/template-compiler/std/text/template"
+	"context"
 )
 
-var builtin6 = []byte("\n  <ul>\n  ")
-var builtin10 = []byte("\nNo items!\n")
-var builtin2 = []byte("\n")
-var builtin4 = []byte("Hello")
-var builtin3 = []byte(" World!\n")
-var builtin5 = []byte("This is a template!\n\n")
-var builtin7 = []byte("\n    <li>")
-var builtin8 = []byte("</li>\n  ")
-var builtin9 = []byte("\n  </ul>\n")
-var builtin11 = []byte("hello!")
+var builtin8 = []byte("\n  <ul>\n  ")
+var builtin12 = []byte("\nNo items!\n")
+var builtin4 = []byte("\n")
+var builtin2 = []byte("\n\n")
+var builtin3 = []byte("\n4\n4\n")
+var builtin6 = []byte("Hello")
+var builtin5 = []byte(" World!\n")
+var builtin7 = []byte("This is a template!\n\n")
+var builtin9 = []byte("\n    <li>")
+var builtin10 = []byte("</li>\n  ")
+var builtin11 = []byte("\n  </ul>\n")
+var builtin13 = []byte("hello!")
 var builtin0 = []byte("Hello from a!\n")
 var builtin1 = []byte("Hello from b!\n")
 
 
 func init () {
   compiledTemplates.Add("a.tpl", fnaTpl)
+  compiledTemplates.AddAppend("a.tpl", fnaTplAppend)
+  compiledTemplates.AddContext("a.tpl", fnaTplContext)
+  compiledTemplates.SetSizeHint("a.tpl", 14)
   compiledTemplates.Add("b.tpl", fnbTpl)
+  compiledTemplates.AddAppend("b.tpl", fnbTplAppend)
+  compiledTemplates.AddContext("b.tpl", fnbTplContext)
+  compiledTemplates.SetSizeHint("b.tpl", 14)
   compiledTemplates.Add("c.tpl", fncTpl)
+  compiledTemplates.AddAppend("c.tpl", fncTplAppend)
+  compiledTemplates.AddContext("c.tpl", fncTplContext)
+  compiledTemplates.SetSizeHint("c.tpl", 14)
   compiledTemplates.Add("d.tpl", fndTpl)
+  compiledTemplates.AddAppend("d.tpl", fndTplAppend)
+  compiledTemplates.AddContext("d.tpl", fndTplContext)
+  compiledTemplates.SetSizeHint("d.tpl", 14)
   compiledTemplates.Add("tt", fndTplTt)
+  compiledTemplates.AddAppend("tt", fndTplTtAppend)
+  compiledTemplates.AddContext("tt", fndTplTtContext)
+  compiledTemplates.SetSizeHint("tt", 5)
   compiledTemplates.Add("e.tpl", fneTpl)
+  compiledTemplates.AddAppend("e.tpl", fneTplAppend)
+  compiledTemplates.AddContext("e.tpl", fneTplContext)
+  compiledTemplates.SetSizeHint("e.tpl", 33)
   compiledTemplates.Add("f.tpl", fnfTpl)
+  compiledTemplates.AddAppend("f.tpl", fnfTplAppend)
+  compiledTemplates.AddContext("f.tpl", fnfTplContext)
+  compiledTemplates.SetSizeHint("f.tpl", 33)
   compiledTemplates.Add("embed", fnnotafileEmbed)
+  compiledTemplates.AddAppend("embed", fnnotafileEmbedAppend)
+  compiledTemplates.AddContext("embed", fnnotafileEmbedContext)
   compiledTemplates.Add("notafile", fnnotafile)
+  compiledTemplates.AddAppend("notafile", fnnotafileAppend)
+  compiledTemplates.AddContext("notafile", fnnotafileContext)
+  compiledTemplates.SetSizeHint("notafile", 6)
   tpl3X0 := compiledTemplates.MustGet("d.tpl")
   tpl3Y0 := compiledTemplates.MustGet("tt")
   tpl3X0, _ = tpl3X0.Compiled(tpl3Y0)
@@ -47,216 +75,877 @@ func init () {
 }
 
 func fnaTpl(t parse.Templater, w io.Writer, indata interface{}) error {
+//line templates/a.tpl:1:1
 	if _, werr := w.Write(builtin0); werr != nil {
-		return werr
+		return aliastemplate.NewExecError("a.tpl", "a.tpl:1:0", "Hello from a!\n", werr)
 	}
 	return nil
 }
+//line gen.go:85
+
+func fnaTplAppend(t parse.Templater, dst []byte, indata interface{}) ([]byte, error) {
+//line templates/a.tpl:1:1
+	dst = append(dst, builtin0...)
+	return dst, nil
+}
+//line gen.go:92
 
 func fnbTpl(t parse.Templater, w io.Writer, indata interface{}) error {
+//line templates/b.tpl:1:1
 	if _, werr := w.Write(builtin1); werr != nil {
-		return werr
+		return aliastemplate.NewExecError("b.tpl", "b.tpl:1:0", "Hello from b!\n", werr)
 	}
 	return nil
 }
+//line gen.go:101
+
+func fnbTplAppend(t parse.Templater, dst []byte, indata interface{}) ([]byte, error) {
+//line templates/b.tpl:1:1
+	dst = append(dst, builtin1...)
+	return dst, nil
+}
+//line gen.go:108
 
 func fncTpl(t parse.Templater, w io.Writer, indata interface{}) error {
-	var bw bytes.Buffer
 	var data aliasdata.MyTemplateData
 	if d, ok := indata.(aliasdata.MyTemplateData); ok {
 		data = d
+	} else if indata != nil {
+		return fmt.Errorf("template: c.tpl: unexpected data type %T, wants data.MyTemplateData", indata)
 	}
-	var tplZ int = 4
+//line templates/c.tpl:1:12
 	if _, werr := w.Write(builtin2); werr != nil {
-		return werr
-	}
-	var tplA string = template.HTMLEscaper(tplZ)
-	if _, werr := w.Write(builtin2); werr != nil {
-		return werr
+		return aliastemplate.NewExecError("c.tpl", "c.tpl:1:11", "\n\n", werr)
 	}
+//line templates/c.tpl:3:3
 	var tplY string = data.Some
-	if _, werr := w.Write(builtin2); werr != nil {
-		return werr
-	}
-	var var0 string = aliastemplate.HTMLEscaper(tplZ)
-	if _, werr := io.WriteString(w, var0); werr != nil {
-		return werr
-	}
-	if _, werr := w.Write(builtin2); werr != nil {
-		return werr
+//line templates/c.tpl:3:16
+	if _, werr := w.Write(builtin3); werr != nil {
+		return aliastemplate.NewExecError("c.tpl", "c.tpl:3:15", "\n4\n4\n", werr)
 	}
-	bw.WriteString(tplA)
-	template.HTMLEscape(w, bw.Bytes())
-	bw.Reset()
-	if _, werr := w.Write(builtin2); werr != nil {
-		return werr
+//line templates/c.tpl:6:3
+	if werr := template.HTMLEscaperTo(w, tplY); werr != nil {
+		return aliastemplate.NewExecError("c.tpl", "c.tpl:6:2", "{{$y}}", werr)
 	}
-	bw.WriteString(tplY)
-	template.HTMLEscape(w, bw.Bytes())
-	bw.Reset()
-	if _, werr := w.Write(builtin2); werr != nil {
-		return werr
+//line templates/c.tpl:6:7
+	if _, werr := w.Write(builtin4); werr != nil {
+		return aliastemplate.NewExecError("c.tpl", "c.tpl:6:6", "\n", werr)
 	}
+//line templates/c.tpl:7:3
 	var var4 string = data.Some
-	bw.WriteString(var4)
-	template.HTMLEscape(w, bw.Bytes())
-	bw.Reset()
-	if _, werr := w.Write(builtin2); werr != nil {
-		return werr
+//line templates/c.tpl:7:3
+	if werr := template.HTMLEscaperTo(w, var4); werr != nil {
+		return aliastemplate.NewExecError("c.tpl", "c.tpl:7:2", "{{.Some}}", werr)
+	}
+//line templates/c.tpl:7:10
+	if _, werr := w.Write(builtin4); werr != nil {
+		return aliastemplate.NewExecError("c.tpl", "c.tpl:7:9", "\n", werr)
 	}
+//line templates/c.tpl:8:3
 	var tplP string = data.Some
-	if _, werr := w.Write(builtin2); werr != nil {
-		return werr
+//line templates/c.tpl:8:16
+	if _, werr := w.Write(builtin4); werr != nil {
+		return aliastemplate.NewExecError("c.tpl", "c.tpl:8:15", "\n", werr)
 	}
+//line templates/c.tpl:9:3
 	var var6 string = data.Some
-	bw.WriteString(var6)
-	template.HTMLEscape(w, bw.Bytes())
-	bw.Reset()
-	if _, werr := w.Write(builtin2); werr != nil {
-		return werr
+//line templates/c.tpl:9:3
+	if werr := template.HTMLEscaperTo(w, var6); werr != nil {
+		return aliastemplate.NewExecError("c.tpl", "c.tpl:9:2", "{{.Some}}", werr)
 	}
+//line templates/c.tpl:9:10
+	if _, werr := w.Write(builtin4); werr != nil {
+		return aliastemplate.NewExecError("c.tpl", "c.tpl:9:9", "\n", werr)
+	}
+//line templates/c.tpl:10:3
 	var var8 string = data.Some
-	bw.WriteString(var8)
-	template.HTMLEscape(w, bw.Bytes())
-	bw.Reset()
-	if _, werr := w.Write(builtin2); werr != nil {
-		return werr
+//line templates/c.tpl:10:3
+	if werr := template.HTMLEscaperTo(w, var8); werr != nil {
+		return aliastemplate.NewExecError("c.tpl", "c.tpl:10:2", "{{.Some}}", werr)
 	}
+//line templates/c.tpl:10:10
+	if _, werr := w.Write(builtin4); werr != nil {
+		return aliastemplate.NewExecError("c.tpl", "c.tpl:10:9", "\n", werr)
+	}
+//line templates/c.tpl:11:3
 	var var10 string = data.Some
-	bw.WriteString(var10)
-	template.HTMLEscape(w, bw.Bytes())
-	bw.Reset()
-	if _, werr := w.Write(builtin2); werr != nil {
-		return werr
+//line templates/c.tpl:11:3
+	if werr := template.HTMLEscaperTo(w, var10); werr != nil {
+		return aliastemplate.NewExecError("c.tpl", "c.tpl:11:2", "{{.Some}}", werr)
 	}
-	bw.WriteString(tplP)
-	template.HTMLEscape(w, bw.Bytes())
-	bw.Reset()
-	if _, werr := w.Write(builtin2); werr != nil {
-		return werr
+//line templates/c.tpl:11:10
+	if _, werr := w.Write(builtin4); werr != nil {
+		return aliastemplate.NewExecError("c.tpl", "c.tpl:11:9", "\n", werr)
+	}
+//line templates/c.tpl:12:3
+	if werr := template.HTMLEscaperTo(w, tplP); werr != nil {
+		return aliastemplate.NewExecError("c.tpl", "c.tpl:12:2", "{{$p}}", werr)
+	}
+//line templates/c.tpl:12:7
+	if _, werr := w.Write(builtin4); werr != nil {
+		return aliastemplate.NewExecError("c.tpl", "c.tpl:12:6", "\n", werr)
 	}
 	return nil
 }
+//line gen.go:191
+
+func fncTplAppend(t parse.Templater, dst []byte, indata interface{}) ([]byte, error) {
+	var data aliasdata.MyTemplateData
+	if d, ok := indata.(aliasdata.MyTemplateData); ok {
+		data = d
+	} else if indata != nil {
+		return dst, fmt.Errorf("template: c.tpl: unexpected data type %T, wants data.MyTemplateData", indata)
+	}
+//line templates/c.tpl:1:12
+	dst = append(dst, builtin2...)
+//line templates/c.tpl:3:3
+	var tplY string = data.Some
+//line templates/c.tpl:3:16
+	dst = append(dst, builtin3...)
+//line templates/c.tpl:6:3
+	dst = template.HTMLEscaperAppend(dst, tplY)
+//line templates/c.tpl:6:7
+	dst = append(dst, builtin4...)
+//line templates/c.tpl:7:3
+	var var4 string = data.Some
+//line templates/c.tpl:7:3
+	dst = template.HTMLEscaperAppend(dst, var4)
+//line templates/c.tpl:7:10
+	dst = append(dst, builtin4...)
+//line templates/c.tpl:8:3
+	var tplP string = data.Some
+//line templates/c.tpl:8:16
+	dst = append(dst, builtin4...)
+//line templates/c.tpl:9:3
+	var var6 string = data.Some
+//line templates/c.tpl:9:3
+	dst = template.HTMLEscaperAppend(dst, var6)
+//line templates/c.tpl:9:10
+	dst = append(dst, builtin4...)
+//line templates/c.tpl:10:3
+	var var8 string = data.Some
+//line templates/c.tpl:10:3
+	dst = template.HTMLEscaperAppend(dst, var8)
+//line templates/c.tpl:10:10
+	dst = append(dst, builtin4...)
+//line templates/c.tpl:11:3
+	var var10 string = data.Some
+//line templates/c.tpl:11:3
+	dst = template.HTMLEscaperAppend(dst, var10)
+//line templates/c.tpl:11:10
+	dst = append(dst, builtin4...)
+//line templates/c.tpl:12:3
+	dst = template.HTMLEscaperAppend(dst, tplP)
+//line templates/c.tpl:12:7
+	dst = append(dst, builtin4...)
+	return dst, nil
+}
+//line gen.go:244
 
 func fndTpl(t parse.Templater, w io.Writer, indata interface{}) error {
-	if _, werr := w.Write(builtin2); werr != nil {
-		return werr
+	observer, _ := t.(parse.Observer)
+//line templates/d.tpl:1:28
+	if _, werr := w.Write(builtin4); werr != nil {
+		return aliastemplate.NewExecError("d.tpl", "d.tpl:1:27", "\n", werr)
 	}
-	if werr := t.ExecuteTemplate(w, "tt", nil); werr != nil {
-		return werr
+//line templates/d.tpl:2:12
+	if observer != nil {
+		if werr := t.ExecuteTemplate(w, "tt", nil); werr != nil {
+			return werr
+		}
+	} else {
+		if werr := fndTplTt(t, w, nil); werr != nil {
+			return werr
+		}
 	}
-	if _, werr := w.Write(builtin3); werr != nil {
-		return werr
+//line templates/d.tpl:2:18
+	if _, werr := w.Write(builtin5); werr != nil {
+		return aliastemplate.NewExecError("d.tpl", "d.tpl:2:17", " World!\n", werr)
 	}
 	return nil
 }
+//line gen.go:268
+
+func fndTplAppend(t parse.Templater, dst []byte, indata interface{}) ([]byte, error) {
+	w := (*aliastemplate.SliceWriter)(&dst)
+	observer, _ := t.(parse.Observer)
+//line templates/d.tpl:1:28
+	dst = append(dst, builtin4...)
+//line templates/d.tpl:2:12
+	if observer != nil {
+		if werr := t.ExecuteTemplate(w, "tt", nil); werr != nil {
+			return dst, werr
+		}
+	} else {
+		if werr := fndTplTt(t, w, nil); werr != nil {
+			return dst, werr
+		}
+	}
+//line templates/d.tpl:2:18
+	dst = append(dst, builtin5...)
+	return dst, nil
+}
+//line gen.go:289
 
 func fndTplTt(t parse.Templater, w io.Writer, indata interface{}) error {
-	if _, werr := w.Write(builtin4); werr != nil {
-		return werr
+//line templates/d.tpl:1:16
+	if _, werr := w.Write(builtin6); werr != nil {
+		return aliastemplate.NewExecError("tt", "d.tpl:1:15", "Hello", werr)
 	}
 	return nil
 }
+//line gen.go:298
+
+func fndTplTtAppend(t parse.Templater, dst []byte, indata interface{}) ([]byte, error) {
+//line templates/d.tpl:1:16
+	dst = append(dst, builtin6...)
+	return dst, nil
+}
+//line gen.go:305
 
 func fneTpl(t parse.Templater, w io.Writer, indata interface{}) error {
-	var bw bytes.Buffer
+	limiter, _ := t.(parse.Limiter)
 	var data aliasdata.MyTemplateData
 	if d, ok := indata.(aliasdata.MyTemplateData); ok {
 		data = d
+	} else if indata != nil {
+		return fmt.Errorf("template: e.tpl: unexpected data type %T, wants data.MyTemplateData", indata)
 	}
-	if _, werr := w.Write(builtin5); werr != nil {
-		return werr
+//line templates/e.tpl:1:1
+	if _, werr := w.Write(builtin7); werr != nil {
+		return aliastemplate.NewExecError("e.tpl", "e.tpl:1:0", "This is a template!\n...", werr)
 	}
+//line templates/e.tpl:3:16
 	var var2 []string = data.Items
+//line templates/e.tpl:3:12
 	var var1 int = len(var2)
+//line templates/e.tpl:3:6
 	var var0 bool = 0 != var1
+//line templates/e.tpl:3:6
 	if var0 {
-		if _, werr := w.Write(builtin6); werr != nil {
-			return werr
+//line templates/e.tpl:3:25
+		if _, werr := w.Write(builtin8); werr != nil {
+			return aliastemplate.NewExecError("e.tpl", "e.tpl:3:24", "\n  <ul>\n  ", werr)
 		}
+//line templates/e.tpl:5:11
 		var var3 []string = data.Items
+//line templates/e.tpl:5:11
+		var iter0 int
 		for _, iterable := range var3 {
-			if _, werr := w.Write(builtin7); werr != nil {
-				return werr
+			if limiter != nil {
+				iter0++
+				if err := limiter.CheckRange("e.tpl", iter0); err != nil {
+					return err
+				}
 			}
-			bw.WriteString(iterable)
-			template.HTMLEscape(w, bw.Bytes())
-			bw.Reset()
-			if _, werr := w.Write(builtin8); werr != nil {
-				return werr
+//line templates/e.tpl:5:19
+			if _, werr := w.Write(builtin9); werr != nil {
+				return aliastemplate.NewExecError("e.tpl", "e.tpl:5:18", "\n    <li>", werr)
+			}
+//line templates/e.tpl:6:11
+			if werr := template.HTMLEscaperTo(w, iterable); werr != nil {
+				return aliastemplate.NewExecError("e.tpl", "e.tpl:6:10", "{{.}}", werr)
+			}
+//line templates/e.tpl:6:14
+			if _, werr := w.Write(builtin10); werr != nil {
+				return aliastemplate.NewExecError("e.tpl", "e.tpl:6:13", "</li>\n  ", werr)
 			}
 		}
-		if _, werr := w.Write(builtin9); werr != nil {
-			return werr
+//line templates/e.tpl:7:10
+		if _, werr := w.Write(builtin11); werr != nil {
+			return aliastemplate.NewExecError("e.tpl", "e.tpl:7:9", "\n  </ul>\n", werr)
 		}
 	} else {
-		if _, werr := w.Write(builtin10); werr != nil {
-			return werr
+//line templates/e.tpl:9:9
+		if _, werr := w.Write(builtin12); werr != nil {
+			return aliastemplate.NewExecError("e.tpl", "e.tpl:9:8", "\nNo items!\n", werr)
 		}
 	}
-	if _, werr := w.Write(builtin2); werr != nil {
-		return werr
+//line templates/e.tpl:11:8
+	if _, werr := w.Write(builtin4); werr != nil {
+		return aliastemplate.NewExecError("e.tpl", "e.tpl:11:7", "\n", werr)
 	}
 	return nil
 }
+//line gen.go:371
+
+func fneTplAppend(t parse.Templater, dst []byte, indata interface{}) ([]byte, error) {
+	limiter, _ := t.(parse.Limiter)
+	var data aliasdata.MyTemplateData
+	if d, ok := indata.(aliasdata.MyTemplateData); ok {
+		data = d
+	} else if indata != nil {
+		return dst, fmt.Errorf("template: e.tpl: unexpected data type %T, wants data.MyTemplateData", indata)
+	}
+//line templates/e.tpl:1:1
+	dst = append(dst, builtin7...)
+//line templates/e.tpl:3:16
+	var var2 []string = data.Items
+//line templates/e.tpl:3:12
+	var var1 int = len(var2)
+//line templates/e.tpl:3:6
+	var var0 bool = 0 != var1
+//line templates/e.tpl:3:6
+	if var0 {
+//line templates/e.tpl:3:25
+		dst = append(dst, builtin8...)
+//line templates/e.tpl:5:11
+		var var3 []string = data.Items
+//line templates/e.tpl:5:11
+		var iter0 int
+		for _, iterable := range var3 {
+			if limiter != nil {
+				iter0++
+				if err := limiter.CheckRange("e.tpl", iter0); err != nil {
+					return dst, err
+				}
+			}
+//line templates/e.tpl:5:19
+			dst = append(dst, builtin9...)
+//line templates/e.tpl:6:11
+			dst = template.HTMLEscaperAppend(dst, iterable)
+//line templates/e.tpl:6:14
+			dst = append(dst, builtin10...)
+		}
+//line templates/e.tpl:7:10
+		dst = append(dst, builtin11...)
+	} else {
+//line templates/e.tpl:9:9
+		dst = append(dst, builtin12...)
+	}
+//line templates/e.tpl:11:8
+	dst = append(dst, builtin4...)
+	return dst, nil
+}
+//line gen.go:421
 
 func fnfTpl(t parse.Templater, w io.Writer, indata interface{}) error {
-	var bw bytes.Buffer
+	limiter, _ := t.(parse.Limiter)
 	var data aliasdata.MyTemplateData
 	if d, ok := indata.(aliasdata.MyTemplateData); ok {
 		data = d
+	} else if indata != nil {
+		return fmt.Errorf("template: f.tpl: unexpected data type %T, wants data.MyTemplateData", indata)
 	}
-	if _, werr := w.Write(builtin5); werr != nil {
-		return werr
+//line templates/f.tpl:1:1
+	if _, werr := w.Write(builtin7); werr != nil {
+		return aliastemplate.NewExecError("f.tpl", "f.tpl:1:0", "This is a template!\n...", werr)
 	}
+//line templates/f.tpl:3:16
 	var var2 []string = data.MethodItems()
+//line templates/f.tpl:3:12
 	var var1 int = len(var2)
+//line templates/f.tpl:3:6
 	var var0 bool = 0 != var1
+//line templates/f.tpl:3:6
 	if var0 {
-		if _, werr := w.Write(builtin6); werr != nil {
-			return werr
+//line templates/f.tpl:3:31
+		if _, werr := w.Write(builtin8); werr != nil {
+			return aliastemplate.NewExecError("f.tpl", "f.tpl:3:30", "\n  <ul>\n  ", werr)
 		}
+//line templates/f.tpl:5:11
 		var var3 []string = data.MethodItems()
+//line templates/f.tpl:5:11
+		var iter0 int
 		for _, iterable := range var3 {
-			if _, werr := w.Write(builtin7); werr != nil {
-				return werr
+			if limiter != nil {
+				iter0++
+				if err := limiter.CheckRange("f.tpl", iter0); err != nil {
+					return err
+				}
+			}
+//line templates/f.tpl:5:25
+			if _, werr := w.Write(builtin9); werr != nil {
+				return aliastemplate.NewExecError("f.tpl", "f.tpl:5:24", "\n    <li>", werr)
+			}
+//line templates/f.tpl:6:11
+			if werr := template.HTMLEscaperTo(w, iterable); werr != nil {
+				return aliastemplate.NewExecError("f.tpl", "f.tpl:6:10", "{{.}}", werr)
 			}
-			bw.WriteString(iterable)
-			template.HTMLEscape(w, bw.Bytes())
-			bw.Reset()
-			if _, werr := w.Write(builtin8); werr != nil {
-				return werr
+//line templates/f.tpl:6:14
+			if _, werr := w.Write(builtin10); werr != nil {
+				return aliastemplate.NewExecError("f.tpl", "f.tpl:6:13", "</li>\n  ", werr)
 			}
 		}
-		if _, werr := w.Write(builtin9); werr != nil {
-			return werr
+//line templates/f.tpl:7:10
+		if _, werr := w.Write(builtin11); werr != nil {
+			return aliastemplate.NewExecError("f.tpl", "f.tpl:7:9", "\n  </ul>\n", werr)
 		}
 	} else {
-		if _, werr := w.Write(builtin10); werr != nil {
-			return werr
+//line templates/f.tpl:9:9
+		if _, werr := w.Write(builtin12); werr != nil {
+			return aliastemplate.NewExecError("f.tpl", "f.tpl:9:8", "\nNo items!\n", werr)
 		}
 	}
-	if _, werr := w.Write(builtin2); werr != nil {
-		return werr
+//line templates/f.tpl:11:8
+	if _, werr := w.Write(builtin4); werr != nil {
+		return aliastemplate.NewExecError("f.tpl", "f.tpl:11:7", "\n", werr)
 	}
 	return nil
 }
+//line gen.go:487
+
+func fnfTplAppend(t parse.Templater, dst []byte, indata interface{}) ([]byte, error) {
+	limiter, _ := t.(parse.Limiter)
+	var data aliasdata.MyTemplateData
+	if d, ok := indata.(aliasdata.MyTemplateData); ok {
+		data = d
+	} else if indata != nil {
+		return dst, fmt.Errorf("template: f.tpl: unexpected data type %T, wants data.MyTemplateData", indata)
+	}
+//line templates/f.tpl:1:1
+	dst = append(dst, builtin7...)
+//line templates/f.tpl:3:16
+	var var2 []string = data.MethodItems()
+//line templates/f.tpl:3:12
+	var var1 int = len(var2)
+//line templates/f.tpl:3:6
+	var var0 bool = 0 != var1
+//line templates/f.tpl:3:6
+	if var0 {
+//line templates/f.tpl:3:31
+		dst = append(dst, builtin8...)
+//line templates/f.tpl:5:11
+		var var3 []string = data.MethodItems()
+//line templates/f.tpl:5:11
+		var iter0 int
+		for _, iterable := range var3 {
+			if limiter != nil {
+				iter0++
+				if err := limiter.CheckRange("f.tpl", iter0); err != nil {
+					return dst, err
+				}
+			}
+//line templates/f.tpl:5:25
+			dst = append(dst, builtin9...)
+//line templates/f.tpl:6:11
+			dst = template.HTMLEscaperAppend(dst, iterable)
+//line templates/f.tpl:6:14
+			dst = append(dst, builtin10...)
+		}
+//line templates/f.tpl:7:10
+		dst = append(dst, builtin11...)
+	} else {
+//line templates/f.tpl:9:9
+		dst = append(dst, builtin12...)
+	}
+//line templates/f.tpl:11:8
+	dst = append(dst, builtin4...)
+	return dst, nil
+}
+//line gen.go:537
 
 func fnnotafileEmbed(t parse.Templater, w io.Writer, indata interface{}) error {
 	var data aliasdata.MyTemplateData
 	if d, ok := indata.(aliasdata.MyTemplateData); ok {
 		data = d
+	} else if indata != nil {
+		return fmt.Errorf("template: embed: unexpected data type %T, wants data.MyTemplateData", indata)
 	}
 	if _, werr := fmt.Fprintf(w, "%v", data); werr != nil {
-		return werr
+		return aliastemplate.NewExecError("embed", "notafile:1:26", "{{.}}", werr)
 	}
 	return nil
 }
 
+func fnnotafileEmbedAppend(t parse.Templater, dst []byte, indata interface{}) ([]byte, error) {
+	var data aliasdata.MyTemplateData
+	if d, ok := indata.(aliasdata.MyTemplateData); ok {
+		data = d
+	} else if indata != nil {
+		return dst, fmt.Errorf("template: embed: unexpected data type %T, wants data.MyTemplateData", indata)
+	}
+	dst = fmt.Appendf(dst, "%v", data)
+	return dst, nil
+}
+
 func fnnotafile(t parse.Templater, w io.Writer, indata interface{}) error {
-	if _, werr := w.Write(builtin11); werr != nil {
-		return werr
+	if _, werr := w.Write(builtin13); werr != nil {
+		return aliastemplate.NewExecError("notafile", "notafile:1:0", "hello!", werr)
+	}
+	return nil
+}
+
+func fnnotafileAppend(t parse.Templater, dst []byte, indata interface{}) ([]byte, error) {
+	dst = append(dst, builtin13...)
+	return dst, nil
+}
+
+func fnaTplContext(ctx context.Context, t parse.Templater, w io.Writer, indata interface{}) error {
+//line templates/a.tpl:1:1
+	if _, werr := w.Write(builtin0); werr != nil {
+		return aliastemplate.NewExecError("a.tpl", "a.tpl:1:0", "Hello from a!\n", werr)
+	}
+	return nil
+}
+//line gen.go:582
+
+func fnbTplContext(ctx context.Context, t parse.Templater, w io.Writer, indata interface{}) error {
+//line templates/b.tpl:1:1
+	if _, werr := w.Write(builtin1); werr != nil {
+		return aliastemplate.NewExecError("b.tpl", "b.tpl:1:0", "Hello from b!\n", werr)
+	}
+	return nil
+}
+//line gen.go:591
+
+func fncTplContext(ctx context.Context, t parse.Templater, w io.Writer, indata interface{}) error {
+	var data aliasdata.MyTemplateData
+	if d, ok := indata.(aliasdata.MyTemplateData); ok {
+		data = d
+	} else if indata != nil {
+		return fmt.Errorf("template: c.tpl: unexpected data type %T, wants data.MyTemplateData", indata)
+	}
+//line templates/c.tpl:1:12
+	if _, werr := w.Write(builtin2); werr != nil {
+		return aliastemplate.NewExecError("c.tpl", "c.tpl:1:11", "\n\n", werr)
+	}
+//line templates/c.tpl:3:3
+	var tplY string = data.Some
+//line templates/c.tpl:3:16
+	if _, werr := w.Write(builtin3); werr != nil {
+		return aliastemplate.NewExecError("c.tpl", "c.tpl:3:15", "\n4\n4\n", werr)
+	}
+//line templates/c.tpl:6:3
+	if werr := template.HTMLEscaperTo(w, tplY); werr != nil {
+		return aliastemplate.NewExecError("c.tpl", "c.tpl:6:2", "{{$y}}", werr)
+	}
+//line templates/c.tpl:6:7
+	if _, werr := w.Write(builtin4); werr != nil {
+		return aliastemplate.NewExecError("c.tpl", "c.tpl:6:6", "\n", werr)
+	}
+//line templates/c.tpl:7:3
+	var var4 string = data.Some
+//line templates/c.tpl:7:3
+	if werr := template.HTMLEscaperTo(w, var4); werr != nil {
+		return aliastemplate.NewExecError("c.tpl", "c.tpl:7:2", "{{.Some}}", werr)
+	}
+//line templates/c.tpl:7:10
+	if _, werr := w.Write(builtin4); werr != nil {
+		return aliastemplate.NewExecError("c.tpl", "c.tpl:7:9", "\n", werr)
+	}
+//line templates/c.tpl:8:3
+	var tplP string = data.Some
+//line templates/c.tpl:8:16
+	if _, werr := w.Write(builtin4); werr != nil {
+		return aliastemplate.NewExecError("c.tpl", "c.tpl:8:15", "\n", werr)
+	}
+//line templates/c.tpl:9:3
+	var var6 string = data.Some
+//line templates/c.tpl:9:3
+	if werr := template.HTMLEscaperTo(w, var6); werr != nil {
+		return aliastemplate.NewExecError("c.tpl", "c.tpl:9:2", "{{.Some}}", werr)
+	}
+//line templates/c.tpl:9:10
+	if _, werr := w.Write(builtin4); werr != nil {
+		return aliastemplate.NewExecError("c.tpl", "c.tpl:9:9", "\n", werr)
+	}
+//line templates/c.tpl:10:3
+	var var8 string = data.Some
+//line templates/c.tpl:10:3
+	if werr := template.HTMLEscaperTo(w, var8); werr != nil {
+		return aliastemplate.NewExecError("c.tpl", "c.tpl:10:2", "{{.Some}}", werr)
+	}
+//line templates/c.tpl:10:10
+	if _, werr := w.Write(builtin4); werr != nil {
+		return aliastemplate.NewExecError("c.tpl", "c.tpl:10:9", "\n", werr)
+	}
+//line templates/c.tpl:11:3
+	var var10 string = data.Some
+//line templates/c.tpl:11:3
+	if werr := template.HTMLEscaperTo(w, var10); werr != nil {
+		return aliastemplate.NewExecError("c.tpl", "c.tpl:11:2", "{{.Some}}", werr)
+	}
+//line templates/c.tpl:11:10
+	if _, werr := w.Write(builtin4); werr != nil {
+		return aliastemplate.NewExecError("c.tpl", "c.tpl:11:9", "\n", werr)
+	}
+//line templates/c.tpl:12:3
+	if werr := template.HTMLEscaperTo(w, tplP); werr != nil {
+		return aliastemplate.NewExecError("c.tpl", "c.tpl:12:2", "{{$p}}", werr)
+	}
+//line templates/c.tpl:12:7
+	if _, werr := w.Write(builtin4); werr != nil {
+		return aliastemplate.NewExecError("c.tpl", "c.tpl:12:6", "\n", werr)
 	}
 	return nil
 }
+//line gen.go:674
+
+func fndTplContext(ctx context.Context, t parse.Templater, w io.Writer, indata interface{}) error {
+	observer, _ := t.(parse.Observer)
+//line templates/d.tpl:1:28
+	if _, werr := w.Write(builtin4); werr != nil {
+		return aliastemplate.NewExecError("d.tpl", "d.tpl:1:27", "\n", werr)
+	}
+//line templates/d.tpl:2:12
+	if observer != nil {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if werr := t.ExecuteTemplateContext(ctx, w, "tt", nil); werr != nil {
+			return werr
+		}
+	} else {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if werr := fndTplTtContext(ctx, t, w, nil); werr != nil {
+			return werr
+		}
+	}
+//line templates/d.tpl:2:18
+	if _, werr := w.Write(builtin5); werr != nil {
+		return aliastemplate.NewExecError("d.tpl", "d.tpl:2:17", " World!\n", werr)
+	}
+	return nil
+}
+//line gen.go:704
+
+func fndTplTtContext(ctx context.Context, t parse.Templater, w io.Writer, indata interface{}) error {
+//line templates/d.tpl:1:16
+	if _, werr := w.Write(builtin6); werr != nil {
+		return aliastemplate.NewExecError("tt", "d.tpl:1:15", "Hello", werr)
+	}
+	return nil
+}
+//line gen.go:713
+
+func fneTplContext(ctx context.Context, t parse.Templater, w io.Writer, indata interface{}) error {
+	limiter, _ := t.(parse.Limiter)
+	var data aliasdata.MyTemplateData
+	if d, ok := indata.(aliasdata.MyTemplateData); ok {
+		data = d
+	} else if indata != nil {
+		return fmt.Errorf("template: e.tpl: unexpected data type %T, wants data.MyTemplateData", indata)
+	}
+//line templates/e.tpl:1:1
+	if _, werr := w.Write(builtin7); werr != nil {
+		return aliastemplate.NewExecError("e.tpl", "e.tpl:1:0", "This is a template!\n...", werr)
+	}
+//line templates/e.tpl:3:16
+	var var2 []string = data.Items
+//line templates/e.tpl:3:12
+	var var1 int = len(var2)
+//line templates/e.tpl:3:6
+	var var0 bool = 0 != var1
+//line templates/e.tpl:3:6
+	if var0 {
+//line templates/e.tpl:3:25
+		if _, werr := w.Write(builtin8); werr != nil {
+			return aliastemplate.NewExecError("e.tpl", "e.tpl:3:24", "\n  <ul>\n  ", werr)
+		}
+//line templates/e.tpl:5:11
+		var var3 []string = data.Items
+//line templates/e.tpl:5:11
+		var iter0 int
+		for _, iterable := range var3 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if limiter != nil {
+				iter0++
+				if err := limiter.CheckRange("e.tpl", iter0); err != nil {
+					return err
+				}
+			}
+//line templates/e.tpl:5:19
+			if _, werr := w.Write(builtin9); werr != nil {
+				return aliastemplate.NewExecError("e.tpl", "e.tpl:5:18", "\n    <li>", werr)
+			}
+//line templates/e.tpl:6:11
+			if werr := template.HTMLEscaperTo(w, iterable); werr != nil {
+				return aliastemplate.NewExecError("e.tpl", "e.tpl:6:10", "{{.}}", werr)
+			}
+//line templates/e.tpl:6:14
+			if _, werr := w.Write(builtin10); werr != nil {
+				return aliastemplate.NewExecError("e.tpl", "e.tpl:6:13", "</li>\n  ", werr)
+			}
+		}
+//line templates/e.tpl:7:10
+		if _, werr := w.Write(builtin11); werr != nil {
+			return aliastemplate.NewExecError("e.tpl", "e.tpl:7:9", "\n  </ul>\n", werr)
+		}
+	} else {
+//line templates/e.tpl:9:9
+		if _, werr := w.Write(builtin12); werr != nil {
+			return aliastemplate.NewExecError("e.tpl", "e.tpl:9:8", "\nNo items!\n", werr)
+		}
+	}
+//line templates/e.tpl:11:8
+	if _, werr := w.Write(builtin4); werr != nil {
+		return aliastemplate.NewExecError("e.tpl", "e.tpl:11:7", "\n", werr)
+	}
+	return nil
+}
+//line gen.go:782
+
+func fnfTplContext(ctx context.Context, t parse.Templater, w io.Writer, indata interface{}) error {
+	limiter, _ := t.(parse.Limiter)
+	var data aliasdata.MyTemplateData
+	if d, ok := indata.(aliasdata.MyTemplateData); ok {
+		data = d
+	} else if indata != nil {
+		return fmt.Errorf("template: f.tpl: unexpected data type %T, wants data.MyTemplateData", indata)
+	}
+//line templates/f.tpl:1:1
+	if _, werr := w.Write(builtin7); werr != nil {
+		return aliastemplate.NewExecError("f.tpl", "f.tpl:1:0", "This is a template!\n...", werr)
+	}
+//line templates/f.tpl:3:16
+	var var2 []string = data.MethodItems()
+//line templates/f.tpl:3:12
+	var var1 int = len(var2)
+//line templates/f.tpl:3:6
+	var var0 bool = 0 != var1
+//line templates/f.tpl:3:6
+	if var0 {
+//line templates/f.tpl:3:31
+		if _, werr := w.Write(builtin8); werr != nil {
+			return aliastemplate.NewExecError("f.tpl", "f.tpl:3:30", "\n  <ul>\n  ", werr)
+		}
+//line templates/f.tpl:5:11
+		var var3 []string = data.MethodItems()
+//line templates/f.tpl:5:11
+		var iter0 int
+		for _, iterable := range var3 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if limiter != nil {
+				iter0++
+				if err := limiter.CheckRange("f.tpl", iter0); err != nil {
+					return err
+				}
+			}
+//line templates/f.tpl:5:25
+			if _, werr := w.Write(builtin9); werr != nil {
+				return aliastemplate.NewExecError("f.tpl", "f.tpl:5:24", "\n    <li>", werr)
+			}
+//line templates/f.tpl:6:11
+			if werr := template.HTMLEscaperTo(w, iterable); werr != nil {
+				return aliastemplate.NewExecError("f.tpl", "f.tpl:6:10", "{{.}}", werr)
+			}
+//line templates/f.tpl:6:14
+			if _, werr := w.Write(builtin10); werr != nil {
+				return aliastemplate.NewExecError("f.tpl", "f.tpl:6:13", "</li>\n  ", werr)
+			}
+		}
+//line templates/f.tpl:7:10
+		if _, werr := w.Write(builtin11); werr != nil {
+			return aliastemplate.NewExecError("f.tpl", "f.tpl:7:9", "\n  </ul>\n", werr)
+		}
+	} else {
+//line templates/f.tpl:9:9
+		if _, werr := w.Write(builtin12); werr != nil {
+			return aliastemplate.NewExecError("f.tpl", "f.tpl:9:8", "\nNo items!\n", werr)
+		}
+	}
+//line templates/f.tpl:11:8
+	if _, werr := w.Write(builtin4); werr != nil {
+		return aliastemplate.NewExecError("f.tpl", "f.tpl:11:7", "\n", werr)
+	}
+	return nil
+}
+//line gen.go:851
+
+func fnnotafileEmbedContext(ctx context.Context, t parse.Templater, w io.Writer, indata interface{}) error {
+	var data aliasdata.MyTemplateData
+	if d, ok := indata.(aliasdata.MyTemplateData); ok {
+		data = d
+	} else if indata != nil {
+		return fmt.Errorf("template: embed: unexpected data type %T, wants data.MyTemplateData", indata)
+	}
+	if _, werr := fmt.Fprintf(w, "%v", data); werr != nil {
+		return aliastemplate.NewExecError("embed", "notafile:1:26", "{{.}}", werr)
+	}
+	return nil
+}
+
+func fnnotafileContext(ctx context.Context, t parse.Templater, w io.Writer, indata interface{}) error {
+	if _, werr := w.Write(builtin13); werr != nil {
+		return aliastemplate.NewExecError("notafile", "notafile:1:0", "hello!", werr)
+	}
+	return nil
+}
+
+// RenderATpl renders the template "a.tpl".
+func RenderATpl(w io.Writer, data aliasdata.MyTemplateData) error {
+	return compiledTemplates.MustGet("a.tpl").Execute(w, data)
+}
+
+// AppendRenderATpl appends the template "a.tpl" to dst.
+func AppendRenderATpl(dst []byte, data aliasdata.MyTemplateData) ([]byte, error) {
+	return compiledTemplates.MustGet("a.tpl").AppendExecute(dst, data)
+}
+
+// RenderBTpl renders the template "b.tpl".
+func RenderBTpl(w io.Writer, data aliasdata.MyTemplateData) error {
+	return compiledTemplates.MustGet("b.tpl").Execute(w, data)
+}
+
+// AppendRenderBTpl appends the template "b.tpl" to dst.
+func AppendRenderBTpl(dst []byte, data aliasdata.MyTemplateData) ([]byte, error) {
+	return compiledTemplates.MustGet("b.tpl").AppendExecute(dst, data)
+}
+
+// RenderCTpl renders the template "c.tpl".
+func RenderCTpl(w io.Writer, data aliasdata.MyTemplateData) error {
+	return compiledTemplates.MustGet("c.tpl").Execute(w, data)
+}
+
+// AppendRenderCTpl appends the template "c.tpl" to dst.
+func AppendRenderCTpl(dst []byte, data aliasdata.MyTemplateData) ([]byte, error) {
+	return compiledTemplates.MustGet("c.tpl").AppendExecute(dst, data)
+}
+
+// RenderDTpl renders the template "d.tpl".
+func RenderDTpl(w io.Writer, data aliasdata.MyTemplateData) error {
+	return compiledTemplates.MustGet("d.tpl").Execute(w, data)
+}
+
+// AppendRenderDTpl appends the template "d.tpl" to dst.
+func AppendRenderDTpl(dst []byte, data aliasdata.MyTemplateData) ([]byte, error) {
+	return compiledTemplates.MustGet("d.tpl").AppendExecute(dst, data)
+}
+
+// RenderTt renders the template "tt".
+func RenderTt(w io.Writer, data aliasdata.MyTemplateData) error {
+	return compiledTemplates.MustGet("tt").Execute(w, data)
+}
+
+// AppendRenderTt appends the template "tt" to dst.
+func AppendRenderTt(dst []byte, data aliasdata.MyTemplateData) ([]byte, error) {
+	return compiledTemplates.MustGet("tt").AppendExecute(dst, data)
+}
+
+// RenderETpl renders the template "e.tpl".
+func RenderETpl(w io.Writer, data aliasdata.MyTemplateData) error {
+	return compiledTemplates.MustGet("e.tpl").Execute(w, data)
+}
+
+// AppendRenderETpl appends the template "e.tpl" to dst.
+func AppendRenderETpl(dst []byte, data aliasdata.MyTemplateData) ([]byte, error) {
+	return compiledTemplates.MustGet("e.tpl").AppendExecute(dst, data)
+}
+
+// RenderFTpl renders the template "f.tpl".
+func RenderFTpl(w io.Writer, data aliasdata.MyTemplateData) error {
+	return compiledTemplates.MustGet("f.tpl").Execute(w, data)
+}
+
+// AppendRenderFTpl appends the template "f.tpl" to dst.
+func AppendRenderFTpl(dst []byte, data aliasdata.MyTemplateData) ([]byte, error) {
+	return compiledTemplates.MustGet("f.tpl").AppendExecute(dst, data)
+}
+
+// RenderEmbed renders the template "embed".
+func RenderEmbed(w io.Writer, data aliasdata.MyTemplateData) error {
+	return compiledTemplates.MustGet("embed").Execute(w, data)
+}
+
+// AppendRenderEmbed appends the template "embed" to dst.
+func AppendRenderEmbed(dst []byte, data aliasdata.MyTemplateData) ([]byte, error) {
+	return compiledTemplates.MustGet("embed").AppendExecute(dst, data)
+}
 
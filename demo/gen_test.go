@@ -0,0 +1,546 @@
+package main
+
+//golint:ignore
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/mh-cbon/template-compiler/demo/data"
+	html "github.com/mh-cbon/template-compiler/std/html/template"
+	text "github.com/mh-cbon/template-compiler/std/text/template"
+)
+
+// testCompiledTemplate renders the template name with the compiled template and the interpreted template,
+// for each sample, it fails if they do not both succeed with the same output, or both fail.
+func testCompiledTemplate(t *testing.T, jit interface {
+	ExecuteTemplate(io.Writer, string, interface{}) error
+}, name string, samples []interface{}) {
+	t.Helper()
+	for i, data := range samples {
+		var want, got bytes.Buffer
+		wantErr := jit.ExecuteTemplate(&want, name, data)
+		gotErr := compiledTemplates.MustGet(name).Execute(&got, data)
+		if (wantErr == nil) != (gotErr == nil) {
+			t.Errorf("%v: sample %v: the interpreted template error=%v, the compiled template error=%v", name, i, wantErr, gotErr)
+		} else if wantErr == nil && want.String() != got.String() {
+			t.Errorf("%v: sample %v: unexpected output\ninterpreted=%q\ncompiled=%q", name, i, want.String(), got.String())
+		}
+	}
+}
+
+// TestCompiledATpl compares the compiled and the interpreted template "a.tpl".
+func TestCompiledATpl(t *testing.T) {
+	jit, err := html.New("a.tpl").ParseFiles("templates/a.tpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCompiledTemplate(t, jit, "a.tpl", compiledTemplates.Templates[0].Samples("a.tpl"))
+}
+
+// FuzzCompiledATpl compares the compiled and the interpreted template "a.tpl" over fuzzed data.
+func FuzzCompiledATpl(f *testing.F) {
+	jit, err := html.New("a.tpl").ParseFiles("templates/a.tpl")
+	if err != nil {
+		f.Fatal(err)
+	}
+	samples := compiledTemplates.Templates[0].Samples("a.tpl")
+	for i, sample := range samples {
+		d, ok := sample.(data.MyTemplateData)
+		if ok == false {
+			f.Fatalf("a.tpl: sample %v: unexpected data %#v, wants data.MyTemplateData", i, sample)
+		}
+		f.Add(d.Some)
+	}
+	f.Fuzz(func(t *testing.T, v0 string) {
+		var fuzzed []interface{}
+		for i, sample := range samples {
+			d, ok := sample.(data.MyTemplateData)
+			if ok == false {
+				t.Fatalf("a.tpl: sample %v: unexpected data %#v, wants data.MyTemplateData", i, sample)
+			}
+			d.Some = v0
+			fuzzed = append(fuzzed, d)
+		}
+		testCompiledTemplate(t, jit, "a.tpl", fuzzed)
+	})
+}
+
+// BenchmarkCompiledATpl renders the compiled template "a.tpl".
+func BenchmarkCompiledATpl(b *testing.B) {
+	tpl := compiledTemplates.MustGet("a.tpl")
+	samples := compiledTemplates.Templates[0].Samples("a.tpl")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		tpl.Execute(ioutil.Discard, samples[n%len(samples)])
+	}
+}
+
+// BenchmarkInterpretedATpl renders the interpreted template "a.tpl".
+func BenchmarkInterpretedATpl(b *testing.B) {
+	jit, err := html.New("a.tpl").ParseFiles("templates/a.tpl")
+	if err != nil {
+		b.Fatal(err)
+	}
+	samples := compiledTemplates.Templates[0].Samples("a.tpl")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		jit.ExecuteTemplate(ioutil.Discard, "a.tpl", samples[n%len(samples)])
+	}
+}
+
+// TestCompiledBTpl compares the compiled and the interpreted template "b.tpl".
+func TestCompiledBTpl(t *testing.T) {
+	jit, err := html.New("b.tpl").ParseFiles("templates/b.tpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCompiledTemplate(t, jit, "b.tpl", compiledTemplates.Templates[0].Samples("b.tpl"))
+}
+
+// FuzzCompiledBTpl compares the compiled and the interpreted template "b.tpl" over fuzzed data.
+func FuzzCompiledBTpl(f *testing.F) {
+	jit, err := html.New("b.tpl").ParseFiles("templates/b.tpl")
+	if err != nil {
+		f.Fatal(err)
+	}
+	samples := compiledTemplates.Templates[0].Samples("b.tpl")
+	for i, sample := range samples {
+		d, ok := sample.(data.MyTemplateData)
+		if ok == false {
+			f.Fatalf("b.tpl: sample %v: unexpected data %#v, wants data.MyTemplateData", i, sample)
+		}
+		f.Add(d.Some)
+	}
+	f.Fuzz(func(t *testing.T, v0 string) {
+		var fuzzed []interface{}
+		for i, sample := range samples {
+			d, ok := sample.(data.MyTemplateData)
+			if ok == false {
+				t.Fatalf("b.tpl: sample %v: unexpected data %#v, wants data.MyTemplateData", i, sample)
+			}
+			d.Some = v0
+			fuzzed = append(fuzzed, d)
+		}
+		testCompiledTemplate(t, jit, "b.tpl", fuzzed)
+	})
+}
+
+// BenchmarkCompiledBTpl renders the compiled template "b.tpl".
+func BenchmarkCompiledBTpl(b *testing.B) {
+	tpl := compiledTemplates.MustGet("b.tpl")
+	samples := compiledTemplates.Templates[0].Samples("b.tpl")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		tpl.Execute(ioutil.Discard, samples[n%len(samples)])
+	}
+}
+
+// BenchmarkInterpretedBTpl renders the interpreted template "b.tpl".
+func BenchmarkInterpretedBTpl(b *testing.B) {
+	jit, err := html.New("b.tpl").ParseFiles("templates/b.tpl")
+	if err != nil {
+		b.Fatal(err)
+	}
+	samples := compiledTemplates.Templates[0].Samples("b.tpl")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		jit.ExecuteTemplate(ioutil.Discard, "b.tpl", samples[n%len(samples)])
+	}
+}
+
+// TestCompiledCTpl compares the compiled and the interpreted template "c.tpl".
+func TestCompiledCTpl(t *testing.T) {
+	jit, err := html.New("c.tpl").ParseFiles("templates/c.tpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCompiledTemplate(t, jit, "c.tpl", compiledTemplates.Templates[0].Samples("c.tpl"))
+}
+
+// FuzzCompiledCTpl compares the compiled and the interpreted template "c.tpl" over fuzzed data.
+func FuzzCompiledCTpl(f *testing.F) {
+	jit, err := html.New("c.tpl").ParseFiles("templates/c.tpl")
+	if err != nil {
+		f.Fatal(err)
+	}
+	samples := compiledTemplates.Templates[0].Samples("c.tpl")
+	for i, sample := range samples {
+		d, ok := sample.(data.MyTemplateData)
+		if ok == false {
+			f.Fatalf("c.tpl: sample %v: unexpected data %#v, wants data.MyTemplateData", i, sample)
+		}
+		f.Add(d.Some)
+	}
+	f.Fuzz(func(t *testing.T, v0 string) {
+		var fuzzed []interface{}
+		for i, sample := range samples {
+			d, ok := sample.(data.MyTemplateData)
+			if ok == false {
+				t.Fatalf("c.tpl: sample %v: unexpected data %#v, wants data.MyTemplateData", i, sample)
+			}
+			d.Some = v0
+			fuzzed = append(fuzzed, d)
+		}
+		testCompiledTemplate(t, jit, "c.tpl", fuzzed)
+	})
+}
+
+// BenchmarkCompiledCTpl renders the compiled template "c.tpl".
+func BenchmarkCompiledCTpl(b *testing.B) {
+	tpl := compiledTemplates.MustGet("c.tpl")
+	samples := compiledTemplates.Templates[0].Samples("c.tpl")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		tpl.Execute(ioutil.Discard, samples[n%len(samples)])
+	}
+}
+
+// BenchmarkInterpretedCTpl renders the interpreted template "c.tpl".
+func BenchmarkInterpretedCTpl(b *testing.B) {
+	jit, err := html.New("c.tpl").ParseFiles("templates/c.tpl")
+	if err != nil {
+		b.Fatal(err)
+	}
+	samples := compiledTemplates.Templates[0].Samples("c.tpl")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		jit.ExecuteTemplate(ioutil.Discard, "c.tpl", samples[n%len(samples)])
+	}
+}
+
+// TestCompiledDTpl compares the compiled and the interpreted template "d.tpl".
+func TestCompiledDTpl(t *testing.T) {
+	jit, err := html.New("d.tpl").ParseFiles("templates/d.tpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCompiledTemplate(t, jit, "d.tpl", compiledTemplates.Templates[0].Samples("d.tpl"))
+	testCompiledTemplate(t, jit, "tt", compiledTemplates.Templates[0].Samples("tt"))
+}
+
+// FuzzCompiledDTpl compares the compiled and the interpreted template "d.tpl" over fuzzed data.
+func FuzzCompiledDTpl(f *testing.F) {
+	jit, err := html.New("d.tpl").ParseFiles("templates/d.tpl")
+	if err != nil {
+		f.Fatal(err)
+	}
+	samples := compiledTemplates.Templates[0].Samples("d.tpl")
+	for i, sample := range samples {
+		d, ok := sample.(data.MyTemplateData)
+		if ok == false {
+			f.Fatalf("d.tpl: sample %v: unexpected data %#v, wants data.MyTemplateData", i, sample)
+		}
+		f.Add(d.Some)
+	}
+	f.Fuzz(func(t *testing.T, v0 string) {
+		var fuzzed []interface{}
+		for i, sample := range samples {
+			d, ok := sample.(data.MyTemplateData)
+			if ok == false {
+				t.Fatalf("d.tpl: sample %v: unexpected data %#v, wants data.MyTemplateData", i, sample)
+			}
+			d.Some = v0
+			fuzzed = append(fuzzed, d)
+		}
+		testCompiledTemplate(t, jit, "d.tpl", fuzzed)
+	})
+}
+
+// BenchmarkCompiledDTpl renders the compiled template "d.tpl".
+func BenchmarkCompiledDTpl(b *testing.B) {
+	tpl := compiledTemplates.MustGet("d.tpl")
+	samples := compiledTemplates.Templates[0].Samples("d.tpl")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		tpl.Execute(ioutil.Discard, samples[n%len(samples)])
+	}
+}
+
+// BenchmarkInterpretedDTpl renders the interpreted template "d.tpl".
+func BenchmarkInterpretedDTpl(b *testing.B) {
+	jit, err := html.New("d.tpl").ParseFiles("templates/d.tpl")
+	if err != nil {
+		b.Fatal(err)
+	}
+	samples := compiledTemplates.Templates[0].Samples("d.tpl")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		jit.ExecuteTemplate(ioutil.Discard, "d.tpl", samples[n%len(samples)])
+	}
+}
+
+// FuzzCompiledTt compares the compiled and the interpreted template "tt" over fuzzed data.
+func FuzzCompiledTt(f *testing.F) {
+	jit, err := html.New("d.tpl").ParseFiles("templates/d.tpl")
+	if err != nil {
+		f.Fatal(err)
+	}
+	samples := compiledTemplates.Templates[0].Samples("tt")
+	for i, sample := range samples {
+		d, ok := sample.(data.MyTemplateData)
+		if ok == false {
+			f.Fatalf("tt: sample %v: unexpected data %#v, wants data.MyTemplateData", i, sample)
+		}
+		f.Add(d.Some)
+	}
+	f.Fuzz(func(t *testing.T, v0 string) {
+		var fuzzed []interface{}
+		for i, sample := range samples {
+			d, ok := sample.(data.MyTemplateData)
+			if ok == false {
+				t.Fatalf("tt: sample %v: unexpected data %#v, wants data.MyTemplateData", i, sample)
+			}
+			d.Some = v0
+			fuzzed = append(fuzzed, d)
+		}
+		testCompiledTemplate(t, jit, "tt", fuzzed)
+	})
+}
+
+// BenchmarkCompiledTt renders the compiled template "tt".
+func BenchmarkCompiledTt(b *testing.B) {
+	tpl := compiledTemplates.MustGet("tt")
+	samples := compiledTemplates.Templates[0].Samples("tt")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		tpl.Execute(ioutil.Discard, samples[n%len(samples)])
+	}
+}
+
+// BenchmarkInterpretedTt renders the interpreted template "tt".
+func BenchmarkInterpretedTt(b *testing.B) {
+	jit, err := html.New("d.tpl").ParseFiles("templates/d.tpl")
+	if err != nil {
+		b.Fatal(err)
+	}
+	samples := compiledTemplates.Templates[0].Samples("tt")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		jit.ExecuteTemplate(ioutil.Discard, "tt", samples[n%len(samples)])
+	}
+}
+
+// TestCompiledETpl compares the compiled and the interpreted template "e.tpl".
+func TestCompiledETpl(t *testing.T) {
+	jit, err := html.New("e.tpl").ParseFiles("templates/e.tpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCompiledTemplate(t, jit, "e.tpl", compiledTemplates.Templates[0].Samples("e.tpl"))
+}
+
+// FuzzCompiledETpl compares the compiled and the interpreted template "e.tpl" over fuzzed data.
+func FuzzCompiledETpl(f *testing.F) {
+	jit, err := html.New("e.tpl").ParseFiles("templates/e.tpl")
+	if err != nil {
+		f.Fatal(err)
+	}
+	samples := compiledTemplates.Templates[0].Samples("e.tpl")
+	for i, sample := range samples {
+		d, ok := sample.(data.MyTemplateData)
+		if ok == false {
+			f.Fatalf("e.tpl: sample %v: unexpected data %#v, wants data.MyTemplateData", i, sample)
+		}
+		f.Add(d.Some)
+	}
+	f.Fuzz(func(t *testing.T, v0 string) {
+		var fuzzed []interface{}
+		for i, sample := range samples {
+			d, ok := sample.(data.MyTemplateData)
+			if ok == false {
+				t.Fatalf("e.tpl: sample %v: unexpected data %#v, wants data.MyTemplateData", i, sample)
+			}
+			d.Some = v0
+			fuzzed = append(fuzzed, d)
+		}
+		testCompiledTemplate(t, jit, "e.tpl", fuzzed)
+	})
+}
+
+// BenchmarkCompiledETpl renders the compiled template "e.tpl".
+func BenchmarkCompiledETpl(b *testing.B) {
+	tpl := compiledTemplates.MustGet("e.tpl")
+	samples := compiledTemplates.Templates[0].Samples("e.tpl")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		tpl.Execute(ioutil.Discard, samples[n%len(samples)])
+	}
+}
+
+// BenchmarkInterpretedETpl renders the interpreted template "e.tpl".
+func BenchmarkInterpretedETpl(b *testing.B) {
+	jit, err := html.New("e.tpl").ParseFiles("templates/e.tpl")
+	if err != nil {
+		b.Fatal(err)
+	}
+	samples := compiledTemplates.Templates[0].Samples("e.tpl")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		jit.ExecuteTemplate(ioutil.Discard, "e.tpl", samples[n%len(samples)])
+	}
+}
+
+// TestCompiledFTpl compares the compiled and the interpreted template "f.tpl".
+func TestCompiledFTpl(t *testing.T) {
+	jit, err := html.New("f.tpl").ParseFiles("templates/f.tpl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCompiledTemplate(t, jit, "f.tpl", compiledTemplates.Templates[0].Samples("f.tpl"))
+}
+
+// FuzzCompiledFTpl compares the compiled and the interpreted template "f.tpl" over fuzzed data.
+func FuzzCompiledFTpl(f *testing.F) {
+	jit, err := html.New("f.tpl").ParseFiles("templates/f.tpl")
+	if err != nil {
+		f.Fatal(err)
+	}
+	samples := compiledTemplates.Templates[0].Samples("f.tpl")
+	for i, sample := range samples {
+		d, ok := sample.(data.MyTemplateData)
+		if ok == false {
+			f.Fatalf("f.tpl: sample %v: unexpected data %#v, wants data.MyTemplateData", i, sample)
+		}
+		f.Add(d.Some)
+	}
+	f.Fuzz(func(t *testing.T, v0 string) {
+		var fuzzed []interface{}
+		for i, sample := range samples {
+			d, ok := sample.(data.MyTemplateData)
+			if ok == false {
+				t.Fatalf("f.tpl: sample %v: unexpected data %#v, wants data.MyTemplateData", i, sample)
+			}
+			d.Some = v0
+			fuzzed = append(fuzzed, d)
+		}
+		testCompiledTemplate(t, jit, "f.tpl", fuzzed)
+	})
+}
+
+// BenchmarkCompiledFTpl renders the compiled template "f.tpl".
+func BenchmarkCompiledFTpl(b *testing.B) {
+	tpl := compiledTemplates.MustGet("f.tpl")
+	samples := compiledTemplates.Templates[0].Samples("f.tpl")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		tpl.Execute(ioutil.Discard, samples[n%len(samples)])
+	}
+}
+
+// BenchmarkInterpretedFTpl renders the interpreted template "f.tpl".
+func BenchmarkInterpretedFTpl(b *testing.B) {
+	jit, err := html.New("f.tpl").ParseFiles("templates/f.tpl")
+	if err != nil {
+		b.Fatal(err)
+	}
+	samples := compiledTemplates.Templates[0].Samples("f.tpl")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		jit.ExecuteTemplate(ioutil.Discard, "f.tpl", samples[n%len(samples)])
+	}
+}
+
+// TestCompiledNotafile compares the compiled and the interpreted template "notafile".
+func TestCompiledNotafile(t *testing.T) {
+	jit, err := text.New("notafile").Parse("hello!{{define \"embed\"}}{{.}}{{end}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCompiledTemplate(t, jit, "embed", compiledTemplates.Templates[1].Samples("embed"))
+	testCompiledTemplate(t, jit, "notafile", compiledTemplates.Templates[1].Samples("notafile"))
+}
+
+// FuzzCompiledEmbed compares the compiled and the interpreted template "embed" over fuzzed data.
+func FuzzCompiledEmbed(f *testing.F) {
+	jit, err := text.New("notafile").Parse("hello!{{define \"embed\"}}{{.}}{{end}}")
+	if err != nil {
+		f.Fatal(err)
+	}
+	samples := compiledTemplates.Templates[1].Samples("embed")
+	for i, sample := range samples {
+		d, ok := sample.(data.MyTemplateData)
+		if ok == false {
+			f.Fatalf("embed: sample %v: unexpected data %#v, wants data.MyTemplateData", i, sample)
+		}
+		f.Add(d.Some)
+	}
+	f.Fuzz(func(t *testing.T, v0 string) {
+		var fuzzed []interface{}
+		for i, sample := range samples {
+			d, ok := sample.(data.MyTemplateData)
+			if ok == false {
+				t.Fatalf("embed: sample %v: unexpected data %#v, wants data.MyTemplateData", i, sample)
+			}
+			d.Some = v0
+			fuzzed = append(fuzzed, d)
+		}
+		testCompiledTemplate(t, jit, "embed", fuzzed)
+	})
+}
+
+// BenchmarkCompiledEmbed renders the compiled template "embed".
+func BenchmarkCompiledEmbed(b *testing.B) {
+	tpl := compiledTemplates.MustGet("embed")
+	samples := compiledTemplates.Templates[1].Samples("embed")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		tpl.Execute(ioutil.Discard, samples[n%len(samples)])
+	}
+}
+
+// BenchmarkInterpretedEmbed renders the interpreted template "embed".
+func BenchmarkInterpretedEmbed(b *testing.B) {
+	jit, err := text.New("notafile").Parse("hello!{{define \"embed\"}}{{.}}{{end}}")
+	if err != nil {
+		b.Fatal(err)
+	}
+	samples := compiledTemplates.Templates[1].Samples("embed")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		jit.ExecuteTemplate(ioutil.Discard, "embed", samples[n%len(samples)])
+	}
+}
+
+// BenchmarkCompiledNotafile renders the compiled template "notafile".
+func BenchmarkCompiledNotafile(b *testing.B) {
+	tpl := compiledTemplates.MustGet("notafile")
+	samples := compiledTemplates.Templates[1].Samples("notafile")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		tpl.Execute(ioutil.Discard, samples[n%len(samples)])
+	}
+}
+
+// BenchmarkInterpretedNotafile renders the interpreted template "notafile".
+func BenchmarkInterpretedNotafile(b *testing.B) {
+	jit, err := text.New("notafile").Parse("hello!{{define \"embed\"}}{{.}}{{end}}")
+	if err != nil {
+		b.Fatal(err)
+	}
+	samples := compiledTemplates.Templates[1].Samples("notafile")
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		jit.ExecuteTemplate(ioutil.Discard, "notafile", samples[n%len(samples)])
+	}
+}
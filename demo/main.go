@@ -32,7 +32,7 @@ var compiledTemplates = compiled.New(
 			},
 		},
 	},
-).SetPkg("main")
+).SetPkg("main").SetTests(true).SetBenchmarks(true)
 
 // later, re arrange the demo to not use main,
 // as its not a good case to run.
@@ -2,10 +2,18 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/mh-cbon/template-compiler/compiled"
 	"github.com/mh-cbon/template-compiler/demo/data"
 	"github.com/mh-cbon/template-compiler/std/html/template"
 	text "github.com/mh-cbon/template-compiler/std/text/template"
@@ -153,79 +161,482 @@ func TestTemplatesF(t *testing.T) {
 		t.Errorf("nop\n'%v'\n'%v'", aS, bS)
 	}
 }
+func TestTemplatesRenderFunc(t *testing.T) {
+	var a bytes.Buffer
+	if err := cJitTemplate.Execute(&a, tplData); err != nil {
+		panic(err)
+	}
+	var b bytes.Buffer
+	if err := RenderCTpl(&b, tplData); err != nil {
+		panic(err)
+	}
+	aS := a.String()
+	bS := b.String()
+	if aS != bS {
+		t.Errorf("nop\n'%v'\n'%v'", aS, bS)
+	}
+}
+func TestTemplatesAppend(t *testing.T) {
+	jit := []*template.Template{aJitTemplate, bJitTemplate, cJitTemplate, dJitTemplate, eJitTemplate, fJitTemplate}
+	compiled := []*text.Compiled{aCompiledTemplate, bCompiledTemplate, cCompiledTemplate, dCompiledTemplate, eCompiledTemplate, fCompiledTemplate}
+	for i := range jit {
+		var a bytes.Buffer
+		if err := jit[i].Execute(&a, tplData); err != nil {
+			panic(err)
+		}
+		b, err := compiled[i].AppendExecute([]byte("prefix"), tplData)
+		if err != nil {
+			panic(err)
+		}
+		aS := "prefix" + a.String()
+		bS := string(b)
+		if aS != bS {
+			t.Errorf("nop\n'%v'\n'%v'", aS, bS)
+		}
+	}
+}
+func TestRegistryRender(t *testing.T) {
+	var a bytes.Buffer
+	if err := cJitTemplate.Execute(&a, tplData); err != nil {
+		panic(err)
+	}
+	var b bytes.Buffer
+	if err := compiledTemplates.Render(&b, "c.tpl", tplData); err != nil {
+		panic(err)
+	}
+	if a.String() != b.String() {
+		t.Errorf("nop\n'%v'\n'%v'", a.String(), b.String())
+	}
+	c, err := compiledTemplates.AppendRender(nil, "c.tpl", tplData)
+	if err != nil {
+		panic(err)
+	}
+	if a.String() != string(c) {
+		t.Errorf("nop\n'%v'\n'%v'", a.String(), string(c))
+	}
+	d, err := AppendRenderCTpl(c[:0], tplData)
+	if err != nil {
+		panic(err)
+	}
+	if a.String() != string(d) {
+		t.Errorf("nop\n'%v'\n'%v'", a.String(), string(d))
+	}
+	if err := compiledTemplates.Render(&b, "nop.tpl", tplData); err == nil {
+		t.Error("expected an error when rendering an unknown template")
+	}
+}
+func TestTemplatesExecuteContext(t *testing.T) {
+	var a bytes.Buffer
+	if err := eJitTemplate.Execute(&a, tplData); err != nil {
+		panic(err)
+	}
+	var b bytes.Buffer
+	if err := compiledTemplates.ExecuteContext(context.Background(), &b, "e.tpl", tplData); err != nil {
+		panic(err)
+	}
+	if a.String() != b.String() {
+		t.Errorf("nop\n'%v'\n'%v'", a.String(), b.String())
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := compiledTemplates.ExecuteContext(ctx, &b, "e.tpl", tplData); err != context.Canceled {
+		t.Errorf("unexpected error %v", err)
+	}
+	if compiled.DevMode() {
+		return
+	}
+	// the loop stops at the next iteration once ctx is done.
+	ctx, cancel = context.WithCancel(context.Background())
+	w := &cancelWriter{cancel: cancel, after: 100}
+	if err := compiledTemplates.ExecuteContext(ctx, w, "e.tpl", tplData); err != context.Canceled {
+		t.Errorf("unexpected error %v", err)
+	}
+	if w.Len() >= a.Len() {
+		t.Errorf("expected the rendering to stop, got %v bytes", w.Len())
+	}
+}
+
+// cancelWriter cancels its context once after bytes are written.
+type cancelWriter struct {
+	bytes.Buffer
+	cancel func()
+	after  int
+}
+
+func (c *cancelWriter) Write(p []byte) (int, error) {
+	if c.Len() >= c.after {
+		c.cancel()
+	}
+	return c.Buffer.Write(p)
+}
+func TestTemplatesLimits(t *testing.T) {
+	if compiled.DevMode() {
+		t.Skip("the development mode interprets the templates")
+	}
+	var b bytes.Buffer
+	err := compiledTemplates.ExecuteTemplateLimits(&b, "e.tpl", tplData, text.Limits{MaxRangeIterations: 10})
+	expected := "template: e.tpl: exceeded maximum range iterations (10)"
+	if err == nil || err.Error() != expected {
+		t.Errorf("unexpected error\nexpected=%v\ngot=%v", expected, err)
+	}
+	b.Reset()
+	err = compiledTemplates.ExecuteTemplateLimits(&b, "e.tpl", tplData, text.Limits{MaxBytes: 100})
+	var limitErr *text.LimitError
+	if errors.As(err, &limitErr) == false || b.Len() > 100 {
+		t.Errorf("unexpected error %v, output size %v", err, b.Len())
+	}
+	b.Reset()
+	err = compiledTemplates.ExecuteTemplateLimits(&b, "d.tpl", tplData, text.Limits{MaxDepth: 1, MaxRangeIterations: 10})
+	if err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	// the typed render funcs apply the limits of the registry.
+	compiledTemplates.SetLimits(text.Limits{MaxRangeIterations: 10})
+	defer compiledTemplates.SetLimits(text.Limits{})
+	b.Reset()
+	err = RenderETpl(&b, tplData)
+	if err == nil || err.Error() != expected {
+		t.Errorf("unexpected error\nexpected=%v\ngot=%v", expected, err)
+	}
+	_, err = AppendRenderETpl(nil, tplData)
+	if err == nil || err.Error() != expected {
+		t.Errorf("unexpected error\nexpected=%v\ngot=%v", expected, err)
+	}
+}
+
+type templateObservations []string
+
+func (o *templateObservations) OnStart(name string) {
+	*o = append(*o, "start "+name)
+}
+
+func (o *templateObservations) OnEnd(name string, n int, dur time.Duration, err error) {
+	*o = append(*o, fmt.Sprintf("end %v %v %v", name, n, err))
+}
+
+func TestTemplatesObserver(t *testing.T) {
+	if compiled.DevMode() {
+		t.Skip("the development mode interprets the templates")
+	}
+	o := &templateObservations{}
+	compiledTemplates.SetObserver(o)
+	defer compiledTemplates.SetObserver(nil)
+
+	var a bytes.Buffer
+	if err := dJitTemplate.Execute(&a, tplData); err != nil {
+		panic(err)
+	}
+	expected := []string{"start d.tpl", "start tt", "end tt 5 <nil>", "end d.tpl 14 <nil>"}
+	var b bytes.Buffer
+	if err := compiledTemplates.ExecuteTemplate(&b, "d.tpl", tplData); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != a.String() {
+		t.Errorf("unexpected output\nexpected=%q\ngot=%q", a.String(), b.String())
+	}
+	if reflect.DeepEqual([]string(*o), expected) == false {
+		t.Errorf("unexpected observations %q", *o)
+	}
+
+	*o = nil
+	if err := compiledTemplates.ExecuteContext(context.Background(), &b, "d.tpl", tplData); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := compiledTemplates.AppendRender(nil, "d.tpl", tplData); err != nil || string(out) != a.String() {
+		t.Errorf("unexpected output %q %v", out, err)
+	}
+	if reflect.DeepEqual([]string(*o), append(expected, expected...)) == false {
+		t.Errorf("unexpected observations %q", *o)
+	}
+
+	// the typed render funcs are observed.
+	*o = nil
+	b.Reset()
+	if err := RenderDTpl(&b, tplData); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := AppendRenderDTpl(nil, tplData); err != nil || string(out) != a.String() {
+		t.Errorf("unexpected output %q %v", out, err)
+	}
+	if b.String() != a.String() {
+		t.Errorf("unexpected output\nexpected=%q\ngot=%q", a.String(), b.String())
+	}
+	if reflect.DeepEqual([]string(*o), append(expected, expected...)) == false {
+		t.Errorf("unexpected observations %q", *o)
+	}
+}
+
+func TestTemplatesExecError(t *testing.T) {
+	if compiled.DevMode() {
+		t.Skip("the development mode interprets the templates")
+	}
+	errWrite := errors.New("write failed")
+	err := compiledTemplates.ExecuteTemplate(failingWriter{errWrite}, "e.tpl", tplData)
+	execErr, ok := err.(text.ExecError)
+	if ok == false {
+		t.Fatalf("expected an ExecError, got %#v", err)
+	}
+	expected := "template: e.tpl:1:0: executing \"e.tpl\" at <This is a template!\n...>: write failed"
+	if err.Error() != expected {
+		t.Errorf("unexpected error\nexpected=%v\ngot=%v", expected, err)
+	}
+	if execErr.Name != "e.tpl" || execErr.Location != "e.tpl:1:0" {
+		t.Errorf("unexpected error name %q location %q", execErr.Name, execErr.Location)
+	}
+	if errors.Is(err, errWrite) == false {
+		t.Errorf("expected the error to wrap %v", errWrite)
+	}
+}
+
+// failingWriter fails to write.
+type failingWriter struct {
+	err error
+}
+
+func (f failingWriter) Write(p []byte) (int, error) {
+	return 0, f.err
+}
+
+func TestTemplatesLineDirectives(t *testing.T) {
+	if compiled.DevMode() {
+		t.Skip("the development mode interprets the templates")
+	}
+	w := &callerWriter{}
+	if err := compiledTemplates.ExecuteTemplate(w, "d.tpl", tplData); err != nil {
+		t.Fatal(err)
+	}
+	if strings.HasSuffix(filepath.ToSlash(w.file), "templates/d.tpl") == false {
+		t.Errorf("unexpected file of the first write %v", w.file)
+	}
+	if w.line != 1 {
+		t.Errorf("unexpected line of the first write %v", w.line)
+	}
+}
+
+// callerWriter records the position of the caller of its first write.
+type callerWriter struct {
+	bytes.Buffer
+	file string
+	line int
+}
+
+func (c *callerWriter) Write(p []byte) (int, error) {
+	if c.file == "" {
+		_, c.file, c.line, _ = runtime.Caller(1)
+	}
+	return c.Buffer.Write(p)
+}
+
+func TestTemplatesUnexpectedDataType(t *testing.T) {
+	if compiled.DevMode() {
+		t.Skip("the development mode interprets the templates")
+	}
+	var b bytes.Buffer
+	err := cCompiledTemplate.Execute(&b, &tplData)
+	if err == nil {
+		t.Fatal("expected an error when executing with an unexpected data type")
+	}
+	expected := "template: c.tpl: unexpected data type *data.MyTemplateData, wants data.MyTemplateData"
+	if err.Error() != expected {
+		t.Errorf("unexpected error\nexpected=%v\ngot=%v", expected, err)
+	}
+}
+func TestTemplatesSizeHint(t *testing.T) {
+	if compiled.DevMode() {
+		t.Skip("the development mode interprets the templates")
+	}
+	names := []string{"a.tpl", "b.tpl", "c.tpl", "d.tpl", "e.tpl", "f.tpl"}
+	jit := []*template.Template{aJitTemplate, bJitTemplate, cJitTemplate, dJitTemplate, eJitTemplate, fJitTemplate}
+	for i, name := range names {
+		var a bytes.Buffer
+		if err := jit[i].Execute(&a, tplData); err != nil {
+			panic(err)
+		}
+		hint := compiledTemplates.SizeHint(name)
+		if hint <= 0 || hint > a.Len() {
+			t.Errorf("%v: unexpected size hint=%v, output size=%v", name, hint, a.Len())
+		}
+	}
+	if hint := compiledTemplates.SizeHint("nop.tpl"); hint != 0 {
+		t.Errorf("expected no size hint for an unknown template, got=%v", hint)
+	}
+}
 
 func BenchmarkRenderWithCompiledTemplateA(b *testing.B) {
+	b.ReportAllocs()
 	for n := 0; n < b.N; n++ {
 		aCompiledTemplate.Execute(Discard, tplData)
 	}
 }
 
 func BenchmarkRenderWithJitTemplateA(b *testing.B) {
+	b.ReportAllocs()
 	for n := 0; n < b.N; n++ {
 		aJitTemplate.Execute(Discard, tplData)
 	}
 }
 
+func BenchmarkAppendRenderWithCompiledTemplateA(b *testing.B) {
+	b.ReportAllocs()
+	var buf []byte
+	for n := 0; n < b.N; n++ {
+		buf, _ = aCompiledTemplate.AppendExecute(buf[:0], tplData)
+	}
+}
+
+func BenchmarkRegistryRenderA(b *testing.B) {
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		compiledTemplates.Render(Discard, "a.tpl", tplData)
+	}
+}
+
 func BenchmarkRenderWithCompiledTemplateB(b *testing.B) {
+	b.ReportAllocs()
 	for n := 0; n < b.N; n++ {
 		bCompiledTemplate.Execute(Discard, tplData)
 	}
 }
 
 func BenchmarkRenderWithJitTemplateB(b *testing.B) {
+	b.ReportAllocs()
 	for n := 0; n < b.N; n++ {
 		bJitTemplate.Execute(Discard, tplData)
 	}
 }
 
+func BenchmarkAppendRenderWithCompiledTemplateB(b *testing.B) {
+	b.ReportAllocs()
+	var buf []byte
+	for n := 0; n < b.N; n++ {
+		buf, _ = bCompiledTemplate.AppendExecute(buf[:0], tplData)
+	}
+}
+
+func BenchmarkRegistryRenderB(b *testing.B) {
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		compiledTemplates.Render(Discard, "b.tpl", tplData)
+	}
+}
+
 func BenchmarkRenderWithCompiledTemplateC(b *testing.B) {
+	b.ReportAllocs()
 	for n := 0; n < b.N; n++ {
 		cCompiledTemplate.Execute(Discard, tplData)
 	}
 }
 
 func BenchmarkRenderWithJitTemplateC(b *testing.B) {
+	b.ReportAllocs()
 	for n := 0; n < b.N; n++ {
 		cJitTemplate.Execute(Discard, tplData)
 	}
 }
 
+func BenchmarkAppendRenderWithCompiledTemplateC(b *testing.B) {
+	b.ReportAllocs()
+	var buf []byte
+	for n := 0; n < b.N; n++ {
+		buf, _ = cCompiledTemplate.AppendExecute(buf[:0], tplData)
+	}
+}
+
+func BenchmarkRegistryRenderC(b *testing.B) {
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		compiledTemplates.Render(Discard, "c.tpl", tplData)
+	}
+}
+
 func BenchmarkRenderWithCompiledTemplateD(b *testing.B) {
+	b.ReportAllocs()
 	for n := 0; n < b.N; n++ {
 		dCompiledTemplate.Execute(Discard, tplData)
 	}
 }
 
 func BenchmarkRenderWithJitTemplateD(b *testing.B) {
+	b.ReportAllocs()
 	for n := 0; n < b.N; n++ {
 		dJitTemplate.Execute(Discard, tplData)
 	}
 }
 
+func BenchmarkAppendRenderWithCompiledTemplateD(b *testing.B) {
+	b.ReportAllocs()
+	var buf []byte
+	for n := 0; n < b.N; n++ {
+		buf, _ = dCompiledTemplate.AppendExecute(buf[:0], tplData)
+	}
+}
+
+func BenchmarkRegistryRenderD(b *testing.B) {
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		compiledTemplates.Render(Discard, "d.tpl", tplData)
+	}
+}
+
 func BenchmarkRenderWithCompiledTemplateE(b *testing.B) {
+	b.ReportAllocs()
 	for n := 0; n < b.N; n++ {
 		eCompiledTemplate.Execute(Discard, tplData)
 	}
 }
 
 func BenchmarkRenderWithJitTemplateE(b *testing.B) {
+	b.ReportAllocs()
 	for n := 0; n < b.N; n++ {
 		eJitTemplate.Execute(Discard, tplData)
 	}
 }
 
+func BenchmarkAppendRenderWithCompiledTemplateE(b *testing.B) {
+	b.ReportAllocs()
+	var buf []byte
+	for n := 0; n < b.N; n++ {
+		buf, _ = eCompiledTemplate.AppendExecute(buf[:0], tplData)
+	}
+}
+
+func BenchmarkRegistryRenderE(b *testing.B) {
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		compiledTemplates.Render(Discard, "e.tpl", tplData)
+	}
+}
+
 func BenchmarkRenderWithCompiledTemplateF(b *testing.B) {
+	b.ReportAllocs()
 	for n := 0; n < b.N; n++ {
 		fCompiledTemplate.Execute(Discard, tplData)
 	}
 }
 
 func BenchmarkRenderWithJitTemplateF(b *testing.B) {
+	b.ReportAllocs()
 	for n := 0; n < b.N; n++ {
 		fJitTemplate.Execute(Discard, tplData)
 	}
 }
 
+func BenchmarkAppendRenderWithCompiledTemplateF(b *testing.B) {
+	b.ReportAllocs()
+	var buf []byte
+	for n := 0; n < b.N; n++ {
+		buf, _ = fCompiledTemplate.AppendExecute(buf[:0], tplData)
+	}
+}
+
+func BenchmarkRegistryRenderF(b *testing.B) {
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		compiledTemplates.Render(Discard, "f.tpl", tplData)
+	}
+}
+
 // func BenchmarkHTMLEscapeString(b *testing.B) {
 // 	for n := 0; n < b.N; n++ {
 // 		template.HTMLEscapeString("some string")
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
 	"io/ioutil"
@@ -37,6 +38,11 @@ func main() {
 		return
 	}
 
+	if flag.Arg(0) == "bench" {
+		panicOnErr(runBenchmarks(flag.Args()[1:]))
+		return
+	}
+
 	wdir := *wdirPtr
 	varName := *varNamePtr
 
@@ -94,11 +100,17 @@ func showHelp() {
   -wdir        The working directory where the bootstrap program is written
                default: $GOPATH/src/template-compilerxx/
 
+Commands
+  bench        Run the generated benchmarks of the current package,
+               and print the compiled templates against the interpreted templates.
+               Its arguments are passed to go test.
+
 Examples
   template-compiler -h
   template-compiler -version
   template-compiler -keep -var theVarName
   template-compiler -keep -var theVarName -wdir /tmp
+  template-compiler bench -benchtime 2s
 `)
 }
 
@@ -121,3 +133,25 @@ func invokeProgram(wdir string) error {
 	c.Stderr = os.Stderr
 	return c.Run()
 }
+
+// runBenchmarks runs the generated benchmarks with go test,
+// then prints the table of the results.
+func runBenchmarks(args []string) error {
+	args = append([]string{"test", "-run", "^$", "-bench", compiler.BenchmarkPattern, "-benchmem"}, args...)
+	var out bytes.Buffer
+	c := exec.Command("go", args...)
+	c.Stdout = &out
+	c.Stderr = os.Stderr
+	if err := c.Run(); err != nil {
+		os.Stderr.Write(out.Bytes())
+		return err
+	}
+	comparisons, err := compiler.ParseBenchmarks(&out)
+	if err != nil {
+		return err
+	}
+	if len(comparisons) == 0 {
+		return fmt.Errorf("No generated benchmarks found, enable them with SetBenchmarks(true)")
+	}
+	return compiler.WriteBenchmarkTable(os.Stdout, comparisons)
+}
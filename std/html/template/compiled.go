@@ -1,6 +1,18 @@
 package template
 
-import "github.com/mh-cbon/template-compiler/std/text/template"
+import (
+	// context is the escaping context of the package.
+	stdcontext "context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+	"unsafe"
+
+	"github.com/mh-cbon/template-compiler/std/text/template"
+	"github.com/mh-cbon/template-compiler/std/text/template/parse"
+)
 
 // publicFuncMap is the map of functions with public funcs
 var publicFuncMap = template.FuncMap{
@@ -9,7 +21,7 @@ var publicFuncMap = template.FuncMap{
 	"_html_template_cssescaper":      CSSEscaper,
 	"_html_template_cssvaluefilter":  CSSValueFilter,
 	"_html_template_htmlnamefilter":  HTMLNameFilter,
-	"_html_template_htmlescaper":     HTMLEscaper,
+	"_html_template_htmlescaper":     HTMLTextEscaper,
 	"_html_template_jsregexpescaper": JSRegexpEscaper,
 	"_html_template_jsstrescaper":    JSStrEscaper,
 	"_html_template_jsvalescaper":    JSValEscaper,
@@ -20,6 +32,97 @@ var publicFuncMap = template.FuncMap{
 	"_html_template_urlnormalizer":   urlNormalizer,
 }
 
+// additions to template.Template
+
+// Compiled registers the compiled templates associated with c into the namespace of t.
+// They are escaped when they are compiled, the escaper skips them.
+// The interpreted templates can call them in a text context,
+// and they call the templates of t, escaped, by their name.
+//
+// It returns an error if a template it replaces has already been executed.
+func (t *Template) Compiled(c *template.Compiled) (*Template, error) {
+	t.nameSpace.mu.Lock()
+	defer t.nameSpace.mu.Unlock()
+	for _, ct := range c.CompiledTemplates() {
+		name := ct.Name()
+		tmpl := t.set[name]
+		if tmpl != nil && tmpl.escapeErr != nil {
+			return nil, fmt.Errorf("html/template: cannot redefine %q after it has executed", name)
+		}
+		text, err := t.text.AddParseTree(name, compiledTree(name, ct.ExecuteFunc(), t))
+		if err != nil {
+			return nil, err
+		}
+		if tmpl == nil {
+			tmpl = &Template{nil, text, nil, t.nameSpace}
+			t.set[name] = tmpl
+		}
+		tmpl.escapeErr = escapeOK
+		tmpl.text = text
+		tmpl.Tree = text.Tree
+	}
+	return t, nil
+}
+
+// compiledTree returns the tree of the compiled template function fn,
+// it is executed with the templates of t.
+func compiledTree(name string, fn parse.CompiledTemplateFunc, t *Template) *parse.Tree {
+	tree := &parse.Tree{Name: name, ParseName: name}
+	tree.Root = &parse.ListNode{NodeType: parse.NodeList}
+	tree.Root.Nodes = append(tree.Root.Nodes, tree.NewCompiledNode(
+		func(templater parse.Templater, w io.Writer, data interface{}) error {
+			return fn(compiledTemplater{Templater: templater, t: t}, w, data)
+		}))
+	return tree
+}
+
+// isCompiledTree tells if tree is the tree of a compiled template.
+func isCompiledTree(tree *parse.Tree) bool {
+	if tree == nil || tree.Root == nil || len(tree.Root.Nodes) != 1 {
+		return false
+	}
+	_, ok := tree.Root.Nodes[0].(*parse.CompiledNode)
+	return ok
+}
+
+// compiledTemplater is the Templater of the compiled templates of an HTML template set,
+// their template calls are escaped such as the calls of the interpreted templates.
+type compiledTemplater struct {
+	// Templater executes the templates within the execution of the set.
+	parse.Templater
+	t *Template
+}
+
+// ExecuteTemplate applies the escaped template name of the set.
+func (c compiledTemplater) ExecuteTemplate(wr io.Writer, name string, data interface{}) error {
+	if err := c.escape(name); err != nil {
+		return err
+	}
+	return c.Templater.ExecuteTemplate(wr, name, data)
+}
+
+// ExecuteTemplateContext is the form of ExecuteTemplate stopping when ctx is done.
+func (c compiledTemplater) ExecuteTemplateContext(ctx stdcontext.Context, wr io.Writer, name string, data interface{}) error {
+	if err := c.escape(name); err != nil {
+		return err
+	}
+	return c.Templater.ExecuteTemplateContext(ctx, wr, name, data)
+}
+
+// escape escapes the template name of the set before it is called.
+// The templates derived for the escaping contexts of the calls,
+// such as name$htmltemplate_stateURL_delimDoubleQuote_attrURL, are escaped with their callers.
+func (c compiledTemplater) escape(name string) error {
+	c.t.nameSpace.mu.Lock()
+	_, ok := c.t.set[name]
+	c.t.nameSpace.mu.Unlock()
+	if ok == false {
+		return nil
+	}
+	_, err := c.t.lookupAndEscapeTemplate(name)
+	return err
+}
+
 // URLNormalizer ...
 func URLNormalizer(args ...interface{}) string {
 	return urlNormalizer(args...)
@@ -65,6 +168,11 @@ func CSSEscaper(args ...interface{}) string {
 	return cssEscaper(args...)
 }
 
+// HTMLTextEscaper escapes for inclusion in HTML text.
+func HTMLTextEscaper(args ...interface{}) string {
+	return htmlEscaper(args...)
+}
+
 // HTMLNospaceEscaper escapes for inclusion in unquoted attribute values.
 func HTMLNospaceEscaper(args ...interface{}) string {
 	return htmlNospaceEscaper(args...)
@@ -84,3 +192,669 @@ func RcdataEscaper(args ...interface{}) string {
 func CommentEscaper(args ...interface{}) string {
 	return commentEscaper(args...)
 }
+
+// Streaming escapers.
+//
+// They are the typed forms of the escapers above for a plain string,
+// the To forms write the escaped value to w instead of returning a new string,
+// the Append forms append it to dst.
+// The compiler uses them when the escaped value is statically a string.
+
+// HTMLEscaperTo writes s escaped for inclusion in HTML text.
+func HTMLEscaperTo(w io.Writer, s string) error {
+	ew := errWriter{w: w}
+	writeHTMLReplaced(&ew, s, htmlReplacementTable, true)
+	return ew.err
+}
+
+// HTMLEscaperBytesTo is the []byte form of HTMLEscaperTo.
+func HTMLEscaperBytesTo(w io.Writer, b []byte) error {
+	return HTMLEscaperTo(w, bytesToString(b))
+}
+
+// HTMLEscaperAppend appends s escaped for inclusion in HTML text to dst.
+func HTMLEscaperAppend(dst []byte, s string) []byte {
+	ew := errWriter{dst: dst}
+	writeHTMLReplaced(&ew, s, htmlReplacementTable, true)
+	return ew.dst
+}
+
+// HTMLEscaperBytesAppend is the []byte form of HTMLEscaperAppend.
+func HTMLEscaperBytesAppend(dst, b []byte) []byte {
+	return HTMLEscaperAppend(dst, bytesToString(b))
+}
+
+// HTMLBuiltinEscaperTo writes s escaped like the html builtin of text/template.
+func HTMLBuiltinEscaperTo(w io.Writer, s string) error {
+	ew := errWriter{w: w}
+	writeHTMLReplaced(&ew, s, htmlBuiltinReplacementTable, true)
+	return ew.err
+}
+
+// HTMLBuiltinEscaperBytesTo is the []byte form of HTMLBuiltinEscaperTo.
+func HTMLBuiltinEscaperBytesTo(w io.Writer, b []byte) error {
+	return HTMLBuiltinEscaperTo(w, bytesToString(b))
+}
+
+// HTMLBuiltinEscaperAppend appends s escaped like the html builtin of text/template to dst.
+func HTMLBuiltinEscaperAppend(dst []byte, s string) []byte {
+	ew := errWriter{dst: dst}
+	writeHTMLReplaced(&ew, s, htmlBuiltinReplacementTable, true)
+	return ew.dst
+}
+
+// HTMLBuiltinEscaperBytesAppend is the []byte form of HTMLBuiltinEscaperAppend.
+func HTMLBuiltinEscaperBytesAppend(dst, b []byte) []byte {
+	return HTMLBuiltinEscaperAppend(dst, bytesToString(b))
+}
+
+// AttrEscaperTo writes s escaped for inclusion in quoted attribute values.
+func AttrEscaperTo(w io.Writer, s string) error {
+	ew := errWriter{w: w}
+	writeHTMLReplaced(&ew, s, htmlReplacementTable, true)
+	return ew.err
+}
+
+// AttrEscaperBytesTo is the []byte form of AttrEscaperTo.
+func AttrEscaperBytesTo(w io.Writer, b []byte) error {
+	return AttrEscaperTo(w, bytesToString(b))
+}
+
+// AttrEscaperAppend appends s escaped for inclusion in quoted attribute values to dst.
+func AttrEscaperAppend(dst []byte, s string) []byte {
+	ew := errWriter{dst: dst}
+	writeHTMLReplaced(&ew, s, htmlReplacementTable, true)
+	return ew.dst
+}
+
+// AttrEscaperBytesAppend is the []byte form of AttrEscaperAppend.
+func AttrEscaperBytesAppend(dst, b []byte) []byte {
+	return AttrEscaperAppend(dst, bytesToString(b))
+}
+
+// RcdataEscaperTo writes s escaped for inclusion in an RCDATA element body.
+func RcdataEscaperTo(w io.Writer, s string) error {
+	ew := errWriter{w: w}
+	writeHTMLReplaced(&ew, s, htmlReplacementTable, true)
+	return ew.err
+}
+
+// RcdataEscaperBytesTo is the []byte form of RcdataEscaperTo.
+func RcdataEscaperBytesTo(w io.Writer, b []byte) error {
+	return RcdataEscaperTo(w, bytesToString(b))
+}
+
+// RcdataEscaperAppend appends s escaped for inclusion in an RCDATA element body to dst.
+func RcdataEscaperAppend(dst []byte, s string) []byte {
+	ew := errWriter{dst: dst}
+	writeHTMLReplaced(&ew, s, htmlReplacementTable, true)
+	return ew.dst
+}
+
+// RcdataEscaperBytesAppend is the []byte form of RcdataEscaperAppend.
+func RcdataEscaperBytesAppend(dst, b []byte) []byte {
+	return RcdataEscaperAppend(dst, bytesToString(b))
+}
+
+// HTMLNospaceEscaperTo writes s escaped for inclusion in unquoted attribute values.
+func HTMLNospaceEscaperTo(w io.Writer, s string) error {
+	ew := errWriter{w: w}
+	writeHTMLReplaced(&ew, s, htmlNospaceReplacementTable, false)
+	return ew.err
+}
+
+// HTMLNospaceEscaperBytesTo is the []byte form of HTMLNospaceEscaperTo.
+func HTMLNospaceEscaperBytesTo(w io.Writer, b []byte) error {
+	return HTMLNospaceEscaperTo(w, bytesToString(b))
+}
+
+// HTMLNospaceEscaperAppend appends s escaped for inclusion in unquoted attribute values to dst.
+func HTMLNospaceEscaperAppend(dst []byte, s string) []byte {
+	ew := errWriter{dst: dst}
+	writeHTMLReplaced(&ew, s, htmlNospaceReplacementTable, false)
+	return ew.dst
+}
+
+// HTMLNospaceEscaperBytesAppend is the []byte form of HTMLNospaceEscaperAppend.
+func HTMLNospaceEscaperBytesAppend(dst, b []byte) []byte {
+	return HTMLNospaceEscaperAppend(dst, bytesToString(b))
+}
+
+// CommentEscaperTo writes nothing, see CommentEscaper.
+func CommentEscaperTo(w io.Writer, s string) error {
+	return nil
+}
+
+// CommentEscaperBytesTo is the []byte form of CommentEscaperTo.
+func CommentEscaperBytesTo(w io.Writer, b []byte) error {
+	return nil
+}
+
+// CommentEscaperAppend appends nothing, see CommentEscaper.
+func CommentEscaperAppend(dst []byte, s string) []byte {
+	return dst
+}
+
+// CommentEscaperBytesAppend is the []byte form of CommentEscaperAppend.
+func CommentEscaperBytesAppend(dst, b []byte) []byte {
+	return dst
+}
+
+// HTMLNameFilterTo writes s if it is a valid part of an HTML attribute or tag name.
+func HTMLNameFilterTo(w io.Writer, s string) error {
+	ew := errWriter{w: w}
+	writeHTMLNameFiltered(&ew, s)
+	return ew.err
+}
+
+// HTMLNameFilterBytesTo is the []byte form of HTMLNameFilterTo.
+func HTMLNameFilterBytesTo(w io.Writer, b []byte) error {
+	return HTMLNameFilterTo(w, bytesToString(b))
+}
+
+// HTMLNameFilterAppend appends s to dst if it is a valid part of an HTML attribute or tag name.
+func HTMLNameFilterAppend(dst []byte, s string) []byte {
+	ew := errWriter{dst: dst}
+	writeHTMLNameFiltered(&ew, s)
+	return ew.dst
+}
+
+// HTMLNameFilterBytesAppend is the []byte form of HTMLNameFilterAppend.
+func HTMLNameFilterBytesAppend(dst, b []byte) []byte {
+	return HTMLNameFilterAppend(dst, bytesToString(b))
+}
+
+// JSValEscaperTo writes s as a JS string literal.
+func JSValEscaperTo(w io.Writer, s string) error {
+	ew := errWriter{w: w}
+	writeJSValEscaped(&ew, s)
+	return ew.err
+}
+
+// JSValEscaperBytesTo is the []byte form of JSValEscaperTo.
+func JSValEscaperBytesTo(w io.Writer, b []byte) error {
+	return JSValEscaperTo(w, bytesToString(b))
+}
+
+// JSValEscaperAppend appends s as a JS string literal to dst.
+func JSValEscaperAppend(dst []byte, s string) []byte {
+	ew := errWriter{dst: dst}
+	writeJSValEscaped(&ew, s)
+	return ew.dst
+}
+
+// JSValEscaperBytesAppend is the []byte form of JSValEscaperAppend.
+func JSValEscaperBytesAppend(dst, b []byte) []byte {
+	return JSValEscaperAppend(dst, bytesToString(b))
+}
+
+// JSStrEscaperTo writes s escaped for inclusion between quotes in JavaScript source.
+func JSStrEscaperTo(w io.Writer, s string) error {
+	ew := errWriter{w: w}
+	writeJSReplaced(&ew, s, jsStrReplacementTable)
+	return ew.err
+}
+
+// JSStrEscaperBytesTo is the []byte form of JSStrEscaperTo.
+func JSStrEscaperBytesTo(w io.Writer, b []byte) error {
+	return JSStrEscaperTo(w, bytesToString(b))
+}
+
+// JSStrEscaperAppend appends s escaped for inclusion between quotes in JavaScript source to dst.
+func JSStrEscaperAppend(dst []byte, s string) []byte {
+	ew := errWriter{dst: dst}
+	writeJSReplaced(&ew, s, jsStrReplacementTable)
+	return ew.dst
+}
+
+// JSStrEscaperBytesAppend is the []byte form of JSStrEscaperAppend.
+func JSStrEscaperBytesAppend(dst, b []byte) []byte {
+	return JSStrEscaperAppend(dst, bytesToString(b))
+}
+
+// JSRegexpEscaperTo writes s escaped for inclusion in a JavaScript regular expression literal.
+func JSRegexpEscaperTo(w io.Writer, s string) error {
+	ew := errWriter{w: w}
+	writeJSRegexpEscaped(&ew, s)
+	return ew.err
+}
+
+// JSRegexpEscaperBytesTo is the []byte form of JSRegexpEscaperTo.
+func JSRegexpEscaperBytesTo(w io.Writer, b []byte) error {
+	return JSRegexpEscaperTo(w, bytesToString(b))
+}
+
+// JSRegexpEscaperAppend appends s escaped for inclusion in a JavaScript regular expression literal to dst.
+func JSRegexpEscaperAppend(dst []byte, s string) []byte {
+	ew := errWriter{dst: dst}
+	writeJSRegexpEscaped(&ew, s)
+	return ew.dst
+}
+
+// JSRegexpEscaperBytesAppend is the []byte form of JSRegexpEscaperAppend.
+func JSRegexpEscaperBytesAppend(dst, b []byte) []byte {
+	return JSRegexpEscaperAppend(dst, bytesToString(b))
+}
+
+// CSSEscaperTo writes s with HTML and CSS special characters escaped using \<hex>+ escapes.
+func CSSEscaperTo(w io.Writer, s string) error {
+	ew := errWriter{w: w}
+	writeCSSEscaped(&ew, s)
+	return ew.err
+}
+
+// CSSEscaperBytesTo is the []byte form of CSSEscaperTo.
+func CSSEscaperBytesTo(w io.Writer, b []byte) error {
+	return CSSEscaperTo(w, bytesToString(b))
+}
+
+// CSSEscaperAppend appends s with HTML and CSS special characters escaped using \<hex>+ escapes to dst.
+func CSSEscaperAppend(dst []byte, s string) []byte {
+	ew := errWriter{dst: dst}
+	writeCSSEscaped(&ew, s)
+	return ew.dst
+}
+
+// CSSEscaperBytesAppend is the []byte form of CSSEscaperAppend.
+func CSSEscaperBytesAppend(dst, b []byte) []byte {
+	return CSSEscaperAppend(dst, bytesToString(b))
+}
+
+// CSSValueFilterTo writes s if it is an innocuous CSS value, see CSSValueFilter.
+func CSSValueFilterTo(w io.Writer, s string) error {
+	ew := errWriter{w: w}
+	writeCSSValueFiltered(&ew, s)
+	return ew.err
+}
+
+// CSSValueFilterBytesTo is the []byte form of CSSValueFilterTo.
+func CSSValueFilterBytesTo(w io.Writer, b []byte) error {
+	return CSSValueFilterTo(w, bytesToString(b))
+}
+
+// CSSValueFilterAppend appends s to dst if it is an innocuous CSS value, see CSSValueFilter.
+func CSSValueFilterAppend(dst []byte, s string) []byte {
+	ew := errWriter{dst: dst}
+	writeCSSValueFiltered(&ew, s)
+	return ew.dst
+}
+
+// CSSValueFilterBytesAppend is the []byte form of CSSValueFilterAppend.
+func CSSValueFilterBytesAppend(dst, b []byte) []byte {
+	return CSSValueFilterAppend(dst, bytesToString(b))
+}
+
+// URLFilterTo writes s unless it contains an unsafe protocol,
+// in which case it writes a defanged URL.
+func URLFilterTo(w io.Writer, s string) error {
+	ew := errWriter{w: w}
+	writeURLFiltered(&ew, s)
+	return ew.err
+}
+
+// URLFilterBytesTo is the []byte form of URLFilterTo.
+func URLFilterBytesTo(w io.Writer, b []byte) error {
+	return URLFilterTo(w, bytesToString(b))
+}
+
+// URLFilterAppend appends s to dst unless it contains an unsafe protocol,
+// in which case it appends a defanged URL.
+func URLFilterAppend(dst []byte, s string) []byte {
+	ew := errWriter{dst: dst}
+	writeURLFiltered(&ew, s)
+	return ew.dst
+}
+
+// URLFilterBytesAppend is the []byte form of URLFilterAppend.
+func URLFilterBytesAppend(dst, b []byte) []byte {
+	return URLFilterAppend(dst, bytesToString(b))
+}
+
+// URLEscaperTo writes s escaped for inclusion in a URL query.
+func URLEscaperTo(w io.Writer, s string) error {
+	ew := errWriter{w: w}
+	writeURLProcessed(&ew, s, false)
+	return ew.err
+}
+
+// URLEscaperBytesTo is the []byte form of URLEscaperTo.
+func URLEscaperBytesTo(w io.Writer, b []byte) error {
+	return URLEscaperTo(w, bytesToString(b))
+}
+
+// URLEscaperAppend appends s escaped for inclusion in a URL query to dst.
+func URLEscaperAppend(dst []byte, s string) []byte {
+	ew := errWriter{dst: dst}
+	writeURLProcessed(&ew, s, false)
+	return ew.dst
+}
+
+// URLEscaperBytesAppend is the []byte form of URLEscaperAppend.
+func URLEscaperBytesAppend(dst, b []byte) []byte {
+	return URLEscaperAppend(dst, bytesToString(b))
+}
+
+// URLNormalizerTo writes s normalized for inclusion in a quote-delimited string
+// or parenthesis delimited url(...).
+func URLNormalizerTo(w io.Writer, s string) error {
+	ew := errWriter{w: w}
+	writeURLProcessed(&ew, s, true)
+	return ew.err
+}
+
+// URLNormalizerBytesTo is the []byte form of URLNormalizerTo.
+func URLNormalizerBytesTo(w io.Writer, b []byte) error {
+	return URLNormalizerTo(w, bytesToString(b))
+}
+
+// URLNormalizerAppend appends s normalized for inclusion in a quote-delimited string
+// or parenthesis delimited url(...) to dst.
+func URLNormalizerAppend(dst []byte, s string) []byte {
+	ew := errWriter{dst: dst}
+	writeURLProcessed(&ew, s, true)
+	return ew.dst
+}
+
+// URLNormalizerBytesAppend is the []byte form of URLNormalizerAppend.
+func URLNormalizerBytesAppend(dst, b []byte) []byte {
+	return URLNormalizerAppend(dst, bytesToString(b))
+}
+
+// errWriter writes strings to w until an error occurs,
+// when w is nil, it appends them to dst.
+type errWriter struct {
+	w   io.Writer
+	dst []byte
+	err error
+}
+
+func (e *errWriter) writeString(s string) {
+	if e.w == nil {
+		e.dst = append(e.dst, s...)
+	} else if e.err == nil && len(s) > 0 {
+		_, e.err = io.WriteString(e.w, s)
+	}
+}
+
+// bytesToString returns a string sharing the memory of b.
+// It is safe because the streaming escapers never retain their input.
+func bytesToString(b []byte) string {
+	return *(*string)(unsafe.Pointer(&b))
+}
+
+// equalLowerASCII tells if s lower cased equals lower.
+// lower must be made of lower case ASCII letters.
+func equalLowerASCII(s, lower string) bool {
+	if len(s) != len(lower) {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		if c != lower[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// writeHTMLReplaced is the streaming form of htmlReplacer.
+func writeHTMLReplaced(ew *errWriter, s string, replacementTable []string, badRunes bool) {
+	written := 0
+	r, n := rune(0), 0
+	for i := 0; i < len(s); i += n {
+		// See comment in htmlReplacer.
+		r, n = utf8.DecodeRuneInString(s[i:])
+		if int(r) < len(replacementTable) {
+			if repl := replacementTable[r]; len(repl) != 0 {
+				ew.writeString(s[written:i])
+				ew.writeString(repl)
+				written = i + n
+			}
+		} else if badRunes {
+			// No-op.
+			// IE does not allow these ranges in unquoted attrs.
+		} else if 0xfdd0 <= r && r <= 0xfdef || 0xfff0 <= r && r <= 0xffff {
+			ew.writeString(s[written:i])
+			ew.writeString(fmt.Sprintf("&#x%x;", r))
+			written = i + n
+		}
+	}
+	ew.writeString(s[written:])
+}
+
+// writeHTMLNameFiltered is the streaming form of htmlNameFilter for a plain string.
+func writeHTMLNameFiltered(ew *errWriter, s string) {
+	if len(s) == 0 {
+		ew.writeString(filterFailsafe)
+		return
+	}
+	s = strings.ToLower(s)
+	if t := attrType(s); t != contentTypePlain {
+		ew.writeString(filterFailsafe)
+		return
+	}
+	for _, r := range s {
+		switch {
+		case '0' <= r && r <= '9':
+		case 'a' <= r && r <= 'z':
+		default:
+			ew.writeString(filterFailsafe)
+			return
+		}
+	}
+	ew.writeString(s)
+}
+
+// writeJSValEscaped is the streaming form of jsValEscaper for a plain string.
+func writeJSValEscaped(ew *errWriter, s string) {
+	ew.writeString(`"`)
+	written := 0
+	for i := 0; i < len(s); {
+		repl := ""
+		r, n := rune(s[i]), 1
+		if r < utf8.RuneSelf {
+			if e := jsonASCIIEscapes[r]; len(e) > 1 {
+				repl = e
+			}
+		} else {
+			r, n = utf8.DecodeRuneInString(s[i:])
+			switch {
+			case r == utf8.RuneError && n == 1:
+				repl = jsonInvalidUTF8
+			case r == '\u2028':
+				repl = `\u2028`
+			case r == '\u2029':
+				repl = `\u2029`
+			}
+		}
+		if repl != "" {
+			ew.writeString(s[written:i])
+			ew.writeString(repl)
+			written = i + n
+		}
+		i += n
+	}
+	ew.writeString(s[written:])
+	ew.writeString(`"`)
+}
+
+// writeJSReplaced is the streaming form of replace.
+func writeJSReplaced(ew *errWriter, s string, replacementTable []string) {
+	r, n, written := rune(0), 0, 0
+	for i := 0; i < len(s); i += n {
+		// See comment in htmlReplacer.
+		r, n = utf8.DecodeRuneInString(s[i:])
+		var repl string
+		switch {
+		case int(r) < len(replacementTable) && replacementTable[r] != "":
+			repl = replacementTable[r]
+		case r == '\u2028':
+			repl = `\u2028`
+		case r == '\u2029':
+			repl = `\u2029`
+		default:
+			continue
+		}
+		ew.writeString(s[written:i])
+		ew.writeString(repl)
+		written = i + n
+	}
+	ew.writeString(s[written:])
+}
+
+// writeJSRegexpEscaped is the streaming form of jsRegexpEscaper for a plain string.
+func writeJSRegexpEscaped(ew *errWriter, s string) {
+	if s == "" {
+		// /{{.X}}/ should not produce a line comment when .X == "".
+		ew.writeString("(?:)")
+		return
+	}
+	writeJSReplaced(ew, s, jsRegexpReplacementTable)
+}
+
+// writeCSSEscaped is the streaming form of cssEscaper for a plain string.
+func writeCSSEscaped(ew *errWriter, s string) {
+	r, n, written := rune(0), 0, 0
+	for i := 0; i < len(s); i += n {
+		// See comment in htmlEscaper.
+		r, n = utf8.DecodeRuneInString(s[i:])
+		if int(r) >= len(cssReplacementTable) || cssReplacementTable[r] == "" {
+			continue
+		}
+		repl := cssReplacementTable[r]
+		ew.writeString(s[written:i])
+		ew.writeString(repl)
+		written = i + n
+		if repl != `\\` && (written == len(s) || isHex(s[written]) || isCSSSpace(s[written])) {
+			ew.writeString(" ")
+		}
+	}
+	ew.writeString(s[written:])
+}
+
+// writeCSSValueFiltered is the streaming form of cssValueFilter for a plain string.
+func writeCSSValueFiltered(ew *errWriter, s string) {
+	if strings.IndexByte(s, '\\') != -1 {
+		// escaped values must be decoded first.
+		ew.writeString(cssValueFilter(s))
+		return
+	}
+	// window holds the last lower cased name chars,
+	// it is enough to look for expression or mozbinding.
+	var window [10]byte
+	nameLen := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case 0, '"', '\'', '(', ')', '/', ';', '@', '[', '\\', ']', '`', '{', '}':
+			ew.writeString(filterFailsafe)
+			return
+		case '-':
+			// Disallow <!-- or -->.
+			// -- should not appear in valid identifiers.
+			if i != 0 && s[i-1] == '-' {
+				ew.writeString(filterFailsafe)
+				return
+			}
+		default:
+			if c < utf8.RuneSelf && isCSSNmchar(rune(c)) {
+				if 'A' <= c && c <= 'Z' {
+					c += 'a' - 'A'
+				}
+				copy(window[:], window[1:])
+				window[len(window)-1] = c
+				nameLen++
+				if nameLen >= len(window) &&
+					(string(window[:]) == string(expressionBytes) || string(window[:]) == string(mozBindingBytes)) {
+					ew.writeString(filterFailsafe)
+					return
+				}
+			}
+		}
+	}
+	ew.writeString(s)
+}
+
+// writeURLFiltered is the streaming form of urlFilter for a plain string.
+func writeURLFiltered(ew *errWriter, s string) {
+	if i := strings.IndexRune(s, ':'); i >= 0 && !strings.ContainsRune(s[:i], '/') {
+		protocol := s[:i]
+		if !equalLowerASCII(protocol, "http") &&
+			!equalLowerASCII(protocol, "https") &&
+			!equalLowerASCII(protocol, "mailto") {
+			ew.writeString("#")
+			ew.writeString(filterFailsafe)
+			return
+		}
+	}
+	ew.writeString(s)
+}
+
+const lowerHex = "0123456789abcdef"
+
+// writeURLProcessed is the streaming form of urlProcessor for a plain string.
+func writeURLProcessed(ew *errWriter, s string, norm bool) {
+	written := 0
+	for i, n := 0, len(s); i < n; i++ {
+		c := s[i]
+		switch c {
+		// See comments in urlProcessor.
+		case '!', '#', '$', '&', '*', '+', ',', '/', ':', ';', '=', '?', '@', '[', ']':
+			if norm {
+				continue
+			}
+		case '-', '.', '_', '~':
+			continue
+		case '%':
+			if norm && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]) {
+				continue
+			}
+		default:
+			if 'a' <= c && c <= 'z' {
+				continue
+			}
+			if 'A' <= c && c <= 'Z' {
+				continue
+			}
+			if '0' <= c && c <= '9' {
+				continue
+			}
+		}
+		ew.writeString(s[written:i])
+		ew.writeString("%")
+		ew.writeString(lowerHex[c>>4 : c>>4+1])
+		ew.writeString(lowerHex[c&0xf : c&0xf+1])
+		written = i + 1
+	}
+	ew.writeString(s[written:])
+}
+
+// htmlBuiltinReplacementTable contains the runes escaped by HTMLEscaper,
+// which is the html builtin of text/template.
+var htmlBuiltinReplacementTable = []string{
+	'"':  "&#34;",
+	'&':  "&amp;",
+	'\'': "&#39;",
+	'<':  "&lt;",
+	'>':  "&gt;",
+}
+
+// jsonASCIIEscapes holds the json encoding of each ASCII char,
+// and jsonInvalidUTF8 the encoding of an invalid UTF-8 byte,
+// so JSValEscaperTo produces the same output than json.Marshal.
+var (
+	jsonASCIIEscapes [utf8.RuneSelf]string
+	jsonInvalidUTF8  string
+)
+
+func init() {
+	for i := range jsonASCIIEscapes {
+		b, _ := json.Marshal(string(rune(i)))
+		jsonASCIIEscapes[i] = string(b[1 : len(b)-1])
+	}
+	b, _ := json.Marshal("\xff")
+	jsonInvalidUTF8 = string(b[1 : len(b)-1])
+}
@@ -0,0 +1,242 @@
+package template
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/mh-cbon/template-compiler/std/text/template"
+	"github.com/mh-cbon/template-compiler/std/text/template/parse"
+)
+
+func TestStreamingEscapers(t *testing.T) {
+	escapers := []struct {
+		name        string
+		escaper     func(...interface{}) string
+		to          func(io.Writer, string) error
+		bytesTo     func(io.Writer, []byte) error
+		append      func([]byte, string) []byte
+		bytesAppend func([]byte, []byte) []byte
+	}{
+		{"AttrEscaper", AttrEscaper, AttrEscaperTo, AttrEscaperBytesTo, AttrEscaperAppend, AttrEscaperBytesAppend},
+		{"CommentEscaper", CommentEscaper, CommentEscaperTo, CommentEscaperBytesTo, CommentEscaperAppend, CommentEscaperBytesAppend},
+		{"CSSEscaper", CSSEscaper, CSSEscaperTo, CSSEscaperBytesTo, CSSEscaperAppend, CSSEscaperBytesAppend},
+		{"CSSValueFilter", CSSValueFilter, CSSValueFilterTo, CSSValueFilterBytesTo, CSSValueFilterAppend, CSSValueFilterBytesAppend},
+		{"HTMLNameFilter", HTMLNameFilter, HTMLNameFilterTo, HTMLNameFilterBytesTo, HTMLNameFilterAppend, HTMLNameFilterBytesAppend},
+		{"HTMLTextEscaper", HTMLTextEscaper, HTMLEscaperTo, HTMLEscaperBytesTo, HTMLEscaperAppend, HTMLEscaperBytesAppend},
+		{"HTMLBuiltinEscaper", HTMLEscaper, HTMLBuiltinEscaperTo, HTMLBuiltinEscaperBytesTo, HTMLBuiltinEscaperAppend, HTMLBuiltinEscaperBytesAppend},
+		{"JSRegexpEscaper", JSRegexpEscaper, JSRegexpEscaperTo, JSRegexpEscaperBytesTo, JSRegexpEscaperAppend, JSRegexpEscaperBytesAppend},
+		{"JSStrEscaper", JSStrEscaper, JSStrEscaperTo, JSStrEscaperBytesTo, JSStrEscaperAppend, JSStrEscaperBytesAppend},
+		{"JSValEscaper", JSValEscaper, JSValEscaperTo, JSValEscaperBytesTo, JSValEscaperAppend, JSValEscaperBytesAppend},
+		{"HTMLNospaceEscaper", HTMLNospaceEscaper, HTMLNospaceEscaperTo, HTMLNospaceEscaperBytesTo, HTMLNospaceEscaperAppend, HTMLNospaceEscaperBytesAppend},
+		{"RcdataEscaper", RcdataEscaper, RcdataEscaperTo, RcdataEscaperBytesTo, RcdataEscaperAppend, RcdataEscaperBytesAppend},
+		{"URLEscaper", URLEscaper, URLEscaperTo, URLEscaperBytesTo, URLEscaperAppend, URLEscaperBytesAppend},
+		{"URLFilter", URLFilter, URLFilterTo, URLFilterBytesTo, URLFilterAppend, URLFilterBytesAppend},
+		{"URLNormalizer", URLNormalizer, URLNormalizerTo, URLNormalizerBytesTo, URLNormalizerAppend, URLNormalizerBytesAppend},
+	}
+	inputs := []string{
+		"",
+		"hello",
+		"Hello, World!",
+		`<a href="x" title='y'>&amp; + -- </a>`,
+		"\x00\t\n\v\f\r \x7f",
+		"\u2028\u2029\ufdd0\ufff0 \xff\xfe",
+		"日本語",
+		"a%20b%zz%",
+		"http://example.com/?q=a b&c=d",
+		"HTTPS://example.com",
+		"javascript:alert(1)",
+		"mailto:a@b.c",
+		"httpſ:x",
+		"onclick",
+		"Title",
+		"data-href",
+		"10px",
+		"#fff",
+		"color: red",
+		"expression(alert(1))",
+		"Moz-Binding",
+		"ex\\70 ression",
+		"a--b",
+		"1.5e3",
+		"/.*+?^${}()|[]\\",
+	}
+	for _, e := range escapers {
+		for _, in := range inputs {
+			want := e.escaper(in)
+			var b bytes.Buffer
+			if err := e.to(&b, in); err != nil {
+				t.Errorf("%vTo(%q): unexpected error %v", e.name, in, err)
+			}
+			if got := b.String(); got != want {
+				t.Errorf("%vTo(%q): got %q want %q", e.name, in, got, want)
+			}
+			b.Reset()
+			if err := e.bytesTo(&b, []byte(in)); err != nil {
+				t.Errorf("%vBytesTo(%q): unexpected error %v", e.name, in, err)
+			}
+			if got := b.String(); got != want {
+				t.Errorf("%vBytesTo(%q): got %q want %q", e.name, in, got, want)
+			}
+			if got := string(e.append([]byte("x"), in)); got != "x"+want {
+				t.Errorf("%vAppend(%q): got %q want %q", e.name, in, got, "x"+want)
+			}
+			if got := string(e.bytesAppend([]byte("x"), []byte(in))); got != "x"+want {
+				t.Errorf("%vBytesAppend(%q): got %q want %q", e.name, in, got, "x"+want)
+			}
+		}
+	}
+}
+
+func TestStreamingEscaperAllocs(t *testing.T) {
+	var b bytes.Buffer
+	b.Grow(1024)
+	s := `<a href="x">&amp; + hello</a>`
+	allocs := testing.AllocsPerRun(100, func() {
+		b.Reset()
+		HTMLEscaperTo(&b, s)
+		JSValEscaperTo(&b, s)
+		URLEscaperTo(&b, s)
+	})
+	if allocs > 0 {
+		t.Errorf("expected no allocations, got %v", allocs)
+	}
+	dst := make([]byte, 0, 1024)
+	allocs = testing.AllocsPerRun(100, func() {
+		dst = HTMLEscaperAppend(dst[:0], s)
+		dst = JSValEscaperAppend(dst, s)
+		dst = URLEscaperAppend(dst, s)
+	})
+	if allocs > 0 {
+		t.Errorf("expected no allocations for the append forms, got %v", allocs)
+	}
+}
+
+func TestHTMLEscaperToInterpreter(t *testing.T) {
+	escapers := []struct {
+		name   string
+		tpl    interface{ Execute(io.Writer, interface{}) error }
+		to     func(io.Writer, string) error
+		append func([]byte, string) []byte
+	}{
+		{"HTMLEscaper", Must(New("").Parse(`{{.}}`)), HTMLEscaperTo, HTMLEscaperAppend},
+		{"HTMLBuiltinEscaper", Must(New("").Parse(`{{html .}}`)), HTMLBuiltinEscaperTo, HTMLBuiltinEscaperAppend},
+		{"HTMLBuiltinEscaper", template.Must(template.New("").Parse(`{{html .}}`)), HTMLBuiltinEscaperTo, HTMLBuiltinEscaperAppend},
+	}
+	inputs := []string{
+		"\x00",
+		"a+b",
+		`"quoted" 'single'`,
+		"<a href=\"x\">&amp; \x00 + </a>",
+	}
+	for i, e := range escapers {
+		for _, in := range inputs {
+			var want bytes.Buffer
+			if err := e.tpl.Execute(&want, in); err != nil {
+				t.Fatal(err)
+			}
+			var b bytes.Buffer
+			if err := e.to(&b, in); err != nil {
+				t.Errorf("Test(%v): %vTo(%q): unexpected error %v", i, e.name, in, err)
+			}
+			if got := b.String(); got != want.String() {
+				t.Errorf("Test(%v): %vTo(%q): got %q want %q", i, e.name, in, got, want.String())
+			}
+			if got := string(e.append(nil, in)); got != want.String() {
+				t.Errorf("Test(%v): %vAppend(%q): got %q want %q", i, e.name, in, got, want.String())
+			}
+		}
+	}
+	for _, in := range inputs {
+		want := HTMLTextEscaper(in)
+		if got := publicFuncMap["_html_template_htmlescaper"].(func(...interface{}) string)(in); got != want {
+			t.Errorf("_html_template_htmlescaper(%q): got %q want %q", in, got, want)
+		}
+	}
+}
+
+func TestTemplateCompiled(t *testing.T) {
+	row := template.NewCompiled("row", func(t parse.Templater, w io.Writer, data interface{}) error {
+		up := t.GetFuncs()["up"].(func(string) string)
+		return HTMLEscaperTo(w, up(data.(map[string]string)["v"]))
+	})
+	page := template.NewCompiled("page", func(t parse.Templater, w io.Writer, data interface{}) error {
+		if _, err := io.WriteString(w, "<p>"); err != nil {
+			return err
+		}
+		if err := t.ExecuteTemplate(w, "interp", data); err != nil {
+			return err
+		}
+		_, err := io.WriteString(w, "</p>")
+		return err
+	})
+	page, _ = page.Compiled(row)
+
+	tpl := Must(New("main").Funcs(FuncMap{"up": strings.ToUpper}).Parse(
+		`{{define "interp"}}<i>{{.v}}</i>{{template "row" .}}{{end}}` +
+			`{{define "attr"}}<a title="{{template "row" .}}">{{end}}` +
+			`<div>{{template "page" .}}</div>`))
+	if _, err := tpl.Compiled(page); err != nil {
+		t.Fatal(err)
+	}
+	if tpl.Lookup("page") == nil || tpl.Lookup("row") == nil {
+		t.Fatal("expected the compiled templates to be registered")
+	}
+
+	tests := []struct {
+		name     string
+		expected string
+		err      string
+	}{
+		{"main", `<div><p><i>&lt;b&gt;</i>&lt;B&gt;</p></div>`, ""},
+		{"page", `<p><i>&lt;b&gt;</i>&lt;B&gt;</p>`, ""},
+		{"row", `&lt;B&gt;`, ""},
+		{"attr", ``, `compiled template "row" cannot be called in the context`},
+	}
+	for _, test := range tests {
+		var b bytes.Buffer
+		err := tpl.ExecuteTemplate(&b, test.name, map[string]string{"v": "<b>"})
+		if test.err != "" {
+			if err == nil || strings.Contains(err.Error(), test.err) == false {
+				t.Errorf("%v: unexpected error %v", test.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error %v", test.name, err)
+		} else if b.String() != test.expected {
+			t.Errorf("%v: unexpected output\nexpected=%v\ngot=%v", test.name, test.expected, b.String())
+		}
+	}
+
+	if _, err := tpl.Compiled(page); err == nil {
+		t.Errorf("expected an error when redefining an executed template")
+	}
+}
+
+func TestTemplateCompiledEscapingContext(t *testing.T) {
+	page := template.NewCompiled("page", func(t parse.Templater, w io.Writer, data interface{}) error {
+		// the call of x in the href attribute of the compiled template.
+		if err := t.ExecuteTemplate(w, "x$htmltemplate_stateURL_delimDoubleQuote_attrURL", data); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, "|"); err != nil {
+			return err
+		}
+		return t.ExecuteTemplate(w, "x", data)
+	})
+	tpl := Must(New("main").Parse(
+		`{{define "x"}}{{.}}{{end}}<a href="{{template "x" .}}">{{template "page" .}}</a>`))
+	if _, err := tpl.Compiled(page); err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := tpl.Execute(&b, "javascript:<b>"); err != nil {
+		t.Fatal(err)
+	}
+	expected := `<a href="#ZgotmplZ">#ZgotmplZ|javascript:&lt;b&gt;</a>`
+	if b.String() != expected {
+		t.Errorf("unexpected output\nexpected=%v\ngot=%v", expected, b.String())
+	}
+}
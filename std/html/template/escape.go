@@ -516,6 +516,17 @@ func (e *escaper) escapeTree(c context, node parse.Node, name string, line int)
 			err:   errorf(ErrNoSuchTemplate, node, line, "no such template %q", name),
 		}, dname
 	}
+	if isCompiledTree(t.Tree) {
+		// The compiled templates are escaped when they are compiled,
+		// they start and end in a text context.
+		if c.state != stateText {
+			return context{
+				state: stateError,
+				err:   errorf(ErrOutputContext, node, line, "compiled template %q cannot be called in the context %v", name, c),
+			}, dname
+		}
+		return c, dname
+	}
 	if dname != name {
 		// Use any template derived during an earlier call to escapeTemplate
 		// with different top level templates, or clone if necessary.
@@ -0,0 +1,18 @@
+package template
+
+import (
+	"io"
+
+	"github.com/mh-cbon/template-compiler/std/text/template"
+)
+
+// additions to template.Template
+
+// ExecuteProfile executes the template like Execute,
+// and records into p the output sizes of its range loops.
+func (t *Template) ExecuteProfile(wr io.Writer, data interface{}, p template.SizeProfile) error {
+	if err := t.escape(); err != nil {
+		return err
+	}
+	return t.text.ExecuteProfile(wr, data, p)
+}
@@ -1,7 +1,12 @@
 package template
 
 import (
+	"bytes"
+	"context"
+	"fmt"
 	"io"
+	"reflect"
+	"sort"
 
 	"github.com/mh-cbon/template-compiler/std/text/template/parse"
 )
@@ -9,11 +14,14 @@ import (
 // additions to template.Template
 
 // Compiled registers a compiled template.
+// The compiled templates join the templates associated with t,
+// they execute the templates they call within the execution of t.
 func (t *Template) Compiled(c *Compiled) (*Template, error) {
 	t.init()
-	// Add the newly parsed trees, including the one for t, into our common structure.
 	for name, tmpl := range c.compiledTmpl {
-		t.tmpl[name] = tmpl.Template
+		if _, err := t.AddParseTree(name, tmpl.Tree); err != nil {
+			return nil, err
+		}
 	}
 	// Add the newly parsed trees, including the one for t, into our common structure.
 	for name, tmpl := range c.tmpl {
@@ -27,18 +35,117 @@ func (t *Template) GetFuncs() map[string]interface{} {
 	return t.parseFuncs
 }
 
+// ExecuteTemplateContext applies the template associated with t that has the given name
+// when ctx is not done. The interpreted templates check ctx before they are executed only.
+func (t *Template) ExecuteTemplateContext(ctx context.Context, wr io.Writer, name string, data interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return t.ExecuteTemplate(wr, name, data)
+}
+
+// additions to the execution state
+
+// walkCompiled executes the compiled template of node with dot.
+// The errors it returns are reported at node, but those already reported by a template.
+func (s *state) walkCompiled(dot reflect.Value, node *parse.CompiledNode) {
+	var data interface{}
+	if dot.IsValid() && dot.CanInterface() {
+		data = dot.Interface()
+	}
+	if err := node.Execute(stateTemplater{s}, s.wr, data); err != nil {
+		if _, ok := err.(ExecError); ok {
+			panic(err)
+		}
+		s.errorf("%w", err)
+	}
+}
+
+// stateTemplater is the Templater of a compiled template executed by the interpreter,
+// the templates it calls are executed one level deeper within the execution,
+// they are looked up among the templates being executed, such as the escaped templates of an html set.
+type stateTemplater struct {
+	s *state
+}
+
+// GetFuncs returns the func map of the template being executed.
+func (t stateTemplater) GetFuncs() map[string]interface{} {
+	return t.s.tmpl.GetFuncs()
+}
+
+// ExecuteTemplate applies the template name to data within the execution.
+func (t stateTemplater) ExecuteTemplate(wr io.Writer, name string, data interface{}) (err error) {
+	defer errRecover(&err)
+	s := t.s
+	tmpl := s.tmpl.tmpl[name]
+	if tmpl == nil {
+		s.errorf("template %q not defined", name)
+	}
+	if s.depth == maxExecDepth {
+		s.errorf("exceeded maximum template depth (%v)", maxExecDepth)
+	}
+	dot := reflect.ValueOf(data)
+	newState := *s
+	newState.depth++
+	newState.tmpl = tmpl
+	newState.wr = wr
+	newState.vars = []variable{{"$", dot}}
+	newState.walk(dot, tmpl.Root)
+	return nil
+}
+
+// ExecuteTemplateContext is the form of ExecuteTemplate stopping when ctx is done.
+// The interpreted templates check ctx before they are executed only.
+func (t stateTemplater) ExecuteTemplateContext(ctx context.Context, wr io.Writer, name string, data interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return t.ExecuteTemplate(wr, name, data)
+}
+
+// Truth tells if the given value is true in the template sense.
+// It is used by compiled templates to test values which type is only known at runtime.
+func Truth(a interface{}) bool {
+	return truth(a)
+}
+
+// NewExecError returns the ExecError of an action of the compiled template name,
+// formatted such as the interpreter reports it. location is the template name
+// and the line:col of the action, context is the action.
+// The errors already reported by a called template are returned unchanged.
+func NewExecError(name, location, context string, err error) error {
+	if _, ok := err.(ExecError); ok {
+		return err
+	}
+	return ExecError{
+		Name:     name,
+		Err:      fmt.Errorf("template: %s: executing %q at <%s>: %w", location, name, context, err),
+		Location: location,
+	}
+}
+
+// Namespace provides the compiled templates by their name,
+// such as a compiled.Registry.
+type Namespace interface {
+	Lookup(name string) (*Compiled, bool)
+}
+
 // Compiled ...
 type Compiled struct {
 	*Template
 	compiledTmpl map[string]*Compiled
+	namespace    Namespace
 	executeFn    parse.CompiledTemplateFunc
+	appendFn     parse.CompiledAppendFunc
+	contextFn    parse.CompiledContextFunc
+	sizeHint     int
 }
 
 // NewCompiled is the template type of a compiled template.
 func (r *Compiled) init() {
 	r.Template.init()
 	r.compiledTmpl = make(map[string]*Compiled)
-	r.Tree = &parse.Tree{}
+	r.Tree = &parse.Tree{Name: r.name, ParseName: r.name}
 	r.Tree.Root = &parse.ListNode{}
 	r.Root = r.Tree.Root
 	r.Tree.Root.Nodes = append(
@@ -49,7 +156,7 @@ func (r *Compiled) init() {
 // NewCompiled makes a new Compiled template instance of a func implementation.
 func NewCompiled(name string, fn parse.CompiledTemplateFunc) *Compiled {
 	r := &Compiled{
-		Template:  &Template{},
+		Template:  &Template{name: name},
 		executeFn: fn,
 	}
 	r.init()
@@ -58,18 +165,138 @@ func NewCompiled(name string, fn parse.CompiledTemplateFunc) *Compiled {
 }
 
 // Execute invokes the compiled template function.
+// A bytes.Buffer is grown once to the size hint of the template.
+// The execution applies the limits and the observer of the namespace of the template.
 func (r *Compiled) Execute(wr io.Writer, data interface{}) error {
+	if b, ok := wr.(*bytes.Buffer); ok && r.sizeHint > 0 {
+		b.Grow(r.sizeHint)
+	}
+	if limits, observer, ok := r.executionOptions(); ok {
+		return newExecution(r, limits, observer).execute(nil, r, wr, data)
+	}
 	// its important to bypass Template.Execute method.
 	return r.executeFn(r, wr, data)
 }
 
+// SetAppend sets the func to render the compiled template into a []byte.
+func (r *Compiled) SetAppend(fn parse.CompiledAppendFunc) *Compiled {
+	r.appendFn = fn
+	return r
+}
+
+// SetContext sets the func to render the compiled template until a context is done.
+func (r *Compiled) SetContext(fn parse.CompiledContextFunc) *Compiled {
+	r.contextFn = fn
+	return r
+}
+
+// ExecuteContext invokes the compiled template function until ctx is done,
+// it returns the error of ctx when it is done.
+// If the template has no context func, ctx is checked before it is executed only.
+// The execution applies the limits and the observer of the namespace of the template.
+func (r *Compiled) ExecuteContext(ctx context.Context, wr io.Writer, data interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if b, ok := wr.(*bytes.Buffer); ok && r.sizeHint > 0 {
+		b.Grow(r.sizeHint)
+	}
+	if limits, observer, ok := r.executionOptions(); ok {
+		return newExecution(r, limits, observer).execute(ctx, r, wr, data)
+	}
+	if r.contextFn != nil {
+		return r.contextFn(ctx, r, wr, data)
+	}
+	return r.executeFn(r, wr, data)
+}
+
+// SetSizeHint sets the estimated output size of the compiled template.
+func (r *Compiled) SetSizeHint(n int) *Compiled {
+	r.sizeHint = n
+	return r
+}
+
+// SizeHint returns the estimated output size of the compiled template.
+func (r *Compiled) SizeHint() int {
+	return r.sizeHint
+}
+
+// AppendExecute appends the output of the compiled template to dst.
+// dst is grown once to the size hint of the template.
+// The execution applies the limits and the observer of the namespace of the template.
+// If the template has no append func, or when the namespace sets limits or an observer,
+// it is executed into dst with a SliceWriter.
+func (r *Compiled) AppendExecute(dst []byte, data interface{}) ([]byte, error) {
+	if cap(dst)-len(dst) < r.sizeHint {
+		dst = append(make([]byte, 0, len(dst)+r.sizeHint), dst...)
+	}
+	if limits, observer, ok := r.executionOptions(); ok {
+		w := SliceWriter(dst)
+		err := newExecution(r, limits, observer).execute(nil, r, &w, data)
+		return w, err
+	}
+	if r.appendFn != nil {
+		return r.appendFn(r, dst, data)
+	}
+	w := SliceWriter(dst)
+	err := r.executeFn(r, &w, data)
+	return w, err
+}
+
+// SetNamespace sets the namespace where the template looks up
+// the templates it calls which are not associated with it.
+func (r *Compiled) SetNamespace(ns Namespace) *Compiled {
+	r.namespace = ns
+	return r
+}
+
+// lookupTemplate returns the compiled template name associated with r,
+// or found within its namespace.
+// It returns nil and true when name is an interpreted template associated with r.
+func (r *Compiled) lookupTemplate(name string) (*Compiled, bool) {
+	if t, ok := r.compiledTmpl[name]; ok {
+		return t, true
+	}
+	if _, ok := r.tmpl[name]; ok {
+		return nil, true
+	}
+	if r.namespace != nil {
+		return r.namespace.Lookup(name)
+	}
+	return nil, false
+}
+
 // ExecuteTemplate invokes the compiled template function.
+// The template name is looked up within the templates associated with r,
+// then within its namespace.
 func (r *Compiled) ExecuteTemplate(wr io.Writer, name string, data interface{}) error {
-	// its important to bypass Template.ExecuteTemplate method.
-	if t, ok := r.compiledTmpl[name]; ok {
-		return t.executeFn(r, wr, data)
+	t, ok := r.lookupTemplate(name)
+	if ok == false {
+		return fmt.Errorf("template: no template %q associated with template %q", name, r.name)
+	}
+	if t == nil {
+		// its important to bypass Template.ExecuteTemplate method for the compiled templates.
+		return r.Template.ExecuteTemplate(wr, name, data)
+	}
+	return t.executeFn(r, wr, data)
+}
+
+// ExecuteTemplateContext is the form of ExecuteTemplate stopping when ctx is done.
+func (r *Compiled) ExecuteTemplateContext(ctx context.Context, wr io.Writer, name string, data interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	t, ok := r.lookupTemplate(name)
+	if ok == false {
+		return fmt.Errorf("template: no template %q associated with template %q", name, r.name)
+	}
+	if t == nil {
+		return r.Template.ExecuteTemplate(wr, name, data)
 	}
-	return r.Template.ExecuteTemplate(wr, name, data)
+	if t.contextFn != nil {
+		return t.contextFn(ctx, r, wr, data)
+	}
+	return t.executeFn(r, wr, data)
 }
 
 // Compiled registers a compiled template.
@@ -83,3 +310,39 @@ func (r *Compiled) Compiled(c *Compiled) (*Compiled, error) {
 	}
 	return r, nil
 }
+
+// CompiledTemplates returns the compiled templates associated with r, including r, sorted by name.
+func (r *Compiled) CompiledTemplates() []*Compiled {
+	names := make([]string, 0, len(r.compiledTmpl))
+	for name := range r.compiledTmpl {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	ret := make([]*Compiled, 0, len(names))
+	for _, name := range names {
+		ret = append(ret, r.compiledTmpl[name])
+	}
+	return ret
+}
+
+// ExecuteFunc returns the compiled template function.
+func (r *Compiled) ExecuteFunc() parse.CompiledTemplateFunc {
+	return r.executeFn
+}
+
+// SliceWriter is an io.Writer appending to a []byte.
+// The append func of a compiled template uses it
+// for the parts of the template that need an io.Writer.
+type SliceWriter []byte
+
+// Write appends p to the slice.
+func (s *SliceWriter) Write(p []byte) (int, error) {
+	*s = append(*s, p...)
+	return len(p), nil
+}
+
+// WriteString appends p to the slice.
+func (s *SliceWriter) WriteString(p string) (int, error) {
+	*s = append(*s, p...)
+	return len(p), nil
+}
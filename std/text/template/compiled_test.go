@@ -0,0 +1,92 @@
+package template
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/mh-cbon/template-compiler/std/text/template/parse"
+)
+
+type mixedData struct {
+	V string
+}
+
+func TestMixedExecution(t *testing.T) {
+	someErr := fmt.Errorf("some error")
+	compiled := map[string]parse.CompiledTemplateFunc{
+		"dot": func(t parse.Templater, w io.Writer, data interface{}) error {
+			_, err := fmt.Fprintf(w, "%T %v", data, data)
+			return err
+		},
+		"fails": func(t parse.Templater, w io.Writer, data interface{}) error {
+			return someErr
+		},
+		"execerror": func(t parse.Templater, w io.Writer, data interface{}) error {
+			return NewExecError("execerror", "c.tpl:2:4", ".Method", someErr)
+		},
+		"callback": func(t parse.Templater, w io.Writer, data interface{}) error {
+			if err := t.ExecuteTemplate(w, "interp", data); err != nil {
+				return err
+			}
+			_, err := io.WriteString(w, t.GetFuncs()["up"].(func(string) string)("!"))
+			return err
+		},
+		"undefined": func(t parse.Templater, w io.Writer, data interface{}) error {
+			return t.ExecuteTemplate(w, "nop", data)
+		},
+		"loop": func(t parse.Templater, w io.Writer, data interface{}) error {
+			return t.ExecuteTemplate(w, "interploop", data)
+		},
+	}
+	tpl := Must(New("main").Funcs(FuncMap{"up": strings.ToUpper}).Parse(
+		`{{define "interp"}}[{{$.V}} {{up .V}}]{{end}}` +
+			`{{define "interploop"}}{{template "loop" .}}{{end}}`))
+	for name, fn := range compiled {
+		if _, err := tpl.Compiled(NewCompiled(name, fn)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		tpl      string
+		data     interface{}
+		expected string
+		err      string
+	}{
+		{`{{template "dot" .}}`, "a", "string a", ""},
+		{`{{template "dot" .}}`, 1, "int 1", ""},
+		{`{{template "dot" .}}`, mixedData{"a"}, "template.mixedData {a}", ""},
+		{`{{template "dot" .}}`, (*mixedData)(nil), "*template.mixedData <nil>", ""},
+		{`{{template "dot" .}}`, nil, "<nil> <nil>", ""},
+		{`{{template "dot" .V}}`, mixedData{"a"}, "string a", ""},
+		{`{{range .}}{{template "dot" .}}{{end}}`, []int{1, 2}, "int 1int 2", ""},
+		{`{{template "callback" .}}`, mixedData{"a"}, "[a A]!", ""},
+		{`a{{template "fails" .}}`, nil, "a", `template: fails:1:0: executing "fails" at <{{compiled}}>: some error`},
+		{`{{template "execerror" .}}`, nil, "", `template: c.tpl:2:4: executing "execerror" at <.Method>: some error`},
+		{`{{template "undefined" .}}`, nil, "", `template: undefined:1:0: executing "undefined" at <{{compiled}}>: template "nop" not defined`},
+		{`{{template "loop" .}}`, nil, "", fmt.Sprintf("exceeded maximum template depth (%v)", maxExecDepth)},
+	}
+	for i, test := range tests {
+		tmpl := Must(Must(tpl.Clone()).Parse(test.tpl))
+		var b bytes.Buffer
+		err := tmpl.Execute(&b, test.data)
+		if test.err == "" && err != nil {
+			t.Errorf("Test(%v): unexpected error %v", i, err)
+		} else if test.err != "" && (err == nil || strings.HasSuffix(err.Error(), test.err) == false) {
+			t.Errorf("Test(%v): unexpected error\nexpected=%v\ngot=%v", i, test.err, err)
+		}
+		if b.String() != test.expected {
+			t.Errorf("Test(%v): unexpected output\nexpected=%v\ngot=%v", i, test.expected, b.String())
+		}
+		if test.err != "" && errors.Is(err, someErr) == false && strings.Contains(test.err, "some error") {
+			t.Errorf("Test(%v): expected the error to wrap the compiled template error %v", i, err)
+		}
+		if _, ok := err.(ExecError); test.err != "" && ok == false {
+			t.Errorf("Test(%v): expected an ExecError, got %#v", i, err)
+		}
+	}
+}
@@ -94,26 +94,34 @@ func doublePercent(str string) string {
 // error evaluating its template. (If a write error occurs, the actual
 // error is returned; it will not be of type ExecError.)
 type ExecError struct {
-	Name string // Name of template.
-	Err  error  // Pre-formatted error.
+	Name     string // Name of template.
+	Err      error  // Pre-formatted error.
+	Location string // Location of the action, such c.tpl:12:5.
 }
 
 func (e ExecError) Error() string {
 	return e.Err.Error()
 }
 
+func (e ExecError) Unwrap() error {
+	return e.Err
+}
+
 // errorf records an ExecError and terminates processing.
 func (s *state) errorf(format string, args ...interface{}) {
 	name := doublePercent(s.tmpl.Name())
+	location := ""
 	if s.node == nil {
 		format = fmt.Sprintf("template: %s: %s", name, format)
 	} else {
-		location, context := s.tmpl.ErrorContext(s.node)
+		var context string
+		location, context = s.tmpl.ErrorContext(s.node)
 		format = fmt.Sprintf("template: %s: executing %q at <%s>: %s", location, name, doublePercent(context), format)
 	}
 	panic(ExecError{
-		Name: s.tmpl.Name(),
-		Err:  fmt.Errorf(format, args...),
+		Name:     s.tmpl.Name(),
+		Err:      fmt.Errorf(format, args...),
+		Location: location,
 	})
 }
 
@@ -245,11 +253,7 @@ func (s *state) walk(dot reflect.Value, node parse.Node) {
 	case *parse.WithNode:
 		s.walkIfOrWith(parse.NodeWith, dot, node.Pipe, node.List, node.ElseList)
 	case *parse.CompiledNode:
-		var v interface{}
-		if dot.IsValid() && dot.IsNil() == false && dot.CanInterface() {
-			v = dot.Interface()
-		}
-		node.Execute(s.tmpl, s.wr, v)
+		s.walkCompiled(dot, node)
 	default:
 		s.errorf("unknown node: %s", node)
 	}
@@ -328,6 +332,8 @@ func (s *state) walkRange(dot reflect.Value, r *parse.RangeNode) {
 		s.walk(elem, r.List)
 		s.pop(mark)
 	}
+	oneIteration, recordLoop := s.profileRange(r, oneIteration)
+	defer recordLoop()
 	switch val.Kind() {
 	case reflect.Array, reflect.Slice:
 		if val.Len() == 0 {
@@ -0,0 +1,200 @@
+package template
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/mh-cbon/template-compiler/std/text/template/parse"
+)
+
+// additions to template.Compiled
+
+// Limits are the limits of an execution of a compiled template,
+// a zero value means no limit.
+type Limits struct {
+	// MaxDepth is the maximum depth of the nested ExecuteTemplate calls.
+	MaxDepth int
+	// MaxBytes is the maximum number of bytes written.
+	MaxBytes int
+	// MaxRangeIterations is the maximum number of iterations of each range.
+	MaxRangeIterations int
+}
+
+// IsZero tells if l sets no limit.
+func (l Limits) IsZero() bool {
+	return l == Limits{}
+}
+
+// limitsNamespace is a Namespace setting the limits of the executions of its templates,
+// such as a compiled.Registry.
+type limitsNamespace interface {
+	Limits() Limits
+}
+
+// LimitError is the error of an execution exceeding one of its limits.
+type LimitError struct {
+	// Name is the name of the template exceeding the limit.
+	Name string
+	// Limit is the exceeded limit, "template depth", "output size" or "range iterations".
+	Limit string
+	// Max is the value of the limit.
+	Max int
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("template: %s: exceeded maximum %s (%d)", e.Name, e.Limit, e.Max)
+}
+
+// ExecuteLimits invokes the compiled template function within limits.
+// It returns a *LimitError when a limit is exceeded.
+func (r *Compiled) ExecuteLimits(wr io.Writer, data interface{}, limits Limits) error {
+	return newExecution(r, limits, nil).execute(nil, r, wr, data)
+}
+
+// ExecuteContextLimits is the form of ExecuteLimits stopping when ctx is done.
+func (r *Compiled) ExecuteContextLimits(ctx context.Context, wr io.Writer, data interface{}, limits Limits) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return newExecution(r, limits, nil).execute(ctx, r, wr, data)
+}
+
+// AppendExecuteLimits is the form of AppendExecute within limits.
+func (r *Compiled) AppendExecuteLimits(dst []byte, data interface{}, limits Limits) ([]byte, error) {
+	w := SliceWriter(dst)
+	err := r.ExecuteLimits(&w, data, limits)
+	return w, err
+}
+
+// executionOptions returns the limits and the observer of the executions
+// set by the namespace of r, it returns false when they are not set.
+func (r *Compiled) executionOptions() (Limits, parse.Observer, bool) {
+	var limits Limits
+	if ns, ok := r.namespace.(limitsNamespace); ok {
+		limits = ns.Limits()
+	}
+	var observer parse.Observer
+	if ns, ok := r.namespace.(observerNamespace); ok {
+		observer = ns.Observer()
+	}
+	return limits, observer, observer != nil || limits.IsZero() == false
+}
+
+// execution is the Templater of an execution with limits or an observer,
+// it is passed to the compiled template functions in place of the template.
+type execution struct {
+	*Compiled
+	limits Limits
+	// observer is notified of the executions of the templates, it can be nil.
+	observer parse.Observer
+	// templater is passed to the compiled template functions,
+	// it is an observedExecution when the execution has an observer.
+	templater parse.Templater
+	// name is the template being executed.
+	name  string
+	depth int
+}
+
+func newExecution(r *Compiled, limits Limits, observer parse.Observer) *execution {
+	l := &execution{Compiled: r, limits: limits, observer: observer, name: r.name}
+	l.templater = l
+	if observer != nil {
+		l.templater = observedExecution{l}
+	}
+	return l
+}
+
+// execute invokes the template t, its output is limited when it is the top level template.
+func (l *execution) execute(ctx context.Context, t *Compiled, wr io.Writer, data interface{}) error {
+	if l.limits.MaxBytes > 0 && l.depth == 0 {
+		wr = &limitedWriter{w: wr, l: l}
+	}
+	if l.observer != nil {
+		return l.observe(wr, func(wr io.Writer) error {
+			return l.run(ctx, t, wr, data)
+		})
+	}
+	return l.run(ctx, t, wr, data)
+}
+
+// run invokes the compiled template function of t.
+func (l *execution) run(ctx context.Context, t *Compiled, wr io.Writer, data interface{}) error {
+	if ctx != nil && t.contextFn != nil {
+		return t.contextFn(ctx, l.templater, wr, data)
+	}
+	return t.executeFn(l.templater, wr, data)
+}
+
+// ExecuteTemplate invokes the template name one level deeper.
+func (l *execution) ExecuteTemplate(wr io.Writer, name string, data interface{}) error {
+	return l.executeTemplate(nil, wr, name, data)
+}
+
+// ExecuteTemplateContext is the form of ExecuteTemplate stopping when ctx is done.
+func (l *execution) ExecuteTemplateContext(ctx context.Context, wr io.Writer, name string, data interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return l.executeTemplate(ctx, wr, name, data)
+}
+
+func (l *execution) executeTemplate(ctx context.Context, wr io.Writer, name string, data interface{}) error {
+	if l.limits.MaxDepth > 0 && l.depth >= l.limits.MaxDepth {
+		return &LimitError{Name: name, Limit: "template depth", Max: l.limits.MaxDepth}
+	}
+	t, ok := l.lookupTemplate(name)
+	if ok == false {
+		return fmt.Errorf("template: no template %q associated with template %q", name, l.Compiled.name)
+	}
+	prev := l.name
+	l.name = name
+	l.depth++
+	var err error
+	if t != nil {
+		err = l.execute(ctx, t, wr, data)
+	} else if l.observer != nil {
+		err = l.observe(wr, func(wr io.Writer) error {
+			return l.Template.ExecuteTemplate(wr, name, data)
+		})
+	} else {
+		err = l.Template.ExecuteTemplate(wr, name, data)
+	}
+	l.depth--
+	l.name = prev
+	return err
+}
+
+// CheckRange returns an error when the n-th iteration of a range
+// of the template name exceeds the limit.
+func (l *execution) CheckRange(name string, n int) error {
+	if l.limits.MaxRangeIterations > 0 && n > l.limits.MaxRangeIterations {
+		return &LimitError{Name: name, Limit: "range iterations", Max: l.limits.MaxRangeIterations}
+	}
+	return nil
+}
+
+// limitedWriter writes to w until the output size limit of the execution is exceeded.
+type limitedWriter struct {
+	w io.Writer
+	l *execution
+	n int
+}
+
+func (lw *limitedWriter) Write(p []byte) (int, error) {
+	if lw.n+len(p) > lw.l.limits.MaxBytes {
+		return 0, &LimitError{Name: lw.l.name, Limit: "output size", Max: lw.l.limits.MaxBytes}
+	}
+	n, err := lw.w.Write(p)
+	lw.n += n
+	return n, err
+}
+
+func (lw *limitedWriter) WriteString(s string) (int, error) {
+	if lw.n+len(s) > lw.l.limits.MaxBytes {
+		return 0, &LimitError{Name: lw.l.name, Limit: "output size", Max: lw.l.limits.MaxBytes}
+	}
+	n, err := io.WriteString(lw.w, s)
+	lw.n += n
+	return n, err
+}
@@ -0,0 +1,71 @@
+package template
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/mh-cbon/template-compiler/std/text/template/parse"
+)
+
+// additions to template.Compiled
+
+// ExecuteObserved invokes the compiled template function within limits until ctx is done,
+// the executions of the template and of its nested templates are reported to o.
+func (r *Compiled) ExecuteObserved(ctx context.Context, wr io.Writer, data interface{}, limits Limits, o parse.Observer) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return newExecution(r, limits, o).execute(ctx, r, wr, data)
+}
+
+// observerNamespace is a Namespace setting the observer of the executions of its templates,
+// such as a compiled.Registry.
+type observerNamespace interface {
+	Observer() parse.Observer
+}
+
+// observedExecution is the Templater of an observed execution,
+// it implements parse.Observer so the compiled templates call their templates by name.
+type observedExecution struct {
+	*execution
+}
+
+// OnStart notifies the observer of the execution.
+func (o observedExecution) OnStart(name string) {
+	o.observer.OnStart(name)
+}
+
+// OnEnd notifies the observer of the execution.
+func (o observedExecution) OnEnd(name string, n int, dur time.Duration, err error) {
+	o.observer.OnEnd(name, n, dur, err)
+}
+
+// observe reports the execution of the current template by fn to the observer.
+func (l *execution) observe(wr io.Writer, fn func(io.Writer) error) error {
+	name := l.name
+	cw := &countingWriter{w: wr}
+	start := time.Now()
+	l.observer.OnStart(name)
+	err := fn(cw)
+	l.observer.OnEnd(name, cw.n, time.Since(start), err)
+	return err
+}
+
+// countingWriter counts the bytes written to w.
+type countingWriter struct {
+	w io.Writer
+	n int
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += n
+	return n, err
+}
+
+func (cw *countingWriter) WriteString(s string) (int, error) {
+	n, err := io.WriteString(cw.w, s)
+	cw.n += n
+	return n, err
+}
@@ -1,8 +1,10 @@
 package parse
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"time"
 )
 
 // CompiledNode is a Node to represent
@@ -18,11 +20,41 @@ type CompiledNode struct {
 type Templater interface {
 	GetFuncs() map[string]interface{}
 	ExecuteTemplate(io.Writer, string, interface{}) error
+	// ExecuteTemplateContext is the form of ExecuteTemplate stopping when ctx is done.
+	ExecuteTemplateContext(context.Context, io.Writer, string, interface{}) error
+}
+
+// Limiter is implemented by the Templater of an execution with limits,
+// the compiled templates check their range loops with it.
+type Limiter interface {
+	// CheckRange returns an error when the n-th iteration of a range
+	// of the template name exceeds the limit of the execution.
+	CheckRange(name string, n int) error
+}
+
+// Observer is notified of the executions of the templates.
+// It is implemented by the Templater of an observed execution,
+// the compiled templates then call their templates with ExecuteTemplate
+// rather than statically, so each call is observed.
+type Observer interface {
+	// OnStart is called before the template name is executed.
+	OnStart(name string)
+	// OnEnd is called once the template name is executed,
+	// n is the number of bytes it wrote, including its nested templates.
+	OnEnd(name string, n int, dur time.Duration, err error)
 }
 
 // CompiledTemplateFunc is the signature of the func responsible to render a compiled template.
 type CompiledTemplateFunc func(t Templater, w io.Writer, data interface{}) error
 
+// CompiledContextFunc is the signature of the func responsible to render a compiled template
+// until ctx is done, it returns the error of ctx when it is done.
+type CompiledContextFunc func(ctx context.Context, t Templater, w io.Writer, data interface{}) error
+
+// CompiledAppendFunc is the signature of the func responsible to render a compiled template
+// by appending its output to dst.
+type CompiledAppendFunc func(t Templater, dst []byte, data interface{}) ([]byte, error)
+
 // NewCompiledNode makes a new instance of CompiledNode
 func (t *Tree) NewCompiledNode(Execute CompiledTemplateFunc) *CompiledNode {
 	return &CompiledNode{tr: t, NodeType: NodeCompiledNode, Execute: Execute}
@@ -268,6 +268,7 @@ func IsEmptyTree(n Node) bool {
 	case *TextNode:
 		return len(bytes.TrimSpace(n.Text)) == 0
 	case *WithNode:
+	case *CompiledNode:
 	default:
 		panic("unknown node: " + n.String())
 	}
@@ -0,0 +1,110 @@
+package template
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"reflect"
+
+	"github.com/mh-cbon/template-compiler/std/text/template/parse"
+)
+
+// additions to template.Template
+
+// SizeProfile holds the output sizes of the range loops observed by ExecuteProfile,
+// the loops are keyed by their location, such as "e.tpl:5:3".
+// The template compiler reads it to estimate the output size of the compiled templates.
+type SizeProfile map[string]*LoopSize
+
+// LoopSize is the output size observed for a range loop.
+type LoopSize struct {
+	Runs       int
+	Iterations int
+	Bytes      int
+}
+
+// IterationSize returns the average size of an iteration of the loop.
+func (l LoopSize) IterationSize() int {
+	if l.Iterations == 0 {
+		return 0
+	}
+	return l.Bytes / l.Iterations
+}
+
+// Size returns the average size of a run of the loop.
+func (l LoopSize) Size() int {
+	if l.Runs == 0 {
+		return 0
+	}
+	return l.Bytes / l.Runs
+}
+
+// ReadSizeProfile reads a profile written by SizeProfile.WriteFile.
+func ReadSizeProfile(path string) (SizeProfile, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p := SizeProfile{}
+	err = json.Unmarshal(b, &p)
+	return p, err
+}
+
+// WriteFile writes the profile to path.
+func (p SizeProfile) WriteFile(path string) error {
+	b, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0644)
+}
+
+// ExecuteProfile executes the template like Execute,
+// and records into p the output sizes of its range loops.
+func (t *Template) ExecuteProfile(wr io.Writer, data interface{}, p SizeProfile) error {
+	return t.execute(&profiler{w: wr, sizes: p}, data)
+}
+
+// profiler is the writer of a profiled execution,
+// it counts the bytes written.
+type profiler struct {
+	w     io.Writer
+	n     int
+	sizes SizeProfile
+}
+
+func (p *profiler) Write(b []byte) (int, error) {
+	n, err := p.w.Write(b)
+	p.n += n
+	return n, err
+}
+
+// record adds a run of the loop node to the profile.
+func (p *profiler) record(t *Template, node *parse.RangeNode, iterations, bytes int) {
+	location, _ := t.ErrorContext(node)
+	l, ok := p.sizes[location]
+	if ok == false {
+		l = &LoopSize{}
+		p.sizes[location] = l
+	}
+	l.Runs++
+	l.Iterations += iterations
+	l.Bytes += bytes
+}
+
+// profileRange wraps the iteration func of a range loop to measure its output,
+// the loop is recorded by calling the returned func once the loop is over.
+func (s *state) profileRange(r *parse.RangeNode, oneIteration func(index, elem reflect.Value)) (func(index, elem reflect.Value), func()) {
+	p, ok := s.wr.(*profiler)
+	if ok == false {
+		return oneIteration, func() {}
+	}
+	var iterations, bytes int
+	profiled := func(index, elem reflect.Value) {
+		n := p.n
+		oneIteration(index, elem)
+		iterations++
+		bytes += p.n - n
+	}
+	return profiled, func() { p.record(s.tmpl, r, iterations, bytes) }
+}
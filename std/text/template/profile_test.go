@@ -0,0 +1,55 @@
+package template
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+func TestExecuteProfile(t *testing.T) {
+	tmpl := Must(New("t").Parse("a\n{{range .}}<{{.}}>{{end}}{{define \"x\"}}{{range .}}{{.}}{{end}}{{end}}{{template \"x\" .}}"))
+	p := SizeProfile{}
+	for _, data := range [][]string{{"a", "bb"}, {"ccc"}, nil} {
+		var b bytes.Buffer
+		if err := tmpl.ExecuteProfile(&b, data, p); err != nil {
+			t.Fatal(err)
+		}
+		var want bytes.Buffer
+		if err := tmpl.Execute(&want, data); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != want.String() {
+			t.Errorf("got %q, want %q", b.String(), want.String())
+		}
+	}
+	want := map[string]LoopSize{
+		"t:2:8":  {Runs: 3, Iterations: 3, Bytes: 12},
+		"t:2:47": {Runs: 3, Iterations: 3, Bytes: 6},
+	}
+	if len(p) != len(want) {
+		t.Fatalf("got %v loops, want %v", len(p), len(want))
+	}
+	for location, l := range want {
+		if got, ok := p[location]; ok == false || *got != l {
+			t.Errorf("loop %v: got %v, want %v", location, got, l)
+		}
+	}
+	if got := p["t:2:8"].Size(); got != 4 {
+		t.Errorf("Size: got %v, want 4", got)
+	}
+	if got := p["t:2:8"].IterationSize(); got != 4 {
+		t.Errorf("IterationSize: got %v, want 4", got)
+	}
+
+	path := filepath.Join(t.TempDir(), "sizes.json")
+	if err := p.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadSizeProfile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *read["t:2:47"] != want["t:2:47"] {
+		t.Errorf("ReadSizeProfile: got %v, want %v", read["t:2:47"], want["t:2:47"])
+	}
+}